@@ -0,0 +1,214 @@
+package sqlitebitmapstore
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+type AggregateOptions struct {
+	AtBlock *uint64 `json:"atBlock,omitempty"`
+	// HistogramBucketSize enables the histogram, grouping values into buckets
+	// of this width. It must be positive and can have a fraction, like 0.25
+	// for values between 0 and 1.
+	HistogramBucketSize *store.NumericValue `json:"histogramBucketSize,omitempty"`
+	// GrammarVersion is the version of the query language that the query is
+	// written in, the latest if not set.
+	GrammarVersion query.GrammarVersion `json:"grammarVersion,omitempty"`
+}
+
+func (o *AggregateOptions) GetAtBlock() uint64 {
+	if o == nil || o.AtBlock == nil {
+		return 0
+	}
+	return *o.AtBlock
+}
+
+func (o *AggregateOptions) GetParseOptions() query.ParseOptions {
+	if o == nil {
+		return query.ParseOptions{}
+	}
+	return query.ParseOptions{Version: o.GrammarVersion}
+}
+
+func (o *AggregateOptions) GetHistogramBucketSize() *store.NumericValue {
+	if o == nil {
+		return nil
+	}
+	return o.HistogramBucketSize
+}
+
+type HistogramBucket struct {
+	// From is inclusive and To is exclusive. To is omitted for the last bucket
+	// if it would exceed the largest numeric value.
+	From  store.NumericValue  `json:"from"`
+	To    *store.NumericValue `json:"to,omitempty"`
+	Count uint64              `json:"count"`
+}
+
+type AggregateResponse struct {
+	Attribute string `json:"attribute"`
+	// Count is the number of matching entities that carry the attribute.
+	Count uint64              `json:"count"`
+	Min   *store.NumericValue `json:"min,omitempty"`
+	Max   *store.NumericValue `json:"max,omitempty"`
+	// Sum is the exact decimal sum, which can exceed the range of numeric
+	// values.
+	Sum       string            `json:"sum"`
+	Average   *float64          `json:"average,omitempty"`
+	Histogram []HistogramBucket `json:"histogram,omitempty"`
+
+	BlockNumber uint64 `json:"blockNumber"`
+}
+
+// AggregateNumericAttribute computes statistics of a numeric attribute over
+// the entities matching queryStr.
+//
+// The statistics are computed from the numeric attribute index: every value
+// bitmap of the attribute is intersected with the query result and the value
+// is weighted by the cardinality of the intersection, so no payloads are
+// decoded.
+func (s *SQLiteStore) AggregateNumericAttribute(
+	ctx context.Context,
+	queryStr string,
+	attribute string,
+	options *AggregateOptions,
+	args ...any,
+) (*AggregateResponse, error) {
+
+	err := s.waitForBlock(ctx, options.GetAtBlock())
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := s.parseQuery(queryStr, options.GetParseOptions(), args)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing query: %w", err)
+	}
+
+	bucketSize := options.GetHistogramBucketSize()
+	if bucketSize != nil && bucketSize.Scaled().Sign() <= 0 {
+		return nil, fmt.Errorf("the histogram bucket size must be positive, got %s", bucketSize)
+	}
+
+	res := &AggregateResponse{
+		Attribute: attribute,
+		Sum:       "0",
+	}
+
+	err = s.ReadTransaction(ctx, func(queries *store.Queries) error {
+
+		lastBlock, err := queries.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		res.BlockNumber = lastBlock
+
+		q, err = prepareQuery(ctx, queries, q, options.GetParseOptions(), lastBlock)
+		if err != nil {
+			return err
+		}
+
+		bitmap, err := q.Evaluate(ctx, queries)
+		if err != nil {
+			return fmt.Errorf("error evaluating query: %w", err)
+		}
+
+		if bitmap.IsEmpty() {
+			return nil
+		}
+
+		values, err := queries.GetNumericAttributeValueBitmaps(ctx, attribute)
+		if err != nil {
+			return fmt.Errorf("error getting numeric attribute %q value bitmaps: %w", attribute, err)
+		}
+
+		// sums are computed in units of 10^-18 to be exact for decimals
+		sum := new(big.Int)
+		weighted := new(big.Int)
+
+		// values are ordered by value, so buckets are created in order as well
+		for _, v := range values {
+			count := bitmap.AndCardinality(v.Bitmap.Bitmap)
+			if count == 0 {
+				continue
+			}
+
+			if res.Min == nil {
+				res.Min = pointerOf(v.Value)
+			}
+			res.Max = pointerOf(v.Value)
+			res.Count += count
+
+			weighted.SetUint64(count)
+			weighted.Mul(weighted, v.Value.Scaled())
+			sum.Add(sum, weighted)
+
+			if bucketSize == nil {
+				continue
+			}
+
+			from, to := histogramBucket(v.Value, *bucketSize)
+			if n := len(res.Histogram); n > 0 && res.Histogram[n-1].From.Cmp(from) == 0 {
+				res.Histogram[n-1].Count += count
+				continue
+			}
+
+			res.Histogram = append(res.Histogram, HistogramBucket{From: from, To: to, Count: count})
+		}
+
+		if res.Count > 0 {
+			res.Sum = formatScaled(sum)
+
+			count := new(big.Int).SetUint64(res.Count)
+			avg, _ := new(big.Rat).SetFrac(sum, count.Mul(count, big.NewInt(1e18))).Float64()
+			res.Average = &avg
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error performing aggregation: %w", err)
+	}
+
+	return res, nil
+}
+
+// histogramBucket returns the bounds of the bucket of width size that v falls
+// in. Buckets are aligned to multiples of size, also for negative values. to
+// is nil if it would exceed the largest numeric value.
+func histogramBucket(v store.NumericValue, size store.NumericValue) (from store.NumericValue, to *store.NumericValue) {
+	scaledSize := size.Scaled()
+
+	// Div rounds towards negative infinity for a positive divisor
+	scaledFrom := new(big.Int).Div(v.Scaled(), scaledSize)
+	scaledFrom.Mul(scaledFrom, scaledSize)
+
+	scaledTo := new(big.Int).Add(scaledFrom, scaledSize)
+
+	from, err := store.NumericValueFromScaled(scaledFrom)
+	if err != nil {
+		// the first bucket is truncated at the smallest numeric value
+		scaledFrom.Lsh(big.NewInt(1e18), 256)
+		from, _ = store.NumericValueFromScaled(scaledFrom.Sub(big.NewInt(1), scaledFrom))
+	}
+
+	if t, err := store.NumericValueFromScaled(scaledTo); err == nil {
+		to = &t
+	}
+
+	return from, to
+}
+
+// formatScaled formats an integer counting units of 10^-18 as a decimal
+// number, without trailing fractional zeros.
+func formatScaled(scaled *big.Int) string {
+	s := new(big.Rat).SetFrac(scaled, big.NewInt(1e18)).FloatString(18)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
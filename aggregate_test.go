@@ -0,0 +1,159 @@
+package sqlitebitmapstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitebitmapstore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/pusher"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+func pointerOf[T any](v T) *T {
+	return &v
+}
+
+var _ = Describe("AggregateNumericAttribute", func() {
+	var (
+		sqlStore *sqlitebitmapstore.SQLiteStore
+		tmpDir   string
+		ctx      context.Context
+		cancel   context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "sqlitestore_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := slog.New(slog.NewTextHandler(GinkgoWriter, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel = context.WithCancel(context.Background())
+
+		owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+		operations := []events.Operation{}
+		for i, price := range []uint64{5, 10, 10, 25} {
+			operations = append(operations, events.Operation{
+				TxIndex: 0,
+				OpIndex: uint64(i),
+				Create: &events.OPCreate{
+					Key:               common.BigToHash(big.NewInt(int64(i + 1))),
+					ContentType:       "text/plain",
+					BTL:               100,
+					Owner:             owner,
+					Content:           []byte("listing"),
+					StringAttributes:  map[string]string{"type": "listing"},
+					NumericAttributes: map[string]uint64{"price": price},
+				},
+			})
+		}
+
+		iterator := pusher.NewPushIterator()
+		go func() {
+			defer GinkgoRecover()
+			iterator.Push(ctx, events.BlockBatch{
+				Blocks: []events.Block{{Number: 100, Operations: operations}},
+			})
+			iterator.Close()
+		}()
+
+		err = sqlStore.FollowEvents(ctx, arkivevents.BatchIterator(iterator.Iterator()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+		if sqlStore != nil {
+			sqlStore.Close()
+		}
+		os.RemoveAll(tmpDir)
+	})
+
+	It("should compute statistics and a histogram from the index", func() {
+		bucketSize := store.NewNumericValue(10)
+		res, err := sqlStore.AggregateNumericAttribute(ctx, `type = "listing"`, "price", &sqlitebitmapstore.AggregateOptions{
+			HistogramBucketSize: &bucketSize,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(res.BlockNumber).To(Equal(uint64(100)))
+		Expect(res.Count).To(Equal(uint64(4)))
+		Expect(*res.Min).To(Equal(store.NewNumericValue(5)))
+		Expect(*res.Max).To(Equal(store.NewNumericValue(25)))
+		Expect(res.Sum).To(Equal("50"))
+		Expect(*res.Average).To(Equal(12.5))
+		Expect(res.Histogram).To(Equal([]sqlitebitmapstore.HistogramBucket{
+			{From: store.NewNumericValue(0), To: pointerOf(store.NewNumericValue(10)), Count: 1},
+			{From: store.NewNumericValue(10), To: pointerOf(store.NewNumericValue(20)), Count: 2},
+			{From: store.NewNumericValue(20), To: pointerOf(store.NewNumericValue(30)), Count: 1},
+		}))
+
+		// numeric values are encoded as decimal strings to keep their precision
+		data, err := json.Marshal(res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{
+			"attribute": "price",
+			"count": 4,
+			"min": "5",
+			"max": "25",
+			"sum": "50",
+			"average": 12.5,
+			"histogram": [
+				{"from": "0", "to": "10", "count": 1},
+				{"from": "10", "to": "20", "count": 2},
+				{"from": "20", "to": "30", "count": 1}
+			],
+			"blockNumber": 100
+		}`))
+	})
+
+	It("should group values into fractional buckets", func() {
+		var options sqlitebitmapstore.AggregateOptions
+		Expect(json.Unmarshal([]byte(`{"histogramBucketSize": 2.5}`), &options)).To(Succeed())
+
+		res, err := sqlStore.AggregateNumericAttribute(ctx, `type = "listing"`, "price", &options)
+		Expect(err).NotTo(HaveOccurred())
+
+		bound := func(s string) store.NumericValue {
+			v, err := store.ParseNumericValue(s)
+			Expect(err).NotTo(HaveOccurred())
+			return v
+		}
+		Expect(res.Histogram).To(Equal([]sqlitebitmapstore.HistogramBucket{
+			{From: bound("5"), To: pointerOf(bound("7.5")), Count: 1},
+			{From: bound("10"), To: pointerOf(bound("12.5")), Count: 2},
+			{From: bound("25"), To: pointerOf(bound("27.5")), Count: 1},
+		}))
+
+		for _, size := range []string{"0", "-1"} {
+			bucketSize := bound(size)
+			_, err = sqlStore.AggregateNumericAttribute(ctx, `type = "listing"`, "price", &sqlitebitmapstore.AggregateOptions{
+				HistogramBucketSize: &bucketSize,
+			})
+			Expect(err).To(MatchError(ContainSubstring("the histogram bucket size must be positive")))
+		}
+	})
+
+	It("should return empty statistics when nothing matches", func() {
+		res, err := sqlStore.AggregateNumericAttribute(ctx, `type = "other"`, "price", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(res.Count).To(BeZero())
+		Expect(res.Min).To(BeNil())
+		Expect(res.Average).To(BeNil())
+		Expect(res.Sum).To(Equal("0"))
+	})
+})
@@ -0,0 +1,246 @@
+package sqlitebitmapstore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// AttributeValuesLimit is the largest number of values that
+// ListAttributeValues returns at a time.
+const AttributeValuesLimit uint64 = 1000
+
+// AttributeType is the type of the values of an attribute.
+type AttributeType string
+
+const (
+	StringAttribute  AttributeType = "string"
+	NumericAttribute AttributeType = "numeric"
+)
+
+// AttributeInfo describes the values of one type of an attribute. An
+// attribute that has string values on some entities and numeric values on
+// others is described twice.
+type AttributeInfo struct {
+	Name           string        `json:"name"`
+	Type           AttributeType `json:"type"`
+	DistinctValues uint64        `json:"distinctValues"`
+	// Entities is the number of entities with a value of this type.
+	Entities uint64 `json:"entities"`
+}
+
+type ListAttributesResponse struct {
+	Attributes  []AttributeInfo `json:"attributes"`
+	BlockNumber uint64          `json:"blockNumber"`
+}
+
+type ListAttributeValuesOptions struct {
+	// Type selects the values to list, StringAttribute if not set.
+	Type AttributeType `json:"type,omitempty"`
+	// Prefix restricts the string values to those that start with it.
+	Prefix        string  `json:"prefix,omitempty"`
+	ValuesPerPage *uint64 `json:"valuesPerPage,omitempty"`
+	Cursor        string  `json:"cursor,omitempty"`
+}
+
+func (o *ListAttributeValuesOptions) GetType() AttributeType {
+	if o == nil || o.Type == "" {
+		return StringAttribute
+	}
+	return o.Type
+}
+
+func (o *ListAttributeValuesOptions) GetPrefix() string {
+	if o == nil {
+		return ""
+	}
+	return o.Prefix
+}
+
+func (o *ListAttributeValuesOptions) GetValuesPerPage() uint64 {
+	if o == nil || o.ValuesPerPage == nil || *o.ValuesPerPage == 0 || *o.ValuesPerPage > AttributeValuesLimit {
+		return AttributeValuesLimit
+	}
+	return *o.ValuesPerPage
+}
+
+func (o *ListAttributeValuesOptions) GetCursor() *string {
+	if o == nil || o.Cursor == "" {
+		return nil
+	}
+	return &o.Cursor
+}
+
+// AttributeValue is a value of an attribute with the number of entities that
+// have it. Value is a string or a store.NumericValue.
+type AttributeValue struct {
+	Value    any    `json:"value"`
+	Entities uint64 `json:"entities"`
+}
+
+type ListAttributeValuesResponse struct {
+	Values []AttributeValue `json:"values"`
+	// Cursor is set if there are more values. It encodes the last value of
+	// this page, a hex encoded string or a decimal number.
+	Cursor      *string `json:"cursor,omitempty"`
+	BlockNumber uint64  `json:"blockNumber"`
+}
+
+// ListAttributes returns the attributes that entities have, including the
+// special attributes, ordered by name. It only reads the attribute
+// statistics, not the bitmaps or the payloads.
+func (s *SQLiteStore) ListAttributes(ctx context.Context) (*ListAttributesResponse, error) {
+	res := &ListAttributesResponse{Attributes: []AttributeInfo{}}
+
+	err := s.ReadTransaction(ctx, func(queries *store.Queries) error {
+
+		lastBlock, err := queries.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		res.BlockNumber = lastBlock
+
+		strs, err := queries.ListStringAttributeStats(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing string attributes: %w", err)
+		}
+		for _, a := range strs {
+			res.Attributes = append(res.Attributes, AttributeInfo{
+				Name:           a.Name,
+				Type:           StringAttribute,
+				DistinctValues: uint64(a.DistinctValues),
+				Entities:       uint64(a.Cardinality),
+			})
+		}
+
+		numerics, err := queries.ListNumericAttributeStats(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing numeric attributes: %w", err)
+		}
+		for _, a := range numerics {
+			res.Attributes = append(res.Attributes, AttributeInfo{
+				Name:           a.Name,
+				Type:           NumericAttribute,
+				DistinctValues: uint64(a.DistinctValues),
+				Entities:       uint64(a.Cardinality),
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing attributes: %w", err)
+	}
+
+	// string attributes come before numeric attributes of the same name
+	slices.SortStableFunc(res.Attributes, func(a, b AttributeInfo) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return res, nil
+}
+
+// ListAttributeValues returns the values of an attribute in order, with the
+// number of entities that have each of them. It only reads the attribute
+// value bitmaps, not the payloads.
+func (s *SQLiteStore) ListAttributeValues(
+	ctx context.Context,
+	name string,
+	options *ListAttributeValuesOptions,
+) (*ListAttributeValuesResponse, error) {
+
+	attributeType := options.GetType()
+	if attributeType != StringAttribute && attributeType != NumericAttribute {
+		return nil, fmt.Errorf("unknown attribute type %q", attributeType)
+	}
+
+	prefix := options.GetPrefix()
+	if prefix != "" && attributeType == NumericAttribute {
+		return nil, fmt.Errorf("numeric values cannot be filtered by prefix")
+	}
+
+	cursor := options.GetCursor()
+
+	var afterString []byte
+	var afterNumber store.NumericValue
+	if cursor != nil {
+		var err error
+		if attributeType == NumericAttribute {
+			afterNumber, err = store.ParseNumericValue(*cursor)
+		} else {
+			afterString, err = hexutil.Decode(*cursor)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding cursor: %w", err)
+		}
+	}
+
+	valuesPerPage := options.GetValuesPerPage()
+
+	res := &ListAttributeValuesResponse{Values: []AttributeValue{}}
+
+	err := s.ReadTransaction(ctx, func(queries *store.Queries) error {
+
+		lastBlock, err := queries.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		res.BlockNumber = lastBlock
+
+		// one more value than requested tells whether there is another page
+		if attributeType == NumericAttribute {
+			values, err := queries.ListNumericAttributeValues(ctx, store.ListNumericAttributeValuesParams{
+				Name:      name,
+				HasAfter:  cursor != nil,
+				After:     afterNumber,
+				MaxValues: int64(valuesPerPage) + 1,
+			})
+			if err != nil {
+				return fmt.Errorf("error listing numeric values: %w", err)
+			}
+			for _, v := range values {
+				res.Values = append(res.Values, AttributeValue{Value: v.Value, Entities: v.Cardinality})
+			}
+		} else {
+			upper, bounded := store.PrefixUpperBound(prefix)
+			values, err := queries.ListStringAttributeValues(ctx, store.ListStringAttributeValuesParams{
+				Name:                name,
+				Prefix:              prefix,
+				HasPrefixUpperBound: bounded,
+				PrefixUpperBound:    upper,
+				HasAfter:            cursor != nil,
+				After:               string(afterString),
+				MaxValues:           int64(valuesPerPage) + 1,
+			})
+			if err != nil {
+				return fmt.Errorf("error listing string values: %w", err)
+			}
+			for _, v := range values {
+				res.Values = append(res.Values, AttributeValue{Value: v.Value, Entities: v.Cardinality})
+			}
+		}
+
+		if uint64(len(res.Values)) > valuesPerPage {
+			res.Values = res.Values[:valuesPerPage]
+			switch last := res.Values[len(res.Values)-1].Value.(type) {
+			case string:
+				res.Cursor = pointerOf(hexutil.Encode([]byte(last)))
+			case store.NumericValue:
+				res.Cursor = pointerOf(last.String())
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing values of attribute %q: %w", name, err)
+	}
+
+	return res, nil
+}
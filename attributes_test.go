@@ -0,0 +1,148 @@
+package sqlitebitmapstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitebitmapstore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+var _ = Describe("attribute discovery", func() {
+	var (
+		sqlStore *sqlitebitmapstore.SQLiteStore
+		tmpDir   string
+		ctx      context.Context
+		cancel   context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "sqlitestore_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := slog.New(slog.NewTextHandler(GinkgoWriter, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel = context.WithCancel(context.Background())
+
+		operations := []events.Operation{}
+		for i, colour := range []string{"red", "green", "red", "grey", ""} {
+			operations = append(operations, createOperation(
+				i,
+				map[string]string{"colour": colour, "size": "large"},
+				map[string]uint64{"size": uint64(i % 2)},
+			))
+		}
+
+		followBlocks(ctx, sqlStore, events.Block{Number: 100, Operations: operations})
+	})
+
+	AfterEach(func() {
+		cancel()
+		if sqlStore != nil {
+			sqlStore.Close()
+		}
+		os.RemoveAll(tmpDir)
+	})
+
+	It("should list the attributes with their types", func() {
+		res, err := sqlStore.ListAttributes(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.BlockNumber).To(Equal(uint64(100)))
+
+		attributes := []sqlitebitmapstore.AttributeInfo{}
+		for _, a := range res.Attributes {
+			if a.Name[0] != '$' {
+				attributes = append(attributes, a)
+			}
+		}
+		Expect(attributes).To(Equal([]sqlitebitmapstore.AttributeInfo{
+			{Name: "colour", Type: sqlitebitmapstore.StringAttribute, DistinctValues: 4, Entities: 5},
+			{Name: "size", Type: sqlitebitmapstore.StringAttribute, DistinctValues: 1, Entities: 5},
+			{Name: "size", Type: sqlitebitmapstore.NumericAttribute, DistinctValues: 2, Entities: 5},
+		}))
+
+		Expect(res.Attributes).To(ContainElement(sqlitebitmapstore.AttributeInfo{
+			Name: "$owner", Type: sqlitebitmapstore.StringAttribute, DistinctValues: 1, Entities: 5,
+		}))
+	})
+
+	It("should list the values of an attribute", func() {
+		res, err := sqlStore.ListAttributeValues(ctx, "colour", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Values).To(Equal([]sqlitebitmapstore.AttributeValue{
+			{Value: "", Entities: 1},
+			{Value: "green", Entities: 1},
+			{Value: "grey", Entities: 1},
+			{Value: "red", Entities: 2},
+		}))
+		Expect(res.Cursor).To(BeNil())
+
+		res, err = sqlStore.ListAttributeValues(ctx, "colour", &sqlitebitmapstore.ListAttributeValuesOptions{Prefix: "gr"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Values).To(Equal([]sqlitebitmapstore.AttributeValue{
+			{Value: "green", Entities: 1},
+			{Value: "grey", Entities: 1},
+		}))
+
+		res, err = sqlStore.ListAttributeValues(ctx, "size", &sqlitebitmapstore.ListAttributeValuesOptions{
+			Type: sqlitebitmapstore.NumericAttribute,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Values).To(Equal([]sqlitebitmapstore.AttributeValue{
+			{Value: store.NewNumericValue(0), Entities: 3},
+			{Value: store.NewNumericValue(1), Entities: 2},
+		}))
+		data, err := json.Marshal(res.Values)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`[{"value": "0", "entities": 3}, {"value": "1", "entities": 2}]`))
+
+		_, err = sqlStore.ListAttributeValues(ctx, "size", &sqlitebitmapstore.ListAttributeValuesOptions{
+			Type:   sqlitebitmapstore.NumericAttribute,
+			Prefix: "1",
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should paginate the values", func() {
+		for _, attributeType := range []sqlitebitmapstore.AttributeType{sqlitebitmapstore.StringAttribute, sqlitebitmapstore.NumericAttribute} {
+			name := "colour"
+			if attributeType == sqlitebitmapstore.NumericAttribute {
+				name = "size"
+			}
+
+			values := []any{}
+			options := &sqlitebitmapstore.ListAttributeValuesOptions{
+				Type:          attributeType,
+				ValuesPerPage: pointerOf(uint64(1)),
+			}
+			for {
+				res, err := sqlStore.ListAttributeValues(ctx, name, options)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(res.Values)).To(BeNumerically("<=", 1))
+				for _, v := range res.Values {
+					values = append(values, v.Value)
+				}
+				if res.Cursor == nil {
+					break
+				}
+				options.Cursor = *res.Cursor
+			}
+
+			if attributeType == sqlitebitmapstore.NumericAttribute {
+				Expect(values).To(Equal([]any{store.NewNumericValue(0), store.NewNumericValue(1)}))
+			} else {
+				Expect(values).To(Equal([]any{"", "green", "grey", "red"}))
+			}
+		}
+	})
+})
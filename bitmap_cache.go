@@ -15,111 +15,133 @@ type nameValue[T any] struct {
 	value T
 }
 
+// cachedBitmap is a bitmap and the cardinality that it has in the database,
+// for updating the attribute statistics when it is flushed.
+type cachedBitmap struct {
+	*store.Bitmap
+	storedCardinality uint64
+}
+
+// attributeStatsDelta is the change of the statistics of an attribute.
+type attributeStatsDelta struct {
+	distinctValues int64
+	cardinality    int64
+}
+
+func (d *attributeStatsDelta) add(b *cachedBitmap) {
+	cardinality := b.GetCardinality()
+	switch {
+	case b.storedCardinality == 0 && cardinality != 0:
+		d.distinctValues++
+	case b.storedCardinality != 0 && cardinality == 0:
+		d.distinctValues--
+	}
+	d.cardinality += int64(cardinality) - int64(b.storedCardinality)
+}
+
 type bitmapCache struct {
 	st store.Querier
 
-	stringBitmaps  map[nameValue[string]]*store.Bitmap
-	numericBitmaps map[nameValue[uint64]]*store.Bitmap
+	stringBitmaps  map[nameValue[string]]*cachedBitmap
+	numericBitmaps map[nameValue[store.NumericValue]]*cachedBitmap
 }
 
 func newBitmapCache(st store.Querier) *bitmapCache {
 	return &bitmapCache{
 		st:             st,
-		stringBitmaps:  make(map[nameValue[string]]*store.Bitmap),
-		numericBitmaps: make(map[nameValue[uint64]]*store.Bitmap),
+		stringBitmaps:  make(map[nameValue[string]]*cachedBitmap),
+		numericBitmaps: make(map[nameValue[store.NumericValue]]*cachedBitmap),
 	}
 }
 
-func (c *bitmapCache) AddToStringBitmap(ctx context.Context, name string, value string, id uint64) (err error) {
+func (c *bitmapCache) stringBitmap(ctx context.Context, name string, value string) (*cachedBitmap, error) {
 	k := nameValue[string]{name: name, value: value}
 	bitmap, ok := c.stringBitmaps[k]
-	if !ok {
-		bitmap, err = c.st.GetStringAttributeValueBitmap(ctx, store.GetStringAttributeValueBitmapParams{Name: name, Value: value})
-
-		if err != nil && err != sql.ErrNoRows {
-			return fmt.Errorf("failed to get string attribute %q value %q bitmap: %w", name, value, err)
-		}
+	if ok {
+		return bitmap, nil
+	}
 
-		if bitmap == nil {
-			bitmap = store.NewBitmap()
-		}
+	stored, err := c.st.GetStringAttributeValueBitmap(ctx, store.GetStringAttributeValueBitmapParams{Name: name, Value: value})
 
-		c.stringBitmaps[k] = bitmap
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get string attribute %q value %q bitmap: %w", name, value, err)
 	}
 
-	bitmap.Add(id)
+	if stored == nil {
+		stored = store.NewBitmap()
+	}
 
-	return nil
+	bitmap = &cachedBitmap{Bitmap: stored, storedCardinality: stored.GetCardinality()}
+	c.stringBitmaps[k] = bitmap
 
+	return bitmap, nil
 }
 
-func (c *bitmapCache) RemoveFromStringBitmap(ctx context.Context, name string, value string, id uint64) (err error) {
-	k := nameValue[string]{name: name, value: value}
-	bitmap, ok := c.stringBitmaps[k]
-	if !ok {
-		bitmap, err = c.st.GetStringAttributeValueBitmap(ctx, store.GetStringAttributeValueBitmapParams{Name: name, Value: value})
-
-		if err != nil && err != sql.ErrNoRows {
-			return fmt.Errorf("failed to get string attribute %q value %q bitmap: %w", name, value, err)
-		}
+func (c *bitmapCache) numericBitmap(ctx context.Context, name string, value store.NumericValue) (*cachedBitmap, error) {
+	k := nameValue[store.NumericValue]{name: name, value: value}
+	bitmap, ok := c.numericBitmaps[k]
+	if ok {
+		return bitmap, nil
+	}
 
-		if bitmap == nil {
-			bitmap = store.NewBitmap()
-		}
+	stored, err := c.st.GetNumericAttributeValueBitmap(ctx, store.GetNumericAttributeValueBitmapParams{Name: name, Value: value})
 
-		c.stringBitmaps[k] = bitmap
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get numeric attribute %q value %q bitmap: %w", name, value, err)
 	}
 
-	bitmap.Remove(id)
+	if stored == nil {
+		stored = store.NewBitmap()
+	}
 
-	return nil
+	bitmap = &cachedBitmap{Bitmap: stored, storedCardinality: stored.GetCardinality()}
+	c.numericBitmaps[k] = bitmap
 
+	return bitmap, nil
 }
 
-func (c *bitmapCache) AddToNumericBitmap(ctx context.Context, name string, value uint64, id uint64) (err error) {
-	k := nameValue[uint64]{name: name, value: value}
-	bitmap, ok := c.numericBitmaps[k]
-	if !ok {
-		bitmap, err = c.st.GetNumericAttributeValueBitmap(ctx, store.GetNumericAttributeValueBitmapParams{Name: name, Value: value})
+func (c *bitmapCache) AddToStringBitmap(ctx context.Context, name string, value string, id uint64) error {
+	bitmap, err := c.stringBitmap(ctx, name, value)
+	if err != nil {
+		return err
+	}
 
-		if err != nil && err != sql.ErrNoRows {
-			return fmt.Errorf("failed to get numeric attribute %q value %q bitmap: %w", name, value, err)
-		}
+	bitmap.Add(id)
 
-		if bitmap == nil {
-			bitmap = store.NewBitmap()
-		}
+	return nil
+}
 
-		c.numericBitmaps[k] = bitmap
+func (c *bitmapCache) RemoveFromStringBitmap(ctx context.Context, name string, value string, id uint64) error {
+	bitmap, err := c.stringBitmap(ctx, name, value)
+	if err != nil {
+		return err
 	}
 
-	bitmap.Add(id)
+	bitmap.Remove(id)
 
 	return nil
-
 }
 
-func (c *bitmapCache) RemoveFromNumericBitmap(ctx context.Context, name string, value uint64, id uint64) (err error) {
-	k := nameValue[uint64]{name: name, value: value}
-	bitmap, ok := c.numericBitmaps[k]
-	if !ok {
-		bitmap, err = c.st.GetNumericAttributeValueBitmap(ctx, store.GetNumericAttributeValueBitmapParams{Name: name, Value: value})
+func (c *bitmapCache) AddToNumericBitmap(ctx context.Context, name string, value store.NumericValue, id uint64) error {
+	bitmap, err := c.numericBitmap(ctx, name, value)
+	if err != nil {
+		return err
+	}
 
-		if err != nil && err != sql.ErrNoRows {
-			return fmt.Errorf("failed to get numeric attribute %q value %q bitmap: %w", name, value, err)
-		}
+	bitmap.Add(id)
 
-		if bitmap == nil {
-			bitmap = store.NewBitmap()
-		}
+	return nil
+}
 
-		c.numericBitmaps[k] = bitmap
+func (c *bitmapCache) RemoveFromNumericBitmap(ctx context.Context, name string, value store.NumericValue, id uint64) error {
+	bitmap, err := c.numericBitmap(ctx, name, value)
+	if err != nil {
+		return err
 	}
 
 	bitmap.Remove(id)
 
 	return nil
-
 }
 
 func (c *bitmapCache) Flush(ctx context.Context) (err error) {
@@ -153,8 +175,15 @@ func (c *bitmapCache) Flush(ctx context.Context) (err error) {
 		return fmt.Errorf("failed to run optimize: %w", err)
 	}
 
+	stringStats := map[string]*attributeStatsDelta{}
+
 	for k, bitmap := range c.stringBitmaps {
 
+		if stringStats[k.name] == nil {
+			stringStats[k.name] = &attributeStatsDelta{}
+		}
+		stringStats[k.name].add(bitmap)
+
 		if bitmap.IsEmpty() {
 			err = c.st.DeleteStringAttributeValueBitmap(ctx, store.DeleteStringAttributeValueBitmapParams{Name: k.name, Value: k.value})
 			if err != nil {
@@ -163,14 +192,21 @@ func (c *bitmapCache) Flush(ctx context.Context) (err error) {
 			continue
 		}
 
-		err = c.st.UpsertStringAttributeValueBitmap(ctx, store.UpsertStringAttributeValueBitmapParams{Name: k.name, Value: k.value, Bitmap: bitmap})
+		err = c.st.UpsertStringAttributeValueBitmap(ctx, store.UpsertStringAttributeValueBitmapParams{Name: k.name, Value: k.value, Bitmap: bitmap.Bitmap, Cardinality: bitmap.GetCardinality()})
 		if err != nil {
 			return fmt.Errorf("failed to upsert string attribute %q value %q bitmap: %w", k.name, k.value, err)
 		}
 	}
 
+	numericStats := map[string]*attributeStatsDelta{}
+
 	for k, bitmap := range c.numericBitmaps {
 
+		if numericStats[k.name] == nil {
+			numericStats[k.name] = &attributeStatsDelta{}
+		}
+		numericStats[k.name].add(bitmap)
+
 		if bitmap.IsEmpty() {
 			err = c.st.DeleteNumericAttributeValueBitmap(ctx, store.DeleteNumericAttributeValueBitmapParams{Name: k.name, Value: k.value})
 			if err != nil {
@@ -179,10 +215,31 @@ func (c *bitmapCache) Flush(ctx context.Context) (err error) {
 			continue
 		}
 
-		err = c.st.UpsertNumericAttributeValueBitmap(ctx, store.UpsertNumericAttributeValueBitmapParams{Name: k.name, Value: k.value, Bitmap: bitmap})
+		err = c.st.UpsertNumericAttributeValueBitmap(ctx, store.UpsertNumericAttributeValueBitmapParams{Name: k.name, Value: k.value, Bitmap: bitmap.Bitmap, Cardinality: bitmap.GetCardinality()})
 		if err != nil {
 			return fmt.Errorf("failed to upsert numeric attribute %q value %q bitmap: %w", k.name, k.value, err)
 		}
 	}
+
+	for name, delta := range stringStats {
+		if *delta == (attributeStatsDelta{}) {
+			continue
+		}
+		err = c.st.UpdateStringAttributeStats(ctx, store.UpdateStringAttributeStatsParams{Name: name, DistinctValuesDelta: delta.distinctValues, CardinalityDelta: delta.cardinality})
+		if err != nil {
+			return fmt.Errorf("failed to update string attribute %q stats: %w", name, err)
+		}
+	}
+
+	for name, delta := range numericStats {
+		if *delta == (attributeStatsDelta{}) {
+			continue
+		}
+		err = c.st.UpdateNumericAttributeStats(ctx, store.UpdateNumericAttributeStatsParams{Name: name, DistinctValuesDelta: delta.distinctValues, CardinalityDelta: delta.cardinality})
+		if err != nil {
+			return fmt.Errorf("failed to update numeric attribute %q stats: %w", name, err)
+		}
+	}
+
 	return nil
 }
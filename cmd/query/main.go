@@ -18,7 +18,9 @@ func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	cfg := struct {
-		dbPath string
+		dbPath          string
+		explain         bool
+		matchedBranches bool
 	}{}
 
 	app := &cli.App{
@@ -31,6 +33,16 @@ func main() {
 				Destination: &cfg.dbPath,
 				EnvVars:     []string{"DB_PATH"},
 			},
+			&cli.BoolFlag{
+				Name:        "explain",
+				Usage:       "report how the query was evaluated",
+				Destination: &cfg.explain,
+			},
+			&cli.BoolFlag{
+				Name:        "matched-branches",
+				Usage:       "annotate the entities with the conjunctions of the query that they match",
+				Destination: &cfg.matchedBranches,
+			},
 		},
 		Action: func(c *cli.Context) error {
 
@@ -70,7 +82,12 @@ func main() {
 						LastModifiedAtBlock:         true,
 						TransactionIndexInBlock:     true,
 						OperationIndexInTransaction: true,
+						PayloadSize:                 true,
+						PayloadHash:                 true,
+						Version:                     true,
 					},
+					Explain:         cfg.explain,
+					MatchedBranches: cfg.matchedBranches,
 				},
 			)
 
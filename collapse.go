@@ -0,0 +1,113 @@
+package sqlitebitmapstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+)
+
+// Collapse reduces the results of a query to at most one entity per value of
+// an attribute, like DISTINCT ON in SQL.
+type Collapse struct {
+	// Attribute is the attribute whose values the results are collapsed on.
+	// String and numeric values are distinct, and entities without the
+	// attribute are dropped.
+	Attribute string `json:"attribute"`
+	// OrderBy is the numeric attribute that decides which entity of a value
+	// is returned, the one with the largest value unless Ascending is set.
+	// Entities without it come last. If it is not set, and between entities
+	// with the same value, the entity that was created last wins, which is
+	// the first one in the order of the results.
+	OrderBy   string `json:"orderBy,omitempty"`
+	Ascending bool   `json:"ascending,omitempty"`
+}
+
+// collapse returns the entities of bitmap that are the first of their value
+// of the attribute. The entities are grouped with the value bitmaps of the
+// attribute, and ordered with the value bitmaps of OrderBy, so no payloads
+// are read.
+func (c *Collapse) collapse(
+	ctx context.Context,
+	queries *store.Queries,
+	bitmap *roaring64.Bitmap,
+) (*roaring64.Bitmap, error) {
+
+	if c.Attribute == "" {
+		return nil, fmt.Errorf("the attribute to collapse on is required")
+	}
+
+	strs, err := queries.EvaluateStringAttribute(ctx, c.Attribute)
+	if err != nil {
+		return nil, fmt.Errorf("error getting string attribute %q value bitmaps: %w", c.Attribute, err)
+	}
+	numerics, err := queries.EvaluateNumericAttribute(ctx, c.Attribute)
+	if err != nil {
+		return nil, fmt.Errorf("error getting numeric attribute %q value bitmaps: %w", c.Attribute, err)
+	}
+
+	groups := []*roaring64.Bitmap{}
+	for _, values := range append(strs, numerics...) {
+		group := roaring64.And(values.Bitmap, bitmap)
+		if !group.IsEmpty() {
+			groups = append(groups, group)
+		}
+	}
+
+	res := roaring64.New()
+
+	if c.OrderBy == "" {
+		for _, group := range groups {
+			res.Add(group.Maximum())
+		}
+		return res, nil
+	}
+
+	grouped := roaring64.New()
+	groupOf := map[uint64]int{}
+	for i, group := range groups {
+		grouped.Or(group)
+		it := group.Iterator()
+		for it.HasNext() {
+			groupOf[it.Next()] = i
+		}
+	}
+
+	orderValues, err := queries.GetNumericAttributeValueBitmaps(ctx, c.OrderBy)
+	if err != nil {
+		return nil, fmt.Errorf("error getting numeric attribute %q value bitmaps: %w", c.OrderBy, err)
+	}
+	if !c.Ascending {
+		slices.Reverse(orderValues)
+	}
+
+	// the values are visited in order, so the first entity of a group that
+	// is seen is the one to return
+	chosen := make([]bool, len(groups))
+	remaining := len(groups)
+
+	for _, v := range orderValues {
+		if remaining == 0 {
+			break
+		}
+		it := roaring64.And(v.Bitmap.Bitmap, grouped).ReverseIterator()
+		for it.HasNext() {
+			id := it.Next()
+			if i := groupOf[id]; !chosen[i] {
+				chosen[i] = true
+				remaining--
+				res.Add(id)
+			}
+		}
+	}
+
+	for i, group := range groups {
+		if !chosen[i] {
+			res.Add(group.Maximum())
+		}
+	}
+
+	return res, nil
+}
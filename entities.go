@@ -0,0 +1,141 @@
+package sqlitebitmapstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GetEntitiesKeyLimit is the largest number of keys that GetEntities looks up
+// at a time.
+const GetEntitiesKeyLimit = 1000
+
+// getEntitiesBatchSize is the number of keys that are looked up with one
+// statement, which keeps it below the SQLite limit on the number of
+// parameters.
+const getEntitiesBatchSize = 500
+
+// EntityNotFoundError is returned by GetEntity if no entity has the key at
+// the block of the read transaction.
+type EntityNotFoundError struct {
+	Key         common.Hash
+	BlockNumber uint64
+}
+
+func (e *EntityNotFoundError) Error() string {
+	return fmt.Sprintf("entity %s not found at block %d", e.Key.Hex(), e.BlockNumber)
+}
+
+type GetEntityResponse struct {
+	Entity      EntityData `json:"entity"`
+	BlockNumber uint64     `json:"blockNumber"`
+}
+
+type GetEntitiesResponse struct {
+	// Entities are the entities that were found, in the order of their keys.
+	Entities []EntityData `json:"entities"`
+	// Missing are the keys that no entity has, in their order.
+	Missing     []common.Hash `json:"missing"`
+	BlockNumber uint64        `json:"blockNumber"`
+}
+
+// GetEntity returns the entity with the key, or an EntityNotFoundError. The
+// key is looked up in the index of the payloads without evaluating a query.
+// Of the options only AtBlock and IncludeData are used.
+func (s *SQLiteStore) GetEntity(
+	ctx context.Context,
+	key common.Hash,
+	options *Options,
+) (*GetEntityResponse, error) {
+
+	res, err := s.GetEntities(ctx, []common.Hash{key}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Entities) == 0 {
+		return nil, &EntityNotFoundError{Key: key, BlockNumber: res.BlockNumber}
+	}
+
+	return &GetEntityResponse{Entity: res.Entities[0], BlockNumber: res.BlockNumber}, nil
+}
+
+// GetEntities returns the entities with the keys, and the keys that no entity
+// has. Keys that are given more than once are returned once. Of the options
+// only AtBlock and IncludeData are used.
+func (s *SQLiteStore) GetEntities(
+	ctx context.Context,
+	keys []common.Hash,
+	options *Options,
+) (*GetEntitiesResponse, error) {
+
+	if len(keys) > GetEntitiesKeyLimit {
+		return nil, fmt.Errorf("too many keys: %d, the limit is %d", len(keys), GetEntitiesKeyLimit)
+	}
+
+	err := s.waitForBlock(ctx, options.GetAtBlock())
+	if err != nil {
+		return nil, err
+	}
+
+	includeData := options.GetIncludeData()
+	projection := includeData.projection()
+
+	res := &GetEntitiesResponse{
+		Entities: []EntityData{},
+		Missing:  []common.Hash{},
+	}
+
+	found := map[common.Hash]*EntityData{}
+
+	err = s.ReadTransaction(ctx, func(queries *store.Queries) error {
+
+		lastBlock, err := queries.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		res.BlockNumber = lastBlock
+
+		for batch := range slices.Chunk(keys, getEntitiesBatchSize) {
+			entityKeys := make([][]byte, 0, len(batch))
+			for _, key := range batch {
+				entityKeys = append(entityKeys, key.Bytes())
+			}
+
+			payloads, err := queries.RetrieveProjectedPayloadsByKeys(ctx, entityKeys, projection)
+			if err != nil {
+				return fmt.Errorf("error retrieving payloads: %w", err)
+			}
+
+			for _, payload := range payloads {
+				found[common.BytesToHash(payload.EntityKey)] = toPayload(payload, includeData)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting entities: %w", err)
+	}
+
+	seen := map[common.Hash]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		ed, ok := found[key]
+		if !ok {
+			res.Missing = append(res.Missing, key)
+			continue
+		}
+		res.Entities = append(res.Entities, *ed)
+	}
+
+	return res, nil
+}
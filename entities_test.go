@@ -0,0 +1,135 @@
+package sqlitebitmapstore_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitebitmapstore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+var _ = Describe("entity lookup", func() {
+	var (
+		sqlStore *sqlitebitmapstore.SQLiteStore
+		tmpDir   string
+		ctx      context.Context
+		cancel   context.CancelFunc
+	)
+
+	keyOf := func(i int) common.Hash {
+		return common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "sqlitestore_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := slog.New(slog.NewTextHandler(GinkgoWriter, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel = context.WithCancel(context.Background())
+
+		operations := []events.Operation{}
+		for i := range 3 {
+			operations = append(operations, createOperation(
+				i,
+				map[string]string{"name": "entity"},
+				map[string]uint64{"index": uint64(i)},
+			))
+		}
+
+		followBlocks(ctx, sqlStore, events.Block{Number: 100, Operations: operations})
+	})
+
+	AfterEach(func() {
+		cancel()
+		if sqlStore != nil {
+			sqlStore.Close()
+		}
+		os.RemoveAll(tmpDir)
+	})
+
+	It("should get an entity by its key", func() {
+		res, err := sqlStore.GetEntity(ctx, keyOf(1), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.BlockNumber).To(Equal(uint64(100)))
+		Expect(*res.Entity.Key).To(Equal(keyOf(1)))
+		Expect(string(res.Entity.Value)).To(Equal("content"))
+		Expect(*res.Entity.ExpiresAt).To(Equal(uint64(200)))
+		Expect(res.Entity.StringAttributes).To(Equal([]sqlitebitmapstore.Attribute[string]{
+			{Key: "name", Value: "entity"},
+		}))
+		Expect(res.Entity.NumericAttributes).To(Equal([]sqlitebitmapstore.Attribute[store.NumericValue]{
+			{Key: "index", Value: store.NewNumericValue(1)},
+		}))
+
+		res, err = sqlStore.GetEntity(ctx, keyOf(2), &sqlitebitmapstore.Options{
+			IncludeData: &sqlitebitmapstore.IncludeData{Version: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Entity).To(Equal(sqlitebitmapstore.EntityData{Version: pointerOf(uint64(1))}))
+	})
+
+	It("should report a missing entity", func() {
+		_, err := sqlStore.GetEntity(ctx, keyOf(7), nil)
+		var notFound *sqlitebitmapstore.EntityNotFoundError
+		Expect(errors.As(err, &notFound)).To(BeTrue())
+		Expect(notFound.Key).To(Equal(keyOf(7)))
+		Expect(notFound.BlockNumber).To(Equal(uint64(100)))
+	})
+
+	It("should get entities by their keys", func() {
+		res, err := sqlStore.GetEntities(
+			ctx,
+			[]common.Hash{keyOf(2), keyOf(5), keyOf(0), keyOf(2), keyOf(6)},
+			&sqlitebitmapstore.Options{IncludeData: &sqlitebitmapstore.IncludeData{Key: true}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Entities).To(Equal([]sqlitebitmapstore.EntityData{
+			{Key: pointerOf(keyOf(2))},
+			{Key: pointerOf(keyOf(0))},
+		}))
+		Expect(res.Missing).To(Equal([]common.Hash{keyOf(5), keyOf(6)}))
+
+		res, err = sqlStore.GetEntities(ctx, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Entities).To(BeEmpty())
+		Expect(res.Missing).To(BeEmpty())
+
+		_, err = sqlStore.GetEntities(ctx, make([]common.Hash, sqlitebitmapstore.GetEntitiesKeyLimit+1), nil)
+		Expect(err).To(MatchError(ContainSubstring("too many keys")))
+	})
+
+	It("should wait for the requested block", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			time.Sleep(200 * time.Millisecond)
+			followBlocks(ctx, sqlStore, events.Block{
+				Number:     101,
+				Operations: []events.Operation{createOperation(3, map[string]string{"name": "late"}, nil)},
+			})
+		}()
+
+		res, err := sqlStore.GetEntity(ctx, keyOf(3), &sqlitebitmapstore.Options{AtBlock: pointerOf(uint64(101))})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.BlockNumber).To(Equal(uint64(101)))
+		Expect(res.Entity.StringAttributes).To(Equal([]sqlitebitmapstore.Attribute[string]{
+			{Key: "name", Value: "late"},
+		}))
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -0,0 +1,149 @@
+package sqlitebitmapstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// payloadBackfillBatchSize is the number of payloads that are loaded at a
+// time when backfilling their synthetic attributes.
+const payloadBackfillBatchSize = 1000
+
+// setPayloadAttributes sets the synthetic attributes that describe the
+// payload of an entity: its content type, its size in bytes and its keccak256
+// hash.
+func setPayloadAttributes(
+	stringAttributes map[string]string,
+	numericAttributes map[string]store.NumericValue,
+	contentType string,
+	payload []byte,
+) {
+	stringAttributes[query.ContentTypeAttributeKey] = contentType
+	stringAttributes[query.PayloadHashAttributeKey] = strings.ToLower(crypto.Keccak256Hash(payload).Hex())
+	numericAttributes[query.PayloadSizeAttributeKey] = store.NewNumericValue(uint64(len(payload)))
+}
+
+// backfillPayloadAttributes sets the payload attributes and the version of
+// the entities written before the store kept them. Their version starts at 1,
+// since the number of earlier updates is not known. It does nothing once every
+// entity has a version.
+//
+// Every batch of payloads is committed on its own, so that the memory used by
+// the bitmaps of a batch stays bounded and an interrupted backfill resumes
+// with the entities that do not have a version yet.
+func backfillPayloadAttributes(ctx context.Context, db *sql.DB, log *slog.Logger) error {
+	st := store.New(db)
+
+	entities, err := st.GetNumberOfEntities(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get number of entities: %w", err)
+	}
+
+	versions, err := attributeCatalog(ctx, st, []string{query.VersionAttributeKey})
+	if err != nil {
+		return fmt.Errorf("failed to get the statistics of %s: %w", query.VersionAttributeKey, err)
+	}
+	if uint64(entities) == versions[query.VersionAttributeKey].NumericEntities {
+		return nil
+	}
+
+	backfilled := 0
+	afterID := uint64(0)
+
+	for {
+		n, lastID, err := backfillPayloadAttributesBatch(ctx, db, afterID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		backfilled += n
+		afterID = lastID
+	}
+
+	log.Info("backfilled payload attributes", "entities", backfilled)
+
+	return nil
+}
+
+// backfillPayloadAttributesBatch sets the payload attributes of up to
+// payloadBackfillBatchSize entities without a version whose id is above
+// afterID in a transaction, and returns their number and the id of the last
+// one.
+func backfillPayloadAttributesBatch(ctx context.Context, db *sql.DB, afterID uint64) (int, uint64, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st := store.New(tx)
+
+	rows, err := st.GetPayloadsWithoutVersion(ctx, store.GetPayloadsWithoutVersionParams{
+		AfterID:   afterID,
+		BatchSize: payloadBackfillBatchSize,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get payloads: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, afterID, nil
+	}
+
+	cache := newBitmapCache(st)
+
+	for _, row := range rows {
+		afterID = row.ID
+
+		stringAttributes := map[string]string{}
+		numericAttributes := map[string]store.NumericValue{
+			query.VersionAttributeKey: store.NewNumericValue(1),
+		}
+		setPayloadAttributes(stringAttributes, numericAttributes, row.ContentType, row.Payload)
+
+		for k, v := range stringAttributes {
+			row.StringAttributes.Values[k] = v
+			err = cache.AddToStringBitmap(ctx, k, v, row.ID)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to add string attribute value bitmap: %w", err)
+			}
+		}
+
+		for k, v := range numericAttributes {
+			row.NumericAttributes.Values[k] = v
+			err = cache.AddToNumericBitmap(ctx, k, v, row.ID)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to add numeric attribute value bitmap: %w", err)
+			}
+		}
+
+		err = st.UpdatePayloadAttributes(ctx, store.UpdatePayloadAttributesParams{
+			StringAttributes:  row.StringAttributes,
+			NumericAttributes: row.NumericAttributes,
+			ID:                row.ID,
+		})
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to update payload %d: %w", row.ID, err)
+		}
+	}
+
+	err = cache.Flush(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to flush bitmap cache: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(rows), afterID, nil
+}
@@ -269,7 +269,7 @@ var _ = Describe("PushIterator", func() {
 				}
 				Expect(row.Payload).To(Equal([]byte("updated content")))
 				Expect(row.StringAttributes.Values["status"]).To(Equal("published"))
-				Expect(row.NumericAttributes.Values["version"]).To(Equal(uint64(2)))
+				Expect(row.NumericAttributes.Values["version"].Uint64()).To(Equal(uint64(2)))
 				return nil
 			})
 			Expect(err).NotTo(HaveOccurred())
@@ -392,7 +392,7 @@ var _ = Describe("PushIterator", func() {
 				if err != nil {
 					return err
 				}
-				originalExpiration = row.NumericAttributes.Values["$expiration"]
+				originalExpiration = row.NumericAttributes.Values["$expiration"].Uint64()
 				return nil
 			})
 			Expect(err).NotTo(HaveOccurred())
@@ -432,7 +432,7 @@ var _ = Describe("PushIterator", func() {
 				if err != nil {
 					return err
 				}
-				newExpiration := row.NumericAttributes.Values["$expiration"]
+				newExpiration := row.NumericAttributes.Values["$expiration"].Uint64()
 				Expect(newExpiration).To(Equal(uint64(1200)))
 				return nil
 			})
@@ -744,14 +744,14 @@ var _ = Describe("PushIterator", func() {
 				Expect(row.StringAttributes.Values["$creator"]).To(Equal(strings.ToLower(owner.Hex())))
 				Expect(row.StringAttributes.Values["$key"]).To(Equal(strings.ToLower(key.Hex())))
 
-				Expect(row.NumericAttributes.Values["$expiration"]).To(Equal(uint64(600)))
-				Expect(row.NumericAttributes.Values["$createdAtBlock"]).To(Equal(uint64(100)))
-				Expect(row.NumericAttributes.Values["$lastModifiedAtBlock"]).To(Equal(uint64(100)))
-				Expect(row.NumericAttributes.Values["$txIndex"]).To(Equal(uint64(5)))
-				Expect(row.NumericAttributes.Values["$opIndex"]).To(Equal(uint64(3)))
+				Expect(row.NumericAttributes.Values["$expiration"].Uint64()).To(Equal(uint64(600)))
+				Expect(row.NumericAttributes.Values["$createdAtBlock"].Uint64()).To(Equal(uint64(100)))
+				Expect(row.NumericAttributes.Values["$lastModifiedAtBlock"].Uint64()).To(Equal(uint64(100)))
+				Expect(row.NumericAttributes.Values["$txIndex"].Uint64()).To(Equal(uint64(5)))
+				Expect(row.NumericAttributes.Values["$opIndex"].Uint64()).To(Equal(uint64(3)))
 
 				expectedSequence := uint64(100)<<32 | uint64(5)<<16 | uint64(3)
-				Expect(row.NumericAttributes.Values["$sequence"]).To(Equal(expectedSequence))
+				Expect(row.NumericAttributes.Values["$sequence"].Uint64()).To(Equal(expectedSequence))
 
 				return nil
 			})
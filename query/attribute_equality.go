@@ -0,0 +1,269 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+)
+
+// AttributeComparisonMaxValues is the largest number of distinct values that
+// two compared attributes can have for the comparison to be evaluated over
+// the bitmap index. Comparisons of attributes with more values scan the
+// attributes of the entities instead.
+const AttributeComparisonMaxValues = 10000
+
+// AttributeComparisonMaxScan is the largest number of entities with both
+// attributes whose attributes a comparison scans. Comparisons that could scan
+// more, judged by the number of entities with the rarer attribute, fail before
+// anything is loaded.
+const AttributeComparisonMaxScan = 100000
+
+// attributeScanBatchSize is the number of entities whose attributes are loaded
+// at a time when a comparison scans them.
+const attributeScanBatchSize = 1000
+
+func (e *AttributeEquality) Normalize() *AttributeEquality {
+	if e.Other < e.Var {
+		// the comparison is symmetric, so the same comparison has one form
+		return &AttributeEquality{Var: e.Other, IsNot: e.IsNot, Other: e.Var}
+	}
+	return &AttributeEquality{Var: e.Var, IsNot: e.IsNot, Other: e.Other}
+}
+
+func (e *AttributeEquality) invert() *AttributeEquality {
+	return &AttributeEquality{
+		Var:   e.Var,
+		IsNot: !e.IsNot,
+		Other: e.Other,
+	}
+}
+
+// comparedStats are the statistics of the compared attributes for one type.
+type comparedStats struct {
+	numeric     bool
+	left, right attributeStatistics
+}
+
+// comparable reports whether some entities have both attributes with this
+// type.
+func (s comparedStats) comparable() bool {
+	return s.left.cardinality != 0 && s.right.cardinality != 0
+}
+
+// maxScanned returns the largest number of entities that a scan can compare,
+// the entities with the rarer of the attributes for each type.
+func maxScanned(all []comparedStats) uint64 {
+	n := uint64(0)
+	for _, s := range all {
+		if s.comparable() {
+			n += min(s.left.cardinality, s.right.cardinality)
+		}
+	}
+	return n
+}
+
+// stats returns the statistics of the attributes for each type.
+func (e *AttributeEquality) stats(ctx context.Context, q *store.Queries) ([]comparedStats, error) {
+	all := []comparedStats{}
+	for _, numeric := range []bool{false, true} {
+		left, err := attributeStats(ctx, q, e.Var, numeric)
+		if err != nil {
+			return nil, err
+		}
+		right, err := attributeStats(ctx, q, e.Other, numeric)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, comparedStats{numeric: numeric, left: left, right: right})
+	}
+	return all, nil
+}
+
+// plan estimates the comparison by the number of entities that can have both
+// attributes.
+func (e *AttributeEquality) plan(ctx context.Context, q *store.Queries, p plannedTerm) (plannedTerm, error) {
+	all, err := e.stats(ctx, q)
+	if err != nil {
+		return plannedTerm{}, err
+	}
+
+	for _, s := range all {
+		if s.comparable() {
+			p.estimate += min(s.left.cardinality, s.right.cardinality)
+			p.cost += s.left.distinctValues + s.right.distinctValues
+		}
+	}
+
+	return p, nil
+}
+
+// Evaluate joins the value bitmaps of the two attributes on their values. If
+// one of them has more than AttributeComparisonMaxValues values, the
+// attributes of the entities that have both are compared instead, as long as
+// at most AttributeComparisonMaxScan entities have the rarer attribute.
+func (e *AttributeEquality) Evaluate(
+	ctx context.Context,
+	q *store.Queries,
+) (*roaring64.Bitmap, error) {
+
+	all, err := e.stats(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := false
+	for _, s := range all {
+		if s.comparable() && max(s.left.distinctValues, s.right.distinctValues) > AttributeComparisonMaxValues {
+			scan = true
+		}
+	}
+
+	if scan {
+		if n := maxScanned(all); n > AttributeComparisonMaxScan {
+			return nil, fmt.Errorf(
+				"comparing %s and %s could scan %d entities, the limit is %d",
+				e.Var, e.Other, n, AttributeComparisonMaxScan,
+			)
+		}
+
+		candidates := roaring64.New()
+		for _, s := range all {
+			if !s.comparable() {
+				continue
+			}
+			both, err := e.both(ctx, q, s.numeric)
+			if err != nil {
+				return nil, err
+			}
+			candidates.Or(both)
+		}
+		return e.scan(ctx, q, candidates)
+	}
+
+	res := roaring64.New()
+	for _, s := range all {
+		if !s.comparable() {
+			continue
+		}
+		bm, err := e.evaluate(ctx, q, s.numeric)
+		if err != nil {
+			return nil, err
+		}
+		res.Or(bm)
+	}
+
+	return res, nil
+}
+
+// both returns the entities that have both attributes with values of one
+// type.
+func (e *AttributeEquality) both(
+	ctx context.Context,
+	q *store.Queries,
+	numeric bool,
+) (*roaring64.Bitmap, error) {
+
+	evaluateAttribute := q.EvaluateStringAttribute
+	if numeric {
+		evaluateAttribute = q.EvaluateNumericAttribute
+	}
+
+	left, err := evaluateAttribute(ctx, e.Var)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evaluateAttribute(ctx, e.Other)
+	if err != nil {
+		return nil, err
+	}
+
+	both := union(ctx, left)
+	both.And(union(ctx, right))
+
+	return both, nil
+}
+
+// evaluate compares the values of one type over the bitmap index.
+func (e *AttributeEquality) evaluate(
+	ctx context.Context,
+	q *store.Queries,
+	numeric bool,
+) (*roaring64.Bitmap, error) {
+
+	var pairs [][2]*store.Bitmap
+	if numeric {
+		rows, err := q.EvaluateNumericAttributesEqual(ctx, store.EvaluateNumericAttributesEqualParams{Name: e.Var, Other: e.Other})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			pairs = append(pairs, [2]*store.Bitmap{row.Bitmap, row.OtherBitmap})
+		}
+	} else {
+		rows, err := q.EvaluateStringAttributesEqual(ctx, store.EvaluateStringAttributesEqualParams{Name: e.Var, Other: e.Other})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			pairs = append(pairs, [2]*store.Bitmap{row.Bitmap, row.OtherBitmap})
+		}
+	}
+
+	equal := roaring64.New()
+	for _, pair := range pairs {
+		traceBitmaps(ctx, pair[0], pair[1])
+		equal.Or(roaring64.And(pair[0].Bitmap, pair[1].Bitmap))
+	}
+
+	if !e.IsNot {
+		return equal, nil
+	}
+
+	// the entities with both attributes whose values differ
+	both, err := e.both(ctx, q, numeric)
+	if err != nil {
+		return nil, err
+	}
+	both.AndNot(equal)
+
+	return both, nil
+}
+
+// scan compares the attributes of the candidate entities, the ones that have
+// both attributes.
+func (e *AttributeEquality) scan(
+	ctx context.Context,
+	q *store.Queries,
+	candidates *roaring64.Bitmap,
+) (*roaring64.Bitmap, error) {
+
+	res := roaring64.New()
+
+	for ids := range slices.Chunk(candidates.ToArray(), attributeScanBatchSize) {
+		rows, err := q.ScanAttributes(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attributes: %w", err)
+		}
+
+		for _, row := range rows {
+			strs := row.StringAttributes.Values
+			left, leftOK := strs[e.Var]
+			right, rightOK := strs[e.Other]
+			if leftOK && rightOK && (left == right) != e.IsNot {
+				res.Add(row.ID)
+			}
+
+			numbers := row.NumericAttributes.Values
+			leftNumber, leftOK := numbers[e.Var]
+			rightNumber, rightOK := numbers[e.Other]
+			if leftOK && rightOK && (leftNumber.Cmp(rightNumber) == 0) != e.IsNot {
+				res.Add(row.ID)
+			}
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeEquality(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		v, err := Parse(`$owner = $creator && b != a && c = "a"`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{AttributeEqual: &AttributeEquality{Var: CreatorAttributeKey, Other: OwnerAttributeKey}},
+				{AttributeEqual: &AttributeEquality{Var: "a", IsNot: true, Other: "b"}},
+				{Assign: &Equality{Var: "c", Value: Value{String: pointerOf("a")}}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+
+		require.Equal(t, `$creator = $owner && a != b && c = "a"`, v.String())
+	})
+
+	t.Run("negation", func(t *testing.T) {
+		v, err := Parse(`!(a = b || c != d)`)
+		require.NoError(t, err)
+		require.Equal(t, `a != b && c = d`, v.String())
+	})
+
+	t.Run("simplify", func(t *testing.T) {
+		v, err := Parse(`a = b && b = a && a = 1`)
+		require.NoError(t, err)
+		require.Equal(t, `a = 1 && a = b`, v.Simplify().String())
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		catalog := Catalog{
+			"name":  {StringEntities: 2},
+			"title": {StringEntities: 2},
+			"size":  {NumericEntities: 2},
+		}
+
+		v, err := ParseWithOptions(
+			`name = title || name = size || name != colour || $owner = $creator`,
+			ParseOptions{Validation: &Validation{Catalog: catalog}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []string{"colour", "name", "size", "title"}, v.Attributes())
+		require.Equal(t, []ValidationWarning{
+			{Term: `name = size`, Attribute: "name", Message: `the attributes "name" and "size" never have values of the same type`},
+			{Term: `colour != name`, Attribute: "colour", Message: `no entity has the attribute "colour"`},
+		}, v.Warnings)
+	})
+
+	t.Run("scan limit", func(t *testing.T) {
+		stats := func(left, right uint64) comparedStats {
+			return comparedStats{
+				left:  attributeStatistics{distinctValues: left, cardinality: left},
+				right: attributeStatistics{distinctValues: right, cardinality: right},
+			}
+		}
+
+		require.Equal(t, uint64(30), maxScanned([]comparedStats{stats(20000, 10), stats(20, 50000)}))
+		// types that only one of the attributes has are not scanned
+		require.Equal(t, uint64(10), maxScanned([]comparedStats{stats(20000, 10), stats(0, 50000)}))
+		require.Greater(t, maxScanned([]comparedStats{stats(200000, AttributeComparisonMaxScan), stats(1, 1)}), uint64(AttributeComparisonMaxScan))
+	})
+
+	t.Run("JSON filters and builder", func(t *testing.T) {
+		v, err := Parse(`a = b || $owner != $creator`)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"or": [
+			{"attribute": "a", "op": "=", "otherAttribute": "b"},
+			{"attribute": "$creator", "op": "!=", "otherAttribute": "$owner"}
+		]}`, string(data))
+
+		parsed, err := ParseJSON(data)
+		require.NoError(t, err)
+		require.Equal(t, v, parsed)
+
+		built, err := Build(Or(EqAttribute("b", "a"), NeqAttribute(OwnerAttributeKey, CreatorAttributeKey)))
+		require.NoError(t, err)
+		require.Equal(t, v, built)
+
+		_, err = ParseJSON([]byte(`{"attribute": "a", "op": "<", "otherAttribute": "b"}`))
+		require.Error(t, err)
+		_, err = ParseJSON([]byte(`{"attribute": "a", "op": "=", "value": 1, "otherAttribute": "b"}`))
+		require.Error(t, err)
+	})
+}
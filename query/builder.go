@@ -0,0 +1,267 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// The builder functions construct queries without going through the string
+// grammar:
+//
+//	query.Build(query.And(query.Eq("type", "nft"), query.Gt("price", 100)))
+//
+// Values are converted like bind arguments, so strings become string values
+// and Go numbers become numeric values. Errors are reported by Build.
+
+// Build normalises an expression into the AST that the parser emits for the
+// equivalent query. A nil expression matches all entities.
+func Build(e *Expression) (*AST, error) {
+	return BuildWithOptions(e, ParseOptions{})
+}
+
+// BuildWithOptions is Build with the MaxConjunctions limit of options, the
+// grammar version is ignored.
+func BuildWithOptions(e *Expression, options ParseOptions) (*AST, error) {
+	if e == nil {
+		return &AST{}, nil
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if err := checkConjunctions(e, options.MaxConjunctions); err != nil {
+		return nil, err
+	}
+
+	return (&TopLevel{Expression: e}).Normalize(), nil
+}
+
+// And matches the entities that match all expressions.
+func And(exprs ...*Expression) *Expression {
+	if err := buildError("And", exprs); err != nil {
+		return failed(err)
+	}
+
+	and := AndExpression{Left: group(exprs[0])}
+	for _, e := range exprs[1:] {
+		and.Right = append(and.Right, &AndRHS{Expr: group(e)})
+	}
+
+	return &Expression{Or: OrExpression{Left: and}}
+}
+
+// Or matches the entities that match any of the expressions.
+func Or(exprs ...*Expression) *Expression {
+	if err := buildError("Or", exprs); err != nil {
+		return failed(err)
+	}
+
+	or := OrExpression{Left: AndExpression{Left: group(exprs[0])}}
+	for _, e := range exprs[1:] {
+		or.Right = append(or.Right, &OrRHS{Expr: AndExpression{Left: group(e)}})
+	}
+
+	return &Expression{Or: or}
+}
+
+// Not matches the entities that do not match the expression.
+func Not(e *Expression) *Expression {
+	if err := buildError("Not", []*Expression{e}); err != nil {
+		return failed(err)
+	}
+
+	return term(EqualExpr{Paren: &Paren{IsNot: true, Nested: *e}})
+}
+
+func Eq(name string, value any) *Expression {
+	return equality(name, false, value)
+}
+
+func Neq(name string, value any) *Expression {
+	return equality(name, true, value)
+}
+
+func Lt(name string, value any) *Expression {
+	v, err := builderValue(name, value)
+	if err != nil {
+		return failed(err)
+	}
+	return term(EqualExpr{LessThan: &LessThan{Var: name, Value: v}})
+}
+
+func Lte(name string, value any) *Expression {
+	v, err := builderValue(name, value)
+	if err != nil {
+		return failed(err)
+	}
+	return term(EqualExpr{LessOrEqualThan: &LessOrEqualThan{Var: name, Value: v}})
+}
+
+func Gt(name string, value any) *Expression {
+	v, err := builderValue(name, value)
+	if err != nil {
+		return failed(err)
+	}
+	return term(EqualExpr{GreaterThan: &GreaterThan{Var: name, Value: v}})
+}
+
+func Gte(name string, value any) *Expression {
+	v, err := builderValue(name, value)
+	if err != nil {
+		return failed(err)
+	}
+	return term(EqualExpr{GreaterOrEqualThan: &GreaterOrEqualThan{Var: name, Value: v}})
+}
+
+// In matches the entities whose attribute has one of the values. Slices are
+// expanded into their elements.
+func In(name string, values ...any) *Expression {
+	return inclusion(name, false, values)
+}
+
+func NotIn(name string, values ...any) *Expression {
+	return inclusion(name, true, values)
+}
+
+// EqAttribute matches the entities whose attributes name and other have the
+// same type and value.
+func EqAttribute(name string, other string) *Expression {
+	return term(EqualExpr{AttributeEqual: &AttributeEquality{Var: name, Other: other}})
+}
+
+// NeqAttribute matches the entities whose attributes name and other have the
+// same type and different values.
+func NeqAttribute(name string, other string) *Expression {
+	return term(EqualExpr{AttributeEqual: &AttributeEquality{Var: name, IsNot: true, Other: other}})
+}
+
+// InKeysOf matches the entities whose attribute is the key of an entity that
+// matches where, like IN (select $key where ...).
+func InKeysOf(name string, where *Expression) *Expression {
+	return keysOf("InKeysOf", name, false, where)
+}
+
+func NotInKeysOf(name string, where *Expression) *Expression {
+	return keysOf("NotInKeysOf", name, true, where)
+}
+
+// GlobMatch matches string values against a glob pattern, like ~.
+func GlobMatch(name string, pattern string) *Expression {
+	return term(EqualExpr{Glob: &Glob{Var: name, Value: pattern}})
+}
+
+func NotGlobMatch(name string, pattern string) *Expression {
+	return term(EqualExpr{Glob: &Glob{Var: name, IsNot: true, Value: pattern}})
+}
+
+// EqualFold compares string values ignoring case, like =*.
+func EqualFold(name string, value string) *Expression {
+	return term(EqualExpr{CaseEqual: &CaseEquality{Var: name, Value: value}})
+}
+
+func NotEqualFold(name string, value string) *Expression {
+	return term(EqualExpr{CaseEqual: &CaseEquality{Var: name, IsNot: true, Value: value}})
+}
+
+// HasPrefix matches string values starting with prefix, like ^=.
+func HasPrefix(name string, prefix string) *Expression {
+	return term(EqualExpr{Prefix: &Prefix{Var: name, Value: prefix}})
+}
+
+func NotHasPrefix(name string, prefix string) *Expression {
+	return term(EqualExpr{Prefix: &Prefix{Var: name, IsNot: true, Value: prefix}})
+}
+
+// RegexMatch matches string values against an RE2 regular expression, like
+// =~.
+func RegexMatch(name string, pattern string) *Expression {
+	return regex(name, false, pattern)
+}
+
+func NotRegexMatch(name string, pattern string) *Expression {
+	return regex(name, true, pattern)
+}
+
+func equality(name string, isNot bool, value any) *Expression {
+	v, err := builderValue(name, value)
+	if err != nil {
+		return failed(err)
+	}
+	return term(EqualExpr{Assign: &Equality{Var: name, IsNot: isNot, Value: v}})
+}
+
+func inclusion(name string, isNot bool, values []any) *Expression {
+	vs := []Value{}
+	for _, value := range values {
+		for _, elem := range expandArg(value) {
+			v, err := builderValue(name, elem)
+			if err != nil {
+				return failed(err)
+			}
+			vs = append(vs, v)
+		}
+	}
+
+	list, err := listValues(vs)
+	if err != nil {
+		return failed(fmt.Errorf("%s: %w", name, err))
+	}
+
+	return term(EqualExpr{Inclusion: &Inclusion{Var: name, IsNot: isNot, Values: list}})
+}
+
+func keysOf(op string, name string, isNot bool, where *Expression) *Expression {
+	if err := buildError(op, []*Expression{where}); err != nil {
+		return failed(err)
+	}
+
+	sub := &Subquery{Select: KeyAttributeKey, Where: where}
+	return term(EqualExpr{Inclusion: &Inclusion{Var: name, IsNot: isNot, Values: Values{Subquery: sub}}})
+}
+
+func regex(name string, isNot bool, pattern string) *Expression {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return failed(fmt.Errorf("%s: invalid regular expression %q: %w", name, pattern, err))
+	}
+	return term(EqualExpr{Regex: &Regex{Var: name, IsNot: isNot, Value: RegexPattern(pattern)}})
+}
+
+func builderValue(name string, value any) (Value, error) {
+	v, err := argValue(value)
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+func buildError(op string, exprs []*Expression) error {
+	if len(exprs) == 0 {
+		return fmt.Errorf("%s needs at least one expression", op)
+	}
+
+	errs := []error{}
+	for _, e := range exprs {
+		if e == nil {
+			return fmt.Errorf("%s of a nil expression", op)
+		}
+		errs = append(errs, e.err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func failed(err error) *Expression {
+	return &Expression{err: err}
+}
+
+func term(e EqualExpr) *Expression {
+	return &Expression{Or: OrExpression{Left: AndExpression{Left: e}}}
+}
+
+// group returns e as a single term, in parentheses unless it already is one.
+func group(e *Expression) EqualExpr {
+	if len(e.Or.Right) == 0 && len(e.Or.Left.Right) == 0 {
+		return e.Or.Left.Left
+	}
+	return EqualExpr{Paren: &Paren{Nested: *e}}
+}
@@ -0,0 +1,154 @@
+package query
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMaxConjunctions is the largest number of conjunctions that the
+// disjunctive normal form of a query can have unless ParseOptions say
+// otherwise.
+const DefaultMaxConjunctions = 4096
+
+// TooManyConjunctionsError is returned for queries whose disjunctive normal
+// form would be too large. Normalising such a query could exhaust CPU and
+// memory, since the size of the normal form can be exponential in the size of
+// the query.
+type TooManyConjunctionsError struct {
+	// Conjunctions is the number of conjunctions of the normal form, or
+	// math.MaxUint64 if it is even larger.
+	Conjunctions uint64
+	Limit        int
+}
+
+func (e *TooManyConjunctionsError) Error() string {
+	conjunctions := fmt.Sprint(e.Conjunctions)
+	if e.Conjunctions == math.MaxUint64 {
+		conjunctions = "more than " + conjunctions
+	}
+	return fmt.Sprintf("query is too complex: its normal form has %s conjunctions, the limit is %d", conjunctions, e.Limit)
+}
+
+// checkConjunctions returns a TooManyConjunctionsError if the normal form of e
+// has more than limit conjunctions. The size is computed without building the
+// normal form.
+func checkConjunctions(e *Expression, limit int) error {
+	if limit <= 0 {
+		limit = DefaultMaxConjunctions
+	}
+
+	n := e.conjunctions(false)
+	if n > uint64(limit) {
+		return &TooManyConjunctionsError{Conjunctions: n, Limit: limit}
+	}
+
+	return e.checkSubqueries(limit)
+}
+
+// checkSubqueries checks the subqueries of the expression, which are
+// normalised on their own.
+func (e *Expression) checkSubqueries(limit int) error {
+	ands := []*AndExpression{&e.Or.Left}
+	for _, rhs := range e.Or.Right {
+		ands = append(ands, &rhs.Expr)
+	}
+
+	for _, and := range ands {
+		terms := []*EqualExpr{&and.Left}
+		for _, rhs := range and.Right {
+			terms = append(terms, &rhs.Expr)
+		}
+		for _, term := range terms {
+			if err := term.checkSubqueries(limit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *EqualExpr) checkSubqueries(limit int) error {
+	switch {
+	case e.Paren != nil:
+		return e.Paren.Nested.checkSubqueries(limit)
+	case e.Not != nil:
+		return e.Not.checkSubqueries(limit)
+	case e.Inclusion != nil && e.Inclusion.Values.Subquery != nil && !e.Inclusion.Values.Subquery.None:
+		return checkConjunctions(e.Inclusion.Values.Subquery.Where, limit)
+	}
+	return nil
+}
+
+// conjunctions returns the number of conjunctions of the normal form of the
+// expression, or of its negation if negated is set. By De Morgan's laws a
+// negated OR is an AND of negations and the other way round, so the roles of
+// sums and products swap under negation.
+func (e *Expression) conjunctions(negated bool) uint64 {
+	ands := []*AndExpression{&e.Or.Left}
+	for _, rhs := range e.Or.Right {
+		ands = append(ands, &rhs.Expr)
+	}
+
+	var n uint64
+	for i, and := range ands {
+		c := and.conjunctions(negated)
+		switch {
+		case i == 0:
+			n = c
+		case negated:
+			n = saturatingMul(n, c)
+		default:
+			n = saturatingAdd(n, c)
+		}
+	}
+
+	return n
+}
+
+func (e *AndExpression) conjunctions(negated bool) uint64 {
+	terms := []*EqualExpr{&e.Left}
+	for _, rhs := range e.Right {
+		terms = append(terms, &rhs.Expr)
+	}
+
+	var n uint64
+	for i, term := range terms {
+		c := term.conjunctions(negated)
+		switch {
+		case i == 0:
+			n = c
+		case negated:
+			n = saturatingAdd(n, c)
+		default:
+			n = saturatingMul(n, c)
+		}
+	}
+
+	return n
+}
+
+func (e *EqualExpr) conjunctions(negated bool) uint64 {
+	switch {
+	case e.Paren != nil:
+		return e.Paren.Nested.conjunctions(negated != e.Paren.IsNot)
+	case e.Not != nil:
+		return e.Not.conjunctions(!negated)
+	default:
+		return 1
+	}
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+func saturatingMul(a, b uint64) uint64 {
+	if a != 0 && b > math.MaxUint64/a {
+		return math.MaxUint64
+	}
+	return a * b
+}
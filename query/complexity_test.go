@@ -0,0 +1,94 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// explodingQuery returns (a0 || b0) && (a1 || b1) && ..., whose normal form
+// has 2^n conjunctions.
+func explodingQuery(n int) string {
+	groups := []string{}
+	for i := range n {
+		groups = append(groups, fmt.Sprintf("(a%d = 1 || b%d = 1)", i, i))
+	}
+	return strings.Join(groups, " && ")
+}
+
+func TestConjunctions(t *testing.T) {
+	for _, tc := range []struct {
+		query        string
+		conjunctions uint64
+	}{
+		{`a = 1`, 1},
+		{`a = 1 && b = 1`, 1},
+		{`a = 1 || b = 1 || c = 1`, 3},
+		{`(a = 1 || b = 1) && (c = 1 || d = 1 || e = 1)`, 6},
+		{`!(a = 1 && b = 1 && c = 1)`, 3},
+		{`!((a = 1 || b = 1) && (c = 1 || d = 1))`, 2},
+		{`!(a = 1 || b = 1) && (c = 1 || d = 1)`, 2},
+		{`!(!(a = 1 || b = 1) || (c = 1 && d = 1))`, 4},
+		{explodingQuery(10), 1024},
+		{explodingQuery(64), math.MaxUint64},
+		{explodingQuery(100), math.MaxUint64},
+	} {
+		t.Run(tc.query[:min(len(tc.query), 60)], func(t *testing.T) {
+			v, err := Parser.ParseString("", tc.query)
+			require.NoError(t, err)
+			require.Equal(t, tc.conjunctions, v.Expression.conjunctions(false))
+
+			if tc.conjunctions <= 1024 {
+				ast := v.Normalize()
+				require.Len(t, ast.Expr.Or.Terms, int(tc.conjunctions))
+			}
+		})
+	}
+}
+
+func TestMaxConjunctions(t *testing.T) {
+	t.Run("default limit", func(t *testing.T) {
+		_, err := Parse(explodingQuery(12))
+		require.NoError(t, err)
+
+		_, err = Parse(explodingQuery(100))
+		var tooMany *TooManyConjunctionsError
+		require.True(t, errors.As(err, &tooMany))
+		require.Equal(t, uint64(math.MaxUint64), tooMany.Conjunctions)
+		require.Equal(t, DefaultMaxConjunctions, tooMany.Limit)
+	})
+
+	t.Run("configured limit", func(t *testing.T) {
+		_, err := ParseWithOptions(explodingQuery(3), ParseOptions{MaxConjunctions: 8})
+		require.NoError(t, err)
+
+		_, err = ParseWithOptions(explodingQuery(4), ParseOptions{MaxConjunctions: 8})
+		require.EqualError(t, err, "query is too complex: its normal form has 16 conjunctions, the limit is 8")
+	})
+
+	t.Run("builder and JSON filters", func(t *testing.T) {
+		ors := []*Expression{}
+		for i := range 4 {
+			ors = append(ors, Or(Eq(fmt.Sprintf("a%d", i), 1), Eq(fmt.Sprintf("b%d", i), 1)))
+		}
+
+		_, err := BuildWithOptions(Not(Or(ors...)), ParseOptions{MaxConjunctions: 8})
+		require.NoError(t, err)
+
+		_, err = BuildWithOptions(And(ors...), ParseOptions{MaxConjunctions: 8})
+		var tooMany *TooManyConjunctionsError
+		require.True(t, errors.As(err, &tooMany))
+
+		filter := `{"and": [
+			{"or": [{"attribute": "a", "op": "=", "value": 1}, {"attribute": "b", "op": "=", "value": 1}]},
+			{"or": [{"attribute": "c", "op": "=", "value": 1}, {"attribute": "d", "op": "=", "value": 1}]}
+		]}`
+		_, err = ParseJSONWithOptions([]byte(filter), ParseOptions{MaxConjunctions: 3})
+		require.True(t, errors.As(err, &tooMany))
+		require.Equal(t, uint64(4), tooMany.Conjunctions)
+	})
+}
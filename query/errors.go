@@ -0,0 +1,224 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// ParseError is returned for queries that are not valid in their grammar
+// version and for invalid JSON filters. Other errors, like arguments that do
+// not match their placeholders or queries that are too complex, are not
+// ParseErrors.
+type ParseError struct {
+	// Line and Column are the 1-based position of the error in the query,
+	// and Offset its byte offset. They are zero if the error has no position,
+	// like for JSON filters that are valid JSON but not valid filters, or for
+	// placeholders that are used with both string and numeric values.
+	Line   int
+	Column int
+	Offset int
+	// Unexpected is the text where the query stopped being valid, empty at
+	// the end of the query.
+	Unexpected string
+	// Expected are the alternatives that the grammar allows at the position
+	// of the error, if they are known.
+	Expected []string
+	// Message describes the error without its position.
+	Message string
+
+	err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
+
+// newParseError converts the errors of the parser and the lexer into a
+// ParseError, other errors are returned unchanged.
+func newParseError(query string, err error) error {
+	var perr participle.Error
+	if !errors.As(err, &perr) {
+		return err
+	}
+
+	pos := perr.Position()
+	e := &ParseError{
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Offset:  pos.Offset,
+		Message: perr.Message(),
+		err:     err,
+	}
+
+	var unexpected *participle.UnexpectedTokenError
+	var lexErr *lexer.Error
+	switch {
+	case errors.As(err, &unexpected):
+		if !unexpected.Unexpected.EOF() {
+			e.Unexpected = unexpected.Unexpected.String()
+		}
+		e.Expected = expectedAlternatives(perr.Message())
+	case errors.As(err, &lexErr):
+		e.Unexpected = textAt(query, pos.Offset)
+		e.Message = fmt.Sprintf("invalid input text %q", e.Unexpected)
+	default:
+		// values that the parser could not convert, like invalid regular
+		// expressions
+		e.Unexpected = textAt(query, pos.Offset)
+	}
+
+	return e
+}
+
+// newFilterError converts the errors of JSON filters into a ParseError. JSON
+// syntax errors have the position of the invalid character, or of the end of
+// the filter if it ends early.
+func newFilterError(data []byte, err error) *ParseError {
+	e := &ParseError{
+		Message: err.Error(),
+		err:     err,
+	}
+
+	offset := -1
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = max(int(syntaxErr.Offset)-1, 0)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		offset = len(data)
+	}
+
+	if offset >= 0 {
+		before := data[:offset]
+		e.Offset = offset
+		e.Line = bytes.Count(before, []byte("\n")) + 1
+		e.Column = utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+		e.Unexpected = textAt(string(data), offset)
+	}
+
+	return e
+}
+
+// newVersionError returns the ParseError for a rule at pos that the grammar
+// version does not have.
+func newVersionError(query string, pos lexer.Position, rule string, version GrammarVersion) *ParseError {
+	return &ParseError{
+		Line:       pos.Line,
+		Column:     pos.Column,
+		Offset:     pos.Offset,
+		Unexpected: textAt(query, pos.Offset),
+		Message:    fmt.Sprintf("%s are not supported in grammar version %d", rule, version),
+	}
+}
+
+// textAt returns the text of the query from offset to the next whitespace.
+// The lexer does not know where an invalid token ends, so it is taken to end
+// there.
+func textAt(query string, offset int) string {
+	if offset >= len(query) {
+		return ""
+	}
+	rest := strings.TrimLeftFunc(query[offset:], unicode.IsSpace)
+	if end := strings.IndexFunc(rest, unicode.IsSpace); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest
+}
+
+// expectedAlternatives extracts the alternatives from the message of an
+// UnexpectedTokenError, which ends in "(expected <grammar>)". The grammar is
+// split at the | that are not nested in parentheses.
+func expectedAlternatives(message string) []string {
+	_, expected, ok := strings.Cut(message, " (expected ")
+	if !ok {
+		return nil
+	}
+	expected = strings.TrimSuffix(expected, ")")
+
+	if isParenthesized(expected) {
+		expected = expected[1 : len(expected)-1]
+	}
+
+	alternatives := []string{}
+	depth, start := 0, 0
+	inString := false
+	for i, r := range expected {
+		switch {
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == '|' && depth == 0:
+			alternatives = append(alternatives, strings.TrimSpace(expected[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(alternatives, strings.TrimSpace(expected[start:]))
+}
+
+// isParenthesized reports whether s is a single group in parentheses.
+func isParenthesized(s string) bool {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return false
+	}
+
+	depth := 0
+	inString := false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 && i != len(s)-1 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// BindError is returned by Prepared.Bind for arguments that do not match the
+// placeholders of the query, like missing arguments, arguments of the wrong
+// type or arguments that are not valid values.
+type BindError struct {
+	Message string
+
+	err error
+}
+
+func (e *BindError) Error() string {
+	return e.Message
+}
+
+func (e *BindError) Unwrap() error {
+	return e.err
+}
+
+func newBindError(err error) *BindError {
+	return &BindError{Message: err.Error(), err: err}
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseError(t *testing.T) {
+	for _, tc := range []struct {
+		query      string
+		line       int
+		column     int
+		unexpected string
+		expected   []string
+	}{
+		{query: `name = `, line: 1, column: 8, expected: []string{"Value"}},
+		{query: `name == 1`, line: 1, column: 7, unexpected: "=", expected: []string{"Value"}},
+		{query: `(name = 1`, line: 1, column: 10, expected: []string{"<rparen>"}},
+		{query: `name ~ 1`, line: 1, column: 8, unexpected: "1", expected: []string{"<string>", "Param"}},
+		{query: "name = 1\n  && other ?= 2", line: 2, column: 12, unexpected: "?", expected: []string{`"REGEXP"`, `"regexp"`}},
+		{query: `name = 1 other`, line: 1, column: 10, unexpected: "other"},
+		{query: `name = "open`, line: 1, column: 8, unexpected: `"open`},
+		{query: `name =~ "(foo"`, line: 1, column: 8, unexpected: `"(foo"`},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := Parse(tc.query)
+
+			var perr *ParseError
+			require.True(t, errors.As(err, &perr), "%v", err)
+			require.Equal(t, tc.line, perr.Line)
+			require.Equal(t, tc.column, perr.Column)
+			require.Equal(t, tc.unexpected, perr.Unexpected)
+			require.Equal(t, tc.expected, perr.Expected)
+		})
+	}
+
+	t.Run("message", func(t *testing.T) {
+		_, err := Parse(`name == 1`)
+		require.EqualError(t, err, `1:7: unexpected token "=" (expected Value)`)
+
+		_, err = Parse(`$name = 1`)
+		require.EqualError(t, err, `1:1: invalid input text "$name"`)
+	})
+
+	t.Run("not a syntax error", func(t *testing.T) {
+		_, err := Compile(`name = 1`, ParseOptions{Version: 3})
+		var perr *ParseError
+		require.False(t, errors.As(err, &perr))
+
+		_, err = Compile(`(a = 1 || b = 1) && (c = 1 || d = 1)`, ParseOptions{MaxConjunctions: 2})
+		require.Error(t, err)
+		require.False(t, errors.As(err, &perr))
+	})
+}
+
+func TestFilterError(t *testing.T) {
+	for _, tc := range []struct {
+		filter     string
+		line       int
+		column     int
+		unexpected string
+	}{
+		{filter: `{"attribute": "a", "op": "=", "value": 1,,}`, line: 1, column: 42, unexpected: ",}"},
+		{filter: "{\n  \"and\": [\n    {\"all\" true}\n  ]\n}", line: 3, column: 12, unexpected: "true}"},
+		{filter: `{"or": [`, line: 1, column: 9},
+		{filter: `{"attribute": "a", "op": "<>", "value": 1}`},
+		{filter: `{"attribute": "a", "op": "=", "value": 1, "extra": 2}`},
+		{filter: `{"attribute": "a", "op": "=~", "value": "(foo"}`},
+		{filter: `{"all": true, "none": true}`},
+	} {
+		t.Run(tc.filter, func(t *testing.T) {
+			_, err := ParseJSON([]byte(tc.filter))
+
+			var perr *ParseError
+			require.True(t, errors.As(err, &perr), "%v", err)
+			require.Equal(t, tc.line, perr.Line)
+			require.Equal(t, tc.column, perr.Column)
+			require.Equal(t, tc.unexpected, perr.Unexpected)
+			require.Contains(t, perr.Error(), "invalid JSON filter")
+		})
+	}
+
+	t.Run("not a syntax error", func(t *testing.T) {
+		_, err := ParseJSONWithOptions([]byte(`{"and": [
+			{"or": [{"attribute": "a", "op": "=", "value": 1}, {"attribute": "b", "op": "=", "value": 1}]},
+			{"or": [{"attribute": "c", "op": "=", "value": 1}, {"attribute": "d", "op": "=", "value": 1}]}
+		]}`), ParseOptions{MaxConjunctions: 2})
+		var perr *ParseError
+		require.Error(t, err)
+		require.False(t, errors.As(err, &perr))
+	})
+}
+
+func TestBindError(t *testing.T) {
+	p, err := Compile(`a = ? && b IN (:list) && c =~ :pattern && $owner = :owner`, ParseOptions{})
+	require.NoError(t, err)
+
+	for name, args := range map[string][]any{
+		"too few arguments": {1},
+		"unknown parameter": {1, Named("other", 1), Named("list", 1), Named("pattern", "a"), Named("owner", "b")},
+		"missing argument":  {1, Named("list", 1), Named("pattern", "a")},
+		"wrong type":        {1, Named("list", 1), Named("pattern", "a"), Named("owner", 1)},
+		"unsupported type":  {struct{}{}, Named("list", 1), Named("pattern", "a"), Named("owner", "b")},
+		"empty list":        {1, Named("list", []int{}), Named("pattern", "a"), Named("owner", "b")},
+		"invalid regex":     {1, Named("list", 1), Named("pattern", "(a"), Named("owner", "b")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.Bind(args...)
+
+			var berr *BindError
+			require.True(t, errors.As(err, &berr), "%v", err)
+			var perr *ParseError
+			require.False(t, errors.As(err, &perr))
+		})
+	}
+
+	_, err = p.Bind(1, Named("list", 1), Named("pattern", "a"), Named("owner", "b"))
+	require.NoError(t, err)
+
+	_, err = Compile(`a = :x && a ~ :x && $expiration = :x`, ParseOptions{})
+	var perr *ParseError
+	require.True(t, errors.As(err, &perr), "%v", err)
+	require.Zero(t, perr.Line)
+	require.EqualError(t, err, "parameter :x is used both as a string and as a numeric value")
+}
+
+func TestExpectedAlternatives(t *testing.T) {
+	require.Nil(t, expectedAlternatives(`unexpected token "b"`))
+	require.Equal(t, []string{"Value"}, expectedAlternatives(`unexpected token "=" (expected Value)`))
+	require.Equal(t, []string{"<string>", "Param"}, expectedAlternatives(`unexpected token "1" (expected (<string> | Param))`))
+	require.Equal(t, []string{`"|"`, "(A | B) C"}, expectedAlternatives(`unexpected token "1" (expected "|" | (A | B) C)`))
+	require.Equal(t, []string{"(A | B) (C | D)"}, expectedAlternatives(`unexpected token "1" (expected (A | B) (C | D))`))
+}
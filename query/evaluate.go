@@ -3,7 +3,11 @@ package query
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
 	"github.com/RoaringBitmap/roaring/v2/roaring64"
@@ -13,16 +17,44 @@ func (t *AST) Evaluate(
 	ctx context.Context,
 	q *store.Queries,
 ) (*roaring64.Bitmap, error) {
+	bm, _, err := t.EvaluateBranches(ctx, q)
+	return bm, err
+}
+
+// EvaluateBranches is Evaluate that also returns the entities that each
+// conjunction of the normal form matches, in the order of t.Expr.Or.Terms. A
+// query without an expression has a single branch that matches all entities.
+// Call Canonical first for the branches to be in the order that String prints
+// them.
+func (t *AST) EvaluateBranches(
+	ctx context.Context,
+	q *store.Queries,
+) (bm *roaring64.Bitmap, branches []*roaring64.Bitmap, err error) {
+	trace := traceFrom(ctx)
+	trace.begin()
+	defer func(start time.Time) {
+		trace.end(bm, start)
+	}(time.Now())
+
 	if t.Expr == nil {
 		ids, err := q.EvaluateAll(ctx)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		bm := roaring64.New()
 		bm.AddMany(ids)
-		return bm, nil
+		return bm, []*roaring64.Bitmap{bm}, nil
+	}
+	if t.UsesHead() {
+		return nil, nil, errors.New("values relative to the block height must be resolved with AtHead")
 	}
-	return t.Expr.Evaluate(ctx, q)
+
+	branches, err = t.Expr.Or.EvaluateBranches(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return roaring64.FastOr(branches...), branches, nil
 }
 
 func (e *ASTExpr) Evaluate(
@@ -36,39 +68,71 @@ func (e *ASTOr) Evaluate(
 	ctx context.Context,
 	q *store.Queries,
 ) (*roaring64.Bitmap, error) {
-	var tmp *roaring64.Bitmap = nil
+	branches, err := e.EvaluateBranches(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return roaring64.FastOr(branches...), nil
+}
+
+// EvaluateBranches returns the entities that each conjunction matches. An
+// empty disjunction has no branches and matches nothing, see
+// AST.MatchesNothing.
+func (e *ASTOr) EvaluateBranches(
+	ctx context.Context,
+	q *store.Queries,
+) ([]*roaring64.Bitmap, error) {
+	branches := make([]*roaring64.Bitmap, 0, len(e.Terms))
 
 	for _, term := range e.Terms {
 		bm, err := term.Evaluate(ctx, q)
 		if err != nil {
 			return nil, err
 		}
-		if tmp == nil {
-			tmp = bm
-		} else {
-			tmp.Or(bm)
-		}
+		branches = append(branches, bm)
 	}
 
-	return tmp, nil
+	return branches, nil
 }
 
 func (e *ASTAnd) Evaluate(
 	ctx context.Context,
 	q *store.Queries,
-) (*roaring64.Bitmap, error) {
-	var tmp *roaring64.Bitmap = nil
+) (tmp *roaring64.Bitmap, err error) {
+	trace := traceFrom(ctx).beginConjunction(e)
+	defer func(start time.Time) {
+		trace.end(tmp, start)
+	}(time.Now())
+
+	planned, ok, err := e.plan(ctx, q, trace)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		trace.emptyByStatistics()
+		return roaring64.New(), nil
+	}
 
-	for _, term := range e.Terms {
-		bm, err := term.Evaluate(ctx, q)
+	for _, p := range planned {
+		start := time.Now()
+		trace.term(p.term)
+
+		bm, err := p.term.Evaluate(ctx, q)
 		if err != nil {
 			return nil, err
 		}
 		if tmp == nil {
-			tmp = bm
+			tmp = bm.Clone()
 		} else {
 			tmp.And(bm)
 		}
+
+		trace.evaluated(p.term, bm, tmp, start)
+
+		if tmp.IsEmpty() {
+			// the remaining terms cannot change the result
+			break
+		}
 	}
 
 	return tmp, nil
@@ -83,6 +147,8 @@ func (e *ASTTerm) Evaluate(
 		return e.Assign.Evaluate(ctx, q)
 	case e.Inclusion != nil:
 		return e.Inclusion.Evaluate(ctx, q)
+	case e.AttributeEqual != nil:
+		return e.AttributeEqual.Evaluate(ctx, q)
 	case e.LessThan != nil:
 		return e.LessThan.Evaluate(ctx, q)
 	case e.LessOrEqualThan != nil:
@@ -93,6 +159,14 @@ func (e *ASTTerm) Evaluate(
 		return e.GreaterOrEqualThan.Evaluate(ctx, q)
 	case e.Glob != nil:
 		return e.Glob.Evaluate(ctx, q)
+	case e.CaseEqual != nil:
+		return e.CaseEqual.Evaluate(ctx, q)
+	case e.Prefix != nil:
+		return e.Prefix.Evaluate(ctx, q)
+	case e.Regex != nil:
+		return e.Regex.Evaluate(ctx, q)
+	case e.Range != nil:
+		return e.Range.Evaluate(ctx, q)
 	default:
 		return nil, fmt.Errorf("unknown equal expression: %v", e)
 	}
@@ -103,8 +177,6 @@ func (e *Glob) Evaluate(
 	q *store.Queries,
 ) (_ *roaring64.Bitmap, err error) {
 
-	bm := roaring64.New()
-
 	var bitmaps []*store.Bitmap
 
 	if e.IsNot {
@@ -125,11 +197,135 @@ func (e *Glob) Evaluate(
 		}
 	}
 
-	for _, bitmap := range bitmaps {
-		bm.Or(bitmap.Bitmap)
+	return union(ctx, bitmaps), nil
+}
+
+func (e *CaseEquality) Evaluate(
+	ctx context.Context,
+	q *store.Queries,
+) (*roaring64.Bitmap, error) {
+	return evaluateMatchingStringValues(ctx, q, e.Var, func(value string) bool {
+		return strings.EqualFold(value, e.Value) != e.IsNot
+	})
+}
+
+func (e *Prefix) Evaluate(
+	ctx context.Context,
+	q *store.Queries,
+) (_ *roaring64.Bitmap, err error) {
+
+	var bitmaps []*store.Bitmap
+
+	// All values with the prefix sort between the prefix and its upper bound,
+	// so this is a range scan on the primary key.
+	upper, bounded := store.PrefixUpperBound(e.Value)
+
+	switch {
+	case !bounded && e.IsNot:
+		bitmaps, err = q.EvaluateStringAttributeValueLowerThan(ctx, store.EvaluateStringAttributeValueLowerThanParams{
+			Name:  e.Var,
+			Value: e.Value,
+		})
+	case !bounded:
+		bitmaps, err = q.EvaluateStringAttributeValueGreaterOrEqualThan(ctx, store.EvaluateStringAttributeValueGreaterOrEqualThanParams{
+			Name:  e.Var,
+			Value: e.Value,
+		})
+	case e.IsNot:
+		bitmaps, err = q.EvaluateStringAttributeValueNotRange(ctx, store.EvaluateStringAttributeValueNotRangeParams{
+			Name:      e.Var,
+			FromValue: e.Value,
+			ToValue:   upper,
+		})
+	default:
+		bitmaps, err = q.EvaluateStringAttributeValueRange(ctx, store.EvaluateStringAttributeValueRangeParams{
+			Name:      e.Var,
+			FromValue: e.Value,
+			ToValue:   upper,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return union(ctx, bitmaps), nil
+}
+
+func (e *Range) Evaluate(
+	ctx context.Context,
+	q *store.Queries,
+) (_ *roaring64.Bitmap, err error) {
+
+	var bitmaps []*store.Bitmap
+
+	if e.From.String != nil {
+		bitmaps, err = q.EvaluateStringAttributeValueBetween(ctx, store.EvaluateStringAttributeValueBetweenParams{
+			Name:          e.Var,
+			FromValue:     *e.From.String,
+			ToValue:       *e.To.String,
+			FromInclusive: e.FromInclusive,
+			ToInclusive:   e.ToInclusive,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		bitmaps, err = q.EvaluateNumericAttributeValueBetween(ctx, store.EvaluateNumericAttributeValueBetweenParams{
+			Name:          e.Var,
+			FromValue:     *e.From.Number,
+			ToValue:       *e.To.Number,
+			FromInclusive: e.FromInclusive,
+			ToInclusive:   e.ToInclusive,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return union(ctx, bitmaps), nil
+}
+
+func (e *Regex) Evaluate(
+	ctx context.Context,
+	q *store.Queries,
+) (*roaring64.Bitmap, error) {
+	re, err := regexp.Compile(string(e.Value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", e.Value, err)
 	}
 
-	return bm, nil
+	return evaluateMatchingStringValues(ctx, q, e.Var, func(value string) bool {
+		return re.MatchString(value) != e.IsNot
+	})
+}
+
+// evaluateMatchingStringValues matches the distinct values of a string
+// attribute in Go and returns the union of the bitmaps of the values that
+// match.
+func evaluateMatchingStringValues(
+	ctx context.Context,
+	q *store.Queries,
+	name string,
+	match func(value string) bool,
+) (*roaring64.Bitmap, error) {
+
+	values, err := q.GetStringAttributeValues(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []string{}
+	for _, value := range values {
+		if match(value) {
+			matching = append(matching, value)
+		}
+	}
+
+	if len(matching) == 0 {
+		return roaring64.New(), nil
+	}
+
+	return evaluateStringInclusion(ctx, q, name, matching)
 }
 
 func (e *LessThan) Evaluate(
@@ -157,13 +353,7 @@ func (e *LessThan) Evaluate(
 		}
 	}
 
-	bm := roaring64.New()
-
-	for _, bitmap := range bitmaps {
-		bm.Or(bitmap.Bitmap)
-	}
-
-	return bm, nil
+	return union(ctx, bitmaps), nil
 }
 
 func (e *LessOrEqualThan) Evaluate(
@@ -191,13 +381,7 @@ func (e *LessOrEqualThan) Evaluate(
 		}
 	}
 
-	bm := roaring64.New()
-
-	for _, bitmap := range bitmaps {
-		bm.Or(bitmap.Bitmap)
-	}
-
-	return bm, nil
+	return union(ctx, bitmaps), nil
 }
 
 func (e *GreaterThan) Evaluate(
@@ -226,13 +410,7 @@ func (e *GreaterThan) Evaluate(
 		}
 	}
 
-	bm := roaring64.New()
-
-	for _, bitmap := range bitmaps {
-		bm.Or(bitmap.Bitmap)
-	}
-
-	return bm, nil
+	return union(ctx, bitmaps), nil
 }
 
 func (e *GreaterOrEqualThan) Evaluate(
@@ -261,13 +439,7 @@ func (e *GreaterOrEqualThan) Evaluate(
 		}
 	}
 
-	bm := roaring64.New()
-
-	for _, bitmap := range bitmaps {
-		bm.Or(bitmap.Bitmap)
-	}
-
-	return bm, nil
+	return union(ctx, bitmaps), nil
 }
 
 func (e *Equality) Evaluate(
@@ -275,6 +447,17 @@ func (e *Equality) Evaluate(
 	q *store.Queries,
 ) (_ *roaring64.Bitmap, err error) {
 
+	if e.Var == KeyAttributeKey && e.Value.String != nil {
+		bm, err := evaluateKeys(ctx, q, []string{*e.Value.String})
+		if err != nil {
+			return nil, err
+		}
+		if e.IsNot {
+			return evaluateExclusion(ctx, q, e.Var, false, bm)
+		}
+		return bm, nil
+	}
+
 	if e.Value.String != nil {
 
 		if e.IsNot {
@@ -288,13 +471,7 @@ func (e *Equality) Evaluate(
 				return nil, err
 			}
 
-			bm := roaring64.New()
-
-			for _, bitmap := range bitmaps {
-				bm.Or(bitmap.Bitmap)
-			}
-
-			return bm, nil
+			return union(ctx, bitmaps), nil
 
 		} else {
 			bm, err := q.EvaluateStringAttributeValueEqual(ctx, store.EvaluateStringAttributeValueEqualParams{
@@ -310,6 +487,8 @@ func (e *Equality) Evaluate(
 				return nil, err
 			}
 
+			traceBitmaps(ctx, bm)
+
 			return bm.Bitmap, nil
 		}
 	} else {
@@ -324,12 +503,7 @@ func (e *Equality) Evaluate(
 				return nil, err
 			}
 
-			bm := roaring64.New()
-			for _, bitmap := range bitmaps {
-				bm.Or(bitmap.Bitmap)
-			}
-
-			return bm, nil
+			return union(ctx, bitmaps), nil
 		} else {
 			bitmap, err := q.EvaluateNumericAttributeValueEqual(ctx, store.EvaluateNumericAttributeValueEqualParams{
 				Name:  e.Var,
@@ -344,6 +518,8 @@ func (e *Equality) Evaluate(
 				return nil, err
 			}
 
+			traceBitmaps(ctx, bitmap)
+
 			return bitmap.Bitmap, nil
 		}
 	}
@@ -355,59 +531,55 @@ func (e *Inclusion) Evaluate(
 	q *store.Queries,
 ) (_ *roaring64.Bitmap, err error) {
 
-	if len(e.Values.Strings) != 0 {
+	if e.Values.Subquery != nil {
+		return e.evaluateSubquery(ctx, q)
+	}
 
-		var bitmaps []*store.Bitmap
+	numeric := len(e.Values.Numbers) != 0
+	if e.IsNot && e.Var != KeyAttributeKey && len(e.Values.Strings)+len(e.Values.Numbers) <= inclusionBatchSize {
+		return e.evaluateNotInclusion(ctx, q)
+	}
 
-		if e.IsNot {
-			bitmaps, err = q.EvaluateStringAttributeValueNotInclusion(ctx, store.EvaluateStringAttributeValueNotInclusionParams{
-				Name:   e.Var,
-				Values: e.Values.Strings,
-			})
-			if err != nil {
-				return nil, err
-			}
-		} else {
+	var included *roaring64.Bitmap
+	if numeric {
+		included, err = evaluateNumericInclusion(ctx, q, e.Var, e.Values.Numbers)
+	} else {
+		included, err = evaluateStringInclusion(ctx, q, e.Var, e.Values.Strings)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-			bitmaps, err = q.EvaluateStringAttributeValueInclusion(ctx, store.EvaluateStringAttributeValueInclusionParams{
-				Name:   e.Var,
-				Values: e.Values.Strings,
-			})
-			if err != nil {
-				return nil, err
-			}
-		}
-		bm := roaring64.New()
-		for _, bitmap := range bitmaps {
-			bm.Or(bitmap.Bitmap)
-		}
-		return bm, nil
+	if !e.IsNot {
+		return included, nil
+	}
 
-	} else {
-		var bitmaps []*store.Bitmap
+	// lists that do not fit in one statement are excluded from all values
+	return evaluateExclusion(ctx, q, e.Var, numeric, included)
+}
 
-		if e.IsNot {
-			bitmaps, err = q.EvaluateNumericAttributeValueNotInclusion(ctx, store.EvaluateNumericAttributeValueNotInclusionParams{
-				Name:   e.Var,
-				Values: e.Values.Numbers,
-			})
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			bitmaps, err = q.EvaluateNumericAttributeValueInclusion(ctx, store.EvaluateNumericAttributeValueInclusionParams{
-				Name:   e.Var,
-				Values: e.Values.Numbers,
-			})
-			if err != nil {
-				return nil, err
-			}
-		}
-		bm := roaring64.New()
-		for _, bitmap := range bitmaps {
-			bm.Or(bitmap.Bitmap)
-		}
-		return bm, nil
+// evaluateNotInclusion evaluates a NOT IN list that fits in one statement.
+func (e *Inclusion) evaluateNotInclusion(
+	ctx context.Context,
+	q *store.Queries,
+) (_ *roaring64.Bitmap, err error) {
+
+	var bitmaps []*store.Bitmap
+
+	if len(e.Values.Strings) != 0 {
+		bitmaps, err = q.EvaluateStringAttributeValueNotInclusion(ctx, store.EvaluateStringAttributeValueNotInclusionParams{
+			Name:   e.Var,
+			Values: e.Values.Strings,
+		})
+	} else {
+		bitmaps, err = q.EvaluateNumericAttributeValueNotInclusion(ctx, store.EvaluateNumericAttributeValueNotInclusionParams{
+			Name:   e.Var,
+			Values: e.Values.Numbers,
+		})
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	return union(ctx, bitmaps), nil
 }
@@ -0,0 +1,213 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+)
+
+// Trace records how a query was evaluated. Evaluate fills it in if the context
+// carries it, see WithTrace. Durations are in nanoseconds.
+type Trace struct {
+	Conjunctions []*ConjunctionTrace `json:"conjunctions"`
+	// SQL are the statements that did not belong to a term, like the one
+	// for $all.
+	SQL         []string      `json:"sql,omitempty"`
+	Cardinality uint64        `json:"cardinality"`
+	Duration    time.Duration `json:"duration"`
+
+	active  bool
+	current *TermTrace
+}
+
+// ConjunctionTrace records the evaluation of a conjunction. Its terms are in
+// the planned order, and the terms after the intersection became empty are not
+// evaluated.
+type ConjunctionTrace struct {
+	Conjunction string `json:"conjunction"`
+	// EmptyByStatistics is set if the statistics showed that a term matches
+	// nothing, so that no term was evaluated.
+	EmptyByStatistics bool          `json:"emptyByStatistics,omitempty"`
+	Terms             []*TermTrace  `json:"terms"`
+	Cardinality       uint64        `json:"cardinality"`
+	Duration          time.Duration `json:"duration"`
+
+	trace *Trace
+	terms map[*ASTTerm]*TermTrace
+}
+
+// TermTrace records the planning and the evaluation of a term.
+type TermTrace struct {
+	Term string `json:"term"`
+	// Estimate is the number of matching entities that the planner
+	// estimated, it is not set for conjunctions with a single term.
+	Estimate  *uint64  `json:"estimate,omitempty"`
+	Evaluated bool     `json:"evaluated"`
+	SQL       []string `json:"sql"`
+	// Bitmaps is the number of value bitmaps that were read and BitmapBytes
+	// their total serialized size.
+	Bitmaps     uint64 `json:"bitmaps"`
+	BitmapBytes uint64 `json:"bitmapBytes"`
+	Cardinality uint64 `json:"cardinality"`
+	// Intersection is the cardinality of the intersection of this and the
+	// previous terms of the conjunction.
+	Intersection uint64        `json:"intersection"`
+	Duration     time.Duration `json:"duration"`
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that makes Evaluate record its work in t.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+func traceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+func (t *Trace) beginConjunction(and *ASTAnd) *ConjunctionTrace {
+	if t == nil {
+		return nil
+	}
+	c := &ConjunctionTrace{
+		Conjunction: and.String(),
+		Terms:       []*TermTrace{},
+		trace:       t,
+		terms:       map[*ASTTerm]*TermTrace{},
+	}
+	t.Conjunctions = append(t.Conjunctions, c)
+	return c
+}
+
+// term returns the trace of a term of the conjunction, which receives the SQL
+// and the bitmaps until another term is traced.
+func (c *ConjunctionTrace) term(term *ASTTerm) *TermTrace {
+	if c == nil {
+		return nil
+	}
+	t, ok := c.terms[term]
+	if !ok {
+		t = &TermTrace{Term: term.String(), SQL: []string{}}
+		c.terms[term] = t
+	}
+	c.trace.current = t
+	return t
+}
+
+// plan records the planned order of the terms.
+func (c *ConjunctionTrace) plan(planned []plannedTerm) {
+	if c == nil {
+		return
+	}
+	for _, p := range planned {
+		t := c.term(p.term)
+		if p.estimated {
+			t.Estimate = &p.estimate
+		}
+		c.Terms = append(c.Terms, t)
+	}
+}
+
+// evaluated records the evaluation of a term.
+func (c *ConjunctionTrace) evaluated(term *ASTTerm, bm, intersection *roaring64.Bitmap, start time.Time) {
+	if c == nil {
+		return
+	}
+	t := c.term(term)
+	t.Evaluated = true
+	t.Cardinality = bm.GetCardinality()
+	t.Intersection = intersection.GetCardinality()
+	t.Duration = time.Since(start)
+}
+
+func (c *ConjunctionTrace) emptyByStatistics() {
+	if c != nil {
+		c.EmptyByStatistics = true
+	}
+}
+
+func (c *ConjunctionTrace) end(bm *roaring64.Bitmap, start time.Time) {
+	if c == nil {
+		return
+	}
+	c.Cardinality = bm.GetCardinality()
+	c.Duration = time.Since(start)
+	c.trace.current = nil
+}
+
+func (t *Trace) begin() {
+	if t != nil {
+		t.active = true
+	}
+}
+
+func (t *Trace) end(bm *roaring64.Bitmap, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.active = false
+	t.current = nil
+	if bm != nil {
+		t.Cardinality = bm.GetCardinality()
+	}
+	t.Duration = time.Since(start)
+}
+
+// traceBitmaps records the bitmaps that a term read.
+func traceBitmaps(ctx context.Context, bitmaps ...*store.Bitmap) {
+	t := traceFrom(ctx)
+	if t == nil || t.current == nil {
+		return
+	}
+	for _, bitmap := range bitmaps {
+		t.current.Bitmaps++
+		t.current.BitmapBytes += bitmap.GetSerializedSizeInBytes()
+	}
+}
+
+// union returns the union of the bitmaps that a term read.
+func union(ctx context.Context, bitmaps []*store.Bitmap) *roaring64.Bitmap {
+	traceBitmaps(ctx, bitmaps...)
+
+	bm := roaring64.New()
+	for _, bitmap := range bitmaps {
+		bm.Or(bitmap.Bitmap)
+	}
+	return bm
+}
+
+// TraceDB returns a DBTX that records the SQL statements that Evaluate runs
+// through it in t.
+func TraceDB(db store.DBTX, t *Trace) store.DBTX {
+	return &tracingDB{DBTX: db, trace: t}
+}
+
+type tracingDB struct {
+	store.DBTX
+	trace *Trace
+}
+
+func (d *tracingDB) record(query string) {
+	switch {
+	case !d.trace.active:
+	case d.trace.current != nil:
+		d.trace.current.SQL = append(d.trace.current.SQL, query)
+	default:
+		d.trace.SQL = append(d.trace.SQL, query)
+	}
+}
+
+func (d *tracingDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	d.record(query)
+	return d.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (d *tracingDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	d.record(query)
+	return d.DBTX.QueryRowContext(ctx, query, args...)
+}
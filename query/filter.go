@@ -0,0 +1,427 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// Filter is the JSON representation of a query. A filter is exactly one of
+//
+//	{"and": [<filter>, ...]}
+//	{"or": [<filter>, ...]}
+//	{"not": <filter>}
+//	{"all": true}
+//	{"none": true}
+//	{"attribute": "price", "op": ">=", "value": 100}
+//	{"attribute": "type", "op": "in", "values": ["nft", "token"]}
+//	{"attribute": "parent", "op": "in", "select": "$key", "where": <filter>}
+//	{"attribute": "$owner", "op": "=", "otherAttribute": "$creator"}
+//
+// The op of a term is one of the operators of the string grammar: "=", "!=",
+// "<", "<=", ">", ">=", "~", "!~", "=*", "!=*", "^=", "!^=", "=~", "!=~",
+// "in" and "not in". String values are JSON strings and numeric values are
+// JSON numbers. A value relative to the block height is an object with the
+// integer offset, {"head": 0} for @head and {"head": -10} for @head - 10. An
+// in or not in term with a select and a where is a subquery, and an = or !=
+// term with an otherAttribute compares two attributes.
+type Filter struct {
+	And []Filter `json:"and,omitempty"`
+	Or  []Filter `json:"or,omitempty"`
+	Not *Filter  `json:"not,omitempty"`
+	All bool     `json:"all,omitempty"`
+	// None matches no entity, like $none.
+	None bool `json:"none,omitempty"`
+
+	Attribute string  `json:"attribute,omitempty"`
+	Op        string  `json:"op,omitempty"`
+	Value     *Value  `json:"value,omitempty"`
+	Values    []Value `json:"values,omitempty"`
+	Select    string  `json:"select,omitempty"`
+	Where     *Filter `json:"where,omitempty"`
+
+	OtherAttribute string `json:"otherAttribute,omitempty"`
+}
+
+// ParseJSON parses a query in the JSON filter format.
+func ParseJSON(data []byte) (*AST, error) {
+	return ParseJSONWithOptions(data, ParseOptions{})
+}
+
+// ParseJSONWithOptions parses a JSON filter with the MaxConjunctions limit of
+// options, the grammar version is ignored. Invalid filters fail with a
+// ParseError.
+func ParseJSONWithOptions(data []byte, options ParseOptions) (*AST, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var f Filter
+	if err := dec.Decode(&f); err != nil {
+		return nil, newFilterError(data, fmt.Errorf("invalid JSON filter: %w", err))
+	}
+
+	if f.All {
+		if !f.isOnly(func(f Filter) bool { return f.All }) {
+			return nil, newFilterError(data, errors.New("invalid JSON filter: all cannot be combined with other fields"))
+		}
+		return &AST{}, nil
+	}
+
+	if f.None {
+		if !f.isOnly(func(f Filter) bool { return f.None }) {
+			return nil, newFilterError(data, errors.New("invalid JSON filter: none cannot be combined with other fields"))
+		}
+		return &AST{Expr: &ASTExpr{Or: ASTOr{Terms: []ASTAnd{}}}}, nil
+	}
+
+	e, err := f.Expression()
+	if err == nil && e != nil {
+		// errors of the builder functions, like invalid regular expressions
+		err = e.err
+	}
+	if err != nil {
+		return nil, newFilterError(data, err)
+	}
+
+	return BuildWithOptions(e, options)
+}
+
+// isOnly reports whether set is the only field of f that is set.
+func (f Filter) isOnly(set func(Filter) bool) bool {
+	fields := []bool{
+		f.And != nil,
+		f.Or != nil,
+		f.Not != nil,
+		f.All,
+		f.None,
+		f.Attribute != "" || f.Op != "" || f.Value != nil || f.Values != nil || f.Select != "" || f.Where != nil ||
+			f.OtherAttribute != "",
+	}
+
+	count := 0
+	for _, isSet := range fields {
+		if isSet {
+			count++
+		}
+	}
+
+	return count == 1 && set(f)
+}
+
+// Expression converts the filter into an expression, see Build.
+func (f Filter) Expression() (*Expression, error) {
+	switch {
+	case f.And != nil && f.isOnly(func(f Filter) bool { return f.And != nil }):
+		exprs, err := filterExpressions(f.And)
+		if err != nil {
+			return nil, err
+		}
+		return And(exprs...), nil
+
+	case f.Or != nil && f.isOnly(func(f Filter) bool { return f.Or != nil }):
+		exprs, err := filterExpressions(f.Or)
+		if err != nil {
+			return nil, err
+		}
+		return Or(exprs...), nil
+
+	case f.Not != nil && f.isOnly(func(f Filter) bool { return f.Not != nil }):
+		e, err := f.Not.Expression()
+		if err != nil {
+			return nil, err
+		}
+		return Not(e), nil
+
+	case f.All:
+		return nil, fmt.Errorf("invalid JSON filter: all can only be used at the top level")
+
+	case f.None:
+		return nil, fmt.Errorf("invalid JSON filter: none can only be used at the top level and as the where of a subquery")
+
+	case f.Attribute != "" && f.isOnly(func(f Filter) bool { return f.Attribute != "" }):
+		return f.term()
+
+	default:
+		return nil, fmt.Errorf("invalid JSON filter: exactly one of and, or, not, all, none or a term is required")
+	}
+}
+
+func filterExpressions(filters []Filter) ([]*Expression, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("invalid JSON filter: empty and or or")
+	}
+
+	exprs := make([]*Expression, 0, len(filters))
+	for _, f := range filters {
+		e, err := f.Expression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	return exprs, nil
+}
+
+var filterOps = []string{"=", "!=", "<", "<=", ">", ">=", "~", "!~", "=*", "!=*", "^=", "!^=", "=~", "!=~", "in", "not in"}
+
+func (f Filter) term() (*Expression, error) {
+	name := f.Attribute
+
+	if !slices.Contains(filterOps, f.Op) {
+		return nil, fmt.Errorf("invalid JSON filter: unknown op %q", f.Op)
+	}
+
+	if (f.Op == "in" || f.Op == "not in") && (f.Select != "" || f.Where != nil) {
+		if f.Value != nil || f.Values != nil {
+			return nil, fmt.Errorf("invalid JSON filter: %q uses either values or a subquery", f.Op)
+		}
+		if f.Select != KeyAttributeKey || f.Where == nil {
+			return nil, fmt.Errorf("invalid JSON filter: a subquery selects %s where a filter matches", KeyAttributeKey)
+		}
+		if f.Where.None && f.Where.isOnly(func(f Filter) bool { return f.None }) {
+			sub := &Subquery{Select: f.Select, None: true}
+			return term(EqualExpr{Inclusion: &Inclusion{Var: name, IsNot: f.Op == "not in", Values: Values{Subquery: sub}}}), nil
+		}
+		where, err := f.Where.Expression()
+		if err != nil {
+			return nil, err
+		}
+		sub := &Subquery{Select: f.Select, Where: where}
+		return term(EqualExpr{Inclusion: &Inclusion{Var: name, IsNot: f.Op == "not in", Values: Values{Subquery: sub}}}), nil
+	}
+
+	if f.Select != "" || f.Where != nil {
+		return nil, fmt.Errorf("invalid JSON filter: %q does not take a subquery", f.Op)
+	}
+
+	if f.OtherAttribute != "" {
+		if f.Op != "=" && f.Op != "!=" {
+			return nil, fmt.Errorf("invalid JSON filter: %q cannot compare attributes", f.Op)
+		}
+		if f.Value != nil || f.Values != nil {
+			return nil, fmt.Errorf("invalid JSON filter: %q uses either a value or another attribute", f.Op)
+		}
+		return term(EqualExpr{AttributeEqual: &AttributeEquality{Var: name, IsNot: f.Op == "!=", Other: f.OtherAttribute}}), nil
+	}
+
+	if f.Op == "in" || f.Op == "not in" {
+		if f.Value != nil {
+			return nil, fmt.Errorf("invalid JSON filter: %q uses values, not value", f.Op)
+		}
+		values, err := listValues(f.Values)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON filter for %q: %w", name, err)
+		}
+		return term(EqualExpr{Inclusion: &Inclusion{Var: name, IsNot: f.Op == "not in", Values: values}}), nil
+	}
+
+	if f.Value == nil || f.Values != nil {
+		return nil, fmt.Errorf("invalid JSON filter: %q uses value, not values", f.Op)
+	}
+	v := *f.Value
+
+	switch f.Op {
+	case "=", "!=":
+		return term(EqualExpr{Assign: &Equality{Var: name, IsNot: f.Op == "!=", Value: v}}), nil
+	case "<":
+		return term(EqualExpr{LessThan: &LessThan{Var: name, Value: v}}), nil
+	case "<=":
+		return term(EqualExpr{LessOrEqualThan: &LessOrEqualThan{Var: name, Value: v}}), nil
+	case ">":
+		return term(EqualExpr{GreaterThan: &GreaterThan{Var: name, Value: v}}), nil
+	case ">=":
+		return term(EqualExpr{GreaterOrEqualThan: &GreaterOrEqualThan{Var: name, Value: v}}), nil
+	}
+
+	if v.String == nil {
+		return nil, fmt.Errorf("invalid JSON filter: %q needs a string value", f.Op)
+	}
+	s := *v.String
+
+	switch f.Op {
+	case "~", "!~":
+		return term(EqualExpr{Glob: &Glob{Var: name, IsNot: f.Op == "!~", Value: s}}), nil
+	case "=*", "!=*":
+		return term(EqualExpr{CaseEqual: &CaseEquality{Var: name, IsNot: f.Op == "!=*", Value: s}}), nil
+	case "^=", "!^=":
+		return term(EqualExpr{Prefix: &Prefix{Var: name, IsNot: f.Op == "!^=", Value: s}}), nil
+	case "=~", "!=~":
+		if _, err := regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("invalid JSON filter: invalid regular expression %q: %w", s, err)
+		}
+		return term(EqualExpr{Regex: &Regex{Var: name, IsNot: f.Op == "!=~", Value: RegexPattern(s)}}), nil
+	}
+
+	panic("unhandled JSON filter op " + f.Op)
+}
+
+// Filter returns the JSON filter representation of the query, which is in
+// disjunctive normal form like the AST.
+func (a *AST) Filter() Filter {
+	if a.Expr == nil {
+		return Filter{All: true}
+	}
+	if a.MatchesNothing() {
+		return Filter{None: true}
+	}
+
+	ors := make([]Filter, 0, len(a.Expr.Or.Terms))
+	for _, and := range a.Expr.Or.Terms {
+		terms := make([]Filter, 0, len(and.Terms))
+		for _, t := range and.Terms {
+			terms = append(terms, t.Filter())
+		}
+		if len(terms) == 1 {
+			ors = append(ors, terms[0])
+		} else {
+			ors = append(ors, Filter{And: terms})
+		}
+	}
+
+	if len(ors) == 1 {
+		return ors[0]
+	}
+	return Filter{Or: ors}
+}
+
+// Filter returns the JSON filter representation of the term.
+func (t *ASTTerm) Filter() Filter {
+	str := func(s string) *Value {
+		return &Value{String: &s}
+	}
+	op := func(isNot bool, op, negated string) string {
+		if isNot {
+			return negated
+		}
+		return op
+	}
+
+	switch {
+	case t.Assign != nil:
+		return Filter{Attribute: t.Assign.Var, Op: op(t.Assign.IsNot, "=", "!="), Value: &t.Assign.Value}
+	case t.AttributeEqual != nil:
+		return Filter{
+			Attribute:      t.AttributeEqual.Var,
+			Op:             op(t.AttributeEqual.IsNot, "=", "!="),
+			OtherAttribute: t.AttributeEqual.Other,
+		}
+	case t.subquery() != nil:
+		where := t.Inclusion.Values.Subquery.Query.Filter()
+		return Filter{
+			Attribute: t.Inclusion.Var,
+			Op:        op(t.Inclusion.IsNot, "in", "not in"),
+			Select:    t.Inclusion.Values.Subquery.Select,
+			Where:     &where,
+		}
+	case t.Inclusion != nil:
+		values := []Value{}
+		for _, s := range t.Inclusion.Values.Strings {
+			values = append(values, *str(s))
+		}
+		for _, n := range t.Inclusion.Values.Numbers {
+			values = append(values, Value{Number: &n})
+		}
+		return Filter{Attribute: t.Inclusion.Var, Op: op(t.Inclusion.IsNot, "in", "not in"), Values: values}
+	case t.LessThan != nil:
+		return Filter{Attribute: t.LessThan.Var, Op: "<", Value: &t.LessThan.Value}
+	case t.LessOrEqualThan != nil:
+		return Filter{Attribute: t.LessOrEqualThan.Var, Op: "<=", Value: &t.LessOrEqualThan.Value}
+	case t.GreaterThan != nil:
+		return Filter{Attribute: t.GreaterThan.Var, Op: ">", Value: &t.GreaterThan.Value}
+	case t.GreaterOrEqualThan != nil:
+		return Filter{Attribute: t.GreaterOrEqualThan.Var, Op: ">=", Value: &t.GreaterOrEqualThan.Value}
+	case t.Glob != nil:
+		return Filter{Attribute: t.Glob.Var, Op: op(t.Glob.IsNot, "~", "!~"), Value: str(t.Glob.Value)}
+	case t.CaseEqual != nil:
+		return Filter{Attribute: t.CaseEqual.Var, Op: op(t.CaseEqual.IsNot, "=*", "!=*"), Value: str(t.CaseEqual.Value)}
+	case t.Prefix != nil:
+		return Filter{Attribute: t.Prefix.Var, Op: op(t.Prefix.IsNot, "^=", "!^="), Value: str(t.Prefix.Value)}
+	case t.Regex != nil:
+		return Filter{Attribute: t.Regex.Var, Op: op(t.Regex.IsNot, "=~", "!=~"), Value: str(string(t.Regex.Value))}
+	case t.Range != nil:
+		return Filter{And: []Filter{
+			{Attribute: t.Range.Var, Op: op(t.Range.FromInclusive, ">", ">="), Value: &t.Range.From},
+			{Attribute: t.Range.Var, Op: op(t.Range.ToInclusive, "<", "<="), Value: &t.Range.To},
+		}}
+	}
+
+	return Filter{}
+}
+
+func (a *AST) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Filter())
+}
+
+func (a *AST) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseJSON(data)
+	if err != nil {
+		return err
+	}
+	*a = *parsed
+	return nil
+}
+
+// headValue is the JSON form of a value relative to the block height.
+type headValue struct {
+	Head *store.NumericValue `json:"head"`
+}
+
+// MarshalJSON encodes a string value as a JSON string, a numeric value as a
+// JSON number and a value relative to the block height as an object with its
+// offset.
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch {
+	case v.String != nil:
+		return json.Marshal(*v.String)
+	case v.Number != nil:
+		return []byte(v.Number.String()), nil
+	case v.Head != nil:
+		return []byte(`{"head":` + v.Head.Offset.String() + `}`), nil
+	default:
+		return nil, errors.New("placeholders cannot be encoded as JSON")
+	}
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v = Value{String: &s}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		var h headValue
+		if err := dec.Decode(&h); err != nil {
+			return err
+		}
+		if h.Head == nil {
+			return errors.New("a value relative to the block height needs a head offset")
+		}
+		if !h.Head.IsInteger() {
+			return fmt.Errorf("the head offset %s is not an integer", h.Head)
+		}
+		*v = Value{Head: &HeadOffset{Offset: *h.Head}}
+		return nil
+	}
+
+	var n store.NumericValue
+	if err := n.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*v = Value{Number: &n}
+	return nil
+}
@@ -0,0 +1,147 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		for _, q := range []string{
+			`$all`,
+			`type = "nft"`,
+			`type = "nft" && price > 100`,
+			`(a = 1 || b != "x") && !(c < -2.5 || d >= 0x10)`,
+			`name IN ("a" "b") || age NOT IN (1 2) || $owner IN ("0xABC")`,
+			`name ~ "a*" && name !~ "b*" && name =* "C" && name !=* "d"`,
+			`name ^= "e" || name !^= "f" || name =~ "^g" || name !=~ "h$"`,
+			`x <= 5 && y = "quote \" and \\ backslash"`,
+			"`content-type` = 'text/plain'",
+			`$none`,
+			`a IN (SELECT $key WHERE $none) || b = 1`,
+			`$expiration < @head + 1000 && $sequence >= @head - 0x10 || a = @head`,
+		} {
+			v, err := Parse(q)
+			require.NoError(t, err, q)
+
+			data, err := json.Marshal(v)
+			require.NoError(t, err, q)
+
+			parsed, err := ParseJSON(data)
+			require.NoError(t, err, string(data))
+			require.Equal(t, v, parsed, string(data))
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		v, err := Parse(`type = "nft" && (price > 100 || size IN (1 2))`)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{"or": [
+			{"and": [
+				{"attribute": "type", "op": "=", "value": "nft"},
+				{"attribute": "price", "op": ">", "value": 100}
+			]},
+			{"and": [
+				{"attribute": "type", "op": "=", "value": "nft"},
+				{"attribute": "size", "op": "in", "values": [1, 2]}
+			]}
+		]}`, string(data))
+	})
+
+	t.Run("values relative to the block height", func(t *testing.T) {
+		v, err := Parse(`$expiration <= @head + 50 && a = @head || $sequence > @head - 5`)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{"or": [
+			{"and": [
+				{"attribute": "$expiration", "op": "<=", "value": {"head": 50}},
+				{"attribute": "a", "op": "=", "value": {"head": 0}}
+			]},
+			{"attribute": "$sequence", "op": ">", "value": {"head": -5}}
+		]}`, string(data))
+	})
+
+	t.Run("nested filters", func(t *testing.T) {
+		v, err := ParseJSON([]byte(`{"and": [
+			{"attribute": "type", "op": "=", "value": "nft"},
+			{"not": {"or": [
+				{"attribute": "price", "op": "<", "value": 1.5},
+				{"attribute": "name", "op": "^=", "value": "x"}
+			]}}
+		]}`))
+		require.NoError(t, err)
+
+		expected, err := Parse(`type = "nft" && !(price < 1.5 || name ^= "x")`)
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		for filter, msg := range map[string]string{
+			`{}`:                            "exactly one of",
+			`{"and": [], "or": []}`:         "exactly one of",
+			`{"and": []}`:                   "empty and or or",
+			`{"attribute": "a", "op": "="}`: `"=" uses value, not values`,
+			`{"attribute": "a", "op": "==", "value": 1}`:              `unknown op "=="`,
+			`{"attribute": "a", "op": "~", "value": 1}`:               `"~" needs a string value`,
+			`{"attribute": "a", "op": "in", "values": [1, "b"]}`:      "mixes string and numeric values",
+			`{"attribute": "a", "op": "=~", "value": "("}`:            "invalid regular expression",
+			`{"and": [{"all": true}]}`:                                "all can only be used at the top level",
+			`{"attribute": "a", "op": "<", "value": {"head": 1.5}}`:   "the head offset 1.5 is not an integer",
+			`{"attribute": "a", "op": "<", "value": {}}`:              "needs a head offset",
+			`{"attribute": "a", "op": "<", "value": {"tail": 1}}`:     `unknown field "tail"`,
+			`{"attribute": "a", "op": "in", "values": [{"head": 1}]}`: "IN lists cannot hold values relative to the block height",
+			`{"not": {"none": true}}`:                                 "none can only be used at the top level",
+			`{"none": true, "attribute": "a"}`:                        "none cannot be combined",
+			`{"attribute": "a", "op": "=", "value": 1, "extra": 1}`:   `unknown field "extra"`,
+		} {
+			_, err := ParseJSON([]byte(filter))
+			require.ErrorContains(t, err, msg, filter)
+		}
+	})
+}
+
+func TestBuilder(t *testing.T) {
+	t.Run("same AST as the parser", func(t *testing.T) {
+		v, err := Build(And(
+			Eq("type", "nft"),
+			Gt("price", 100),
+			Not(Or(In("size", []int{1, 2}), HasPrefix("$owner", "0xAB"))),
+			RegexMatch("name", "^a"),
+		))
+		require.NoError(t, err)
+
+		expected, err := Parse(`type = "nft" && price > 100 && !(size IN (1, 2) || $owner ^= "0xAB") && name =~ "^a"`)
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+
+	t.Run("all", func(t *testing.T) {
+		v, err := Build(nil)
+		require.NoError(t, err)
+		require.Equal(t, &AST{}, v)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := Build(And(Eq("a", 1), Eq("b", struct{}{})))
+		require.ErrorContains(t, err, "b: unsupported argument type struct {}")
+
+		_, err = Build(Or())
+		require.ErrorContains(t, err, "Or needs at least one expression")
+
+		_, err = Build(Not(RegexMatch("a", "(")))
+		require.ErrorContains(t, err, "invalid regular expression")
+
+		_, err = Build(In("a", 1, "b"))
+		require.ErrorContains(t, err, "mixes string and numeric values")
+	})
+}
@@ -0,0 +1,173 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// HeadOffset is a value relative to the block height that the query is
+// evaluated at, written @head, @head + 1000 or @head - 0x10. Offsets are
+// integers.
+type HeadOffset struct {
+	Offset store.NumericValue
+}
+
+func (h *HeadOffset) Capture(values []string) error {
+	s := strings.TrimSpace(strings.TrimPrefix(strings.Join(values, ""), "@head"))
+	if s == "" {
+		h.Offset = store.NewNumericValue(0)
+		return nil
+	}
+
+	sign, digits := s[:1], strings.TrimSpace(s[1:])
+	if sign == "+" {
+		sign = ""
+	}
+
+	offset, err := store.ParseNumericValue(sign + digits)
+	if err != nil {
+		return err
+	}
+	h.Offset = offset
+	return nil
+}
+
+func (h *HeadOffset) String() string {
+	switch {
+	case h.Offset.Scaled().Sign() == 0:
+		return "@head"
+	case h.Offset.IsNegative():
+		return "@head - " + strings.TrimPrefix(h.Offset.String(), "-")
+	default:
+		return "@head + " + h.Offset.String()
+	}
+}
+
+// at returns the value at the block height head.
+func (h *HeadOffset) at(head uint64) (store.NumericValue, error) {
+	scaled := store.NewNumericValue(head).Scaled()
+	scaled.Add(scaled, h.Offset.Scaled())
+
+	v, err := store.NumericValueFromScaled(scaled)
+	if err != nil {
+		return store.NumericValue{}, fmt.Errorf("%s is out of range at block %d: %w", h, head, err)
+	}
+	return v, nil
+}
+
+// UsesHead reports whether the query has values relative to the block height,
+// which AtHead resolves.
+func (a *AST) UsesHead() bool {
+	if a.Expr == nil {
+		return false
+	}
+
+	for _, and := range a.Expr.Or.Terms {
+		for _, t := range and.Terms {
+			if sub := t.subquery(); sub != nil && sub.Query.UsesHead() {
+				return true
+			}
+			for _, v := range t.headValues() {
+				if v.Head != nil {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// AtHead returns the query with the values relative to the block height
+// resolved at the block height head. The query is evaluated with the block
+// height of its read transaction, so that the results are consistent with the
+// data that is returned.
+func (a *AST) AtHead(head uint64) (*AST, error) {
+	if !a.UsesHead() {
+		return a, nil
+	}
+
+	ands := make([]ASTAnd, 0, len(a.Expr.Or.Terms))
+	for _, and := range a.Expr.Or.Terms {
+		terms := make([]ASTTerm, 0, len(and.Terms))
+		for _, t := range and.Terms {
+			resolved, err := t.atHead(head)
+			if err == nil {
+				resolved, err = resolved.mapSubquery(func(q *AST) (*AST, error) {
+					return q.AtHead(head)
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, resolved)
+		}
+		ands = append(ands, ASTAnd{Terms: terms})
+	}
+
+	return &AST{Expr: &ASTExpr{Or: ASTOr{Terms: ands}}, Warnings: a.Warnings}, nil
+}
+
+// headValues returns the values of the term that can be relative to the block
+// height.
+func (t *ASTTerm) headValues() []Value {
+	switch {
+	case t.Assign != nil:
+		return []Value{t.Assign.Value}
+	case t.LessThan != nil:
+		return []Value{t.LessThan.Value}
+	case t.LessOrEqualThan != nil:
+		return []Value{t.LessOrEqualThan.Value}
+	case t.GreaterThan != nil:
+		return []Value{t.GreaterThan.Value}
+	case t.GreaterOrEqualThan != nil:
+		return []Value{t.GreaterOrEqualThan.Value}
+	case t.Range != nil:
+		return []Value{t.Range.From, t.Range.To}
+	}
+	return nil
+}
+
+func (t ASTTerm) atHead(head uint64) (ASTTerm, error) {
+	var err error
+	resolve := func(v Value) Value {
+		if v.Head == nil || err != nil {
+			return v
+		}
+		var n store.NumericValue
+		n, err = v.Head.at(head)
+		return Value{Number: &n}
+	}
+
+	switch {
+	case t.Assign != nil:
+		e := *t.Assign
+		e.Value = resolve(e.Value)
+		t = ASTTerm{Assign: &e}
+	case t.LessThan != nil:
+		e := *t.LessThan
+		e.Value = resolve(e.Value)
+		t = ASTTerm{LessThan: &e}
+	case t.LessOrEqualThan != nil:
+		e := *t.LessOrEqualThan
+		e.Value = resolve(e.Value)
+		t = ASTTerm{LessOrEqualThan: &e}
+	case t.GreaterThan != nil:
+		e := *t.GreaterThan
+		e.Value = resolve(e.Value)
+		t = ASTTerm{GreaterThan: &e}
+	case t.GreaterOrEqualThan != nil:
+		e := *t.GreaterOrEqualThan
+		e.Value = resolve(e.Value)
+		t = ASTTerm{GreaterOrEqualThan: &e}
+	case t.Range != nil:
+		e := *t.Range
+		e.From = resolve(e.From)
+		e.To = resolve(e.To)
+		t = ASTTerm{Range: &e}
+	}
+
+	return t, err
+}
@@ -0,0 +1,64 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHead(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		minus16, err := store.ParseNumericValue("-16")
+		require.NoError(t, err)
+
+		v, err := Parse(`$expiration < @head+1000 && $sequence >= @head - 0x10 && $expiration != @head`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{LessThan: &LessThan{Var: ExpirationAttributeKey, Value: Value{Head: &HeadOffset{Offset: store.NewNumericValue(1000)}}}},
+				{GreaterOrEqualThan: &GreaterOrEqualThan{Var: SequenceAttributeKey, Value: Value{Head: &HeadOffset{Offset: minus16}}}},
+				{Assign: &Equality{Var: ExpirationAttributeKey, IsNot: true, Value: Value{Head: &HeadOffset{Offset: store.NewNumericValue(0)}}}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+		require.True(t, v.UsesHead())
+
+		require.Equal(t, `$expiration != @head && $expiration < @head + 1000 && $sequence >= @head - 16`, v.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, q := range []string{
+			`$expiration < @head + 1.5`,
+			`$expiration < @heads`,
+			`$expiration IN (@head)`,
+			`name ~ @head`,
+		} {
+			_, err := Parse(q)
+			require.Error(t, err, q)
+		}
+	})
+
+	t.Run("at head", func(t *testing.T) {
+		v, err := Parse(`$expiration < @head + 1000 || (name = "a" && $expiration >= @head - 10)`)
+		require.NoError(t, err)
+		printed := v.String()
+
+		resolved, err := v.AtHead(500)
+		require.NoError(t, err)
+		require.False(t, resolved.UsesHead())
+		require.Equal(t, `$expiration < 1500 || $expiration >= 490 && name = "a"`, resolved.String())
+		require.Equal(t, printed, v.String())
+
+		_, err = v.Evaluate(t.Context(), nil)
+		require.Error(t, err)
+
+		v, err = Parse(`$expiration > @head - 10`)
+		require.NoError(t, err)
+		resolved, err = v.AtHead(5)
+		require.NoError(t, err)
+		require.Equal(t, `$expiration > -5`, resolved.String())
+	})
+}
@@ -0,0 +1,149 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+)
+
+// inclusionBatchSize is the largest number of values that are bound to one
+// statement. SQLite limits the number of parameters of a statement, so longer
+// IN lists are evaluated in batches.
+const inclusionBatchSize = 500
+
+// entityKeyLength is the length of an entity key in bytes.
+const entityKeyLength = 32
+
+// evaluateStringInclusion returns the entities whose attribute has one of the
+// string values. Entity keys are looked up in the payloads.
+func evaluateStringInclusion(
+	ctx context.Context,
+	q *store.Queries,
+	name string,
+	values []string,
+) (*roaring64.Bitmap, error) {
+
+	if name == KeyAttributeKey {
+		return evaluateKeys(ctx, q, values)
+	}
+
+	res := roaring64.New()
+	for batch := range slices.Chunk(values, inclusionBatchSize) {
+		bitmaps, err := q.EvaluateStringAttributeValueInclusion(ctx, store.EvaluateStringAttributeValueInclusionParams{
+			Name:   name,
+			Values: batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Or(union(ctx, bitmaps))
+	}
+
+	return res, nil
+}
+
+// evaluateNumericInclusion returns the entities whose attribute has one of
+// the numeric values.
+func evaluateNumericInclusion(
+	ctx context.Context,
+	q *store.Queries,
+	name string,
+	values []store.NumericValue,
+) (*roaring64.Bitmap, error) {
+
+	res := roaring64.New()
+	for batch := range slices.Chunk(values, inclusionBatchSize) {
+		bitmaps, err := q.EvaluateNumericAttributeValueInclusion(ctx, store.EvaluateNumericAttributeValueInclusionParams{
+			Name:   name,
+			Values: batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Or(union(ctx, bitmaps))
+	}
+
+	return res, nil
+}
+
+// evaluateExclusion returns the entities that have the attribute with the
+// given type but are not included. An entity has at most one value per
+// attribute, so these are the entities whose value is not one of the values
+// that matched the included entities.
+func evaluateExclusion(
+	ctx context.Context,
+	q *store.Queries,
+	name string,
+	numeric bool,
+	included *roaring64.Bitmap,
+) (*roaring64.Bitmap, error) {
+
+	if name == KeyAttributeKey && !numeric {
+		// every entity has a key
+		ids, err := q.EvaluateAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all := roaring64.New()
+		all.AddMany(ids)
+		all.AndNot(included)
+		return all, nil
+	}
+
+	evaluateAttribute := q.EvaluateStringAttribute
+	if numeric {
+		evaluateAttribute = q.EvaluateNumericAttribute
+	}
+
+	bitmaps, err := evaluateAttribute(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	all := union(ctx, bitmaps)
+	all.AndNot(included)
+
+	return all, nil
+}
+
+// evaluateKeys returns the entities with the keys, using the unique index on
+// the entity keys of the payloads instead of the bitmaps of $key, of which
+// there is one per entity.
+func evaluateKeys(
+	ctx context.Context,
+	q *store.Queries,
+	values []string,
+) (*roaring64.Bitmap, error) {
+
+	res := roaring64.New()
+	for batch := range slices.Chunk(entityKeys(values), inclusionBatchSize) {
+		ids, err := q.EvaluateEntityKeyInclusion(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		res.AddMany(ids)
+	}
+
+	return res, nil
+}
+
+// entityKeys decodes the values that are entity keys. The values of $key are
+// the lower case hex encoding of the keys, so other values match no entity
+// and are skipped.
+func entityKeys(values []string) [][]byte {
+	keys := make([][]byte, 0, len(values))
+	for _, v := range values {
+		if len(v) != 2+2*entityKeyLength || !strings.HasPrefix(v, "0x") || strings.ToLower(v) != v {
+			continue
+		}
+		key, err := hex.DecodeString(v[2:])
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -1,50 +1,116 @@
 package query
 
 import (
+	"regexp"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
 )
 
 const AnnotationIdentRegex string = `[\p{L}_][\p{L}\p{N}_]*`
 
-// Define the lexer with distinct tokens for each operator and parentheses.
-var lex = lexer.MustSimple([]lexer.SimpleRule{
-	{Name: "Whitespace", Pattern: `[ \t\n\r]+`},
-	{Name: "LParen", Pattern: `\(`},
-	{Name: "RParen", Pattern: `\)`},
-	{Name: "And", Pattern: `&&`},
-	{Name: "Or", Pattern: `\|\|`},
-	{Name: "Neq", Pattern: `!=`},
-	{Name: "Eq", Pattern: `=`},
-	{Name: "Geqt", Pattern: `>=`},
-	{Name: "Leqt", Pattern: `<=`},
-	{Name: "Gt", Pattern: `>`},
-	{Name: "Lt", Pattern: `<`},
-	{Name: "NotGlob", Pattern: `!~`},
-	{Name: "Glob", Pattern: `~`},
-	{Name: "Not", Pattern: `!`},
-	{Name: "EntityKey", Pattern: `0x[a-fA-F0-9]{64}`},
-	{Name: "Address", Pattern: `0x[a-fA-F0-9]{40}`},
-	{Name: "String", Pattern: `"(?:[^"\\]|\\.)*"`},
-	{Name: "Number", Pattern: `[0-9]+`},
-	{Name: "Ident", Pattern: AnnotationIdentRegex},
-	// Meta-annotations, should start with $
-	{Name: "Owner", Pattern: `\$owner`},
-	{Name: "Creator", Pattern: `\$creator`},
-	{Name: "Key", Pattern: `\$key`},
-	{Name: "Expiration", Pattern: `\$expiration`},
-	{Name: "Sequence", Pattern: `\$sequence`},
-	{Name: "All", Pattern: `\$all`},
-	{Name: "Star", Pattern: `\*`},
-})
+// GrammarVersion selects the version of the query language.
+type GrammarVersion int
+
+const (
+	// GrammarV1 is the original query language.
+	GrammarV1 GrammarVersion = 1
+	// GrammarV2 extends GrammarV1 with backtick-quoted identifiers,
+	// comma-separated IN lists, single-quoted strings, -- comments, unary !
+	// without parentheses, placeholders, $none, the payload attributes, @head,
+	// subqueries and attribute comparisons. Every GrammarV1 query is a valid
+	// GrammarV2 query with the same meaning.
+	GrammarV2 GrammarVersion = 2
+
+	LatestGrammarVersion = GrammarV2
+)
+
+// never is a pattern that never matches, used for the tokens that do not
+// exist in a grammar version.
+const never = `[^\s\S]`
+
+// newLexer defines the lexer with distinct tokens for each operator and
+// parentheses.
+func newLexer(version GrammarVersion) *lexer.StatefulDefinition {
+	// since returns pattern for the tokens introduced in a grammar version
+	since := func(introduced GrammarVersion, pattern string) string {
+		if version < introduced {
+			return never
+		}
+		return pattern
+	}
+
+	return lexer.MustSimple([]lexer.SimpleRule{
+		{Name: "Whitespace", Pattern: `[ \t\n\r]+`},
+		{Name: "Comment", Pattern: since(GrammarV2, `--[^\n]*`)},
+		{Name: "LParen", Pattern: `\(`},
+		{Name: "RParen", Pattern: `\)`},
+		{Name: "Comma", Pattern: since(GrammarV2, `,`)},
+		{Name: "And", Pattern: `&&`},
+		{Name: "Or", Pattern: `\|\|`},
+		{Name: "NotMatch", Pattern: `!=~`},
+		{Name: "NotCaseEq", Pattern: `!=\*`},
+		{Name: "NotPrefix", Pattern: `!\^=`},
+		{Name: "Neq", Pattern: `!=`},
+		{Name: "Match", Pattern: `=~`},
+		{Name: "CaseEq", Pattern: `=\*`},
+		{Name: "Prefix", Pattern: `\^=`},
+		{Name: "Eq", Pattern: `=`},
+		{Name: "Geqt", Pattern: `>=`},
+		{Name: "Leqt", Pattern: `<=`},
+		{Name: "Gt", Pattern: `>`},
+		{Name: "Lt", Pattern: `<`},
+		{Name: "NotGlob", Pattern: `!~`},
+		{Name: "Glob", Pattern: `~`},
+		// Bang is a ! that can also negate a single term
+		{Name: "Bang", Pattern: since(GrammarV2, `!`)},
+		{Name: "Not", Pattern: `!`},
+		{Name: "EntityKey", Pattern: `0x[a-fA-F0-9]{64}\b`},
+		{Name: "Address", Pattern: `0x[a-fA-F0-9]{40}\b`},
+		{Name: "String", Pattern: `"(?:[^"\\]|\\.)*"`},
+		{Name: "SingleString", Pattern: since(GrammarV2, `'(?:[^'\\]|\\.)*'`)},
+		// Hexadecimal numbers of exactly 40 or 64 digits are lexed as addresses
+		// and entity keys. Decimal numbers may be negative and have a fraction.
+		{Name: "Number", Pattern: `-?(?:0x[a-fA-F0-9]+|[0-9]+(?:\.[0-9]+)?)`},
+		{Name: "Ident", Pattern: AnnotationIdentRegex},
+		// Placeholders for bind arguments
+		{Name: "Placeholder", Pattern: since(GrammarV2, `\?`)},
+		{Name: "NamedPlaceholder", Pattern: since(GrammarV2, `:`+AnnotationIdentRegex)},
+		// Quoted identifiers can hold any attribute name
+		{Name: "QuotedIdent", Pattern: since(GrammarV2, "`(?:[^`\\\\]|\\\\.)*`")},
+		// Meta-annotations, should start with $
+		{Name: "Owner", Pattern: `\$owner`},
+		{Name: "Creator", Pattern: `\$creator`},
+		{Name: "Key", Pattern: `\$key`},
+		{Name: "Expiration", Pattern: `\$expiration`},
+		{Name: "Sequence", Pattern: `\$sequence`},
+		{Name: "ContentType", Pattern: since(GrammarV2, `\$contentType`)},
+		{Name: "PayloadSize", Pattern: since(GrammarV2, `\$payloadSize`)},
+		{Name: "PayloadHash", Pattern: since(GrammarV2, `\$payloadHash`)},
+		{Name: "Version", Pattern: since(GrammarV2, `\$version`)},
+		{Name: "All", Pattern: `\$all`},
+		{Name: "None", Pattern: since(GrammarV2, `\$none`)},
+		// The block height that the query is evaluated at, with an optional
+		// integer offset
+		{Name: "Head", Pattern: since(GrammarV2, `@head\b(?:\s*[+-]\s*(?:0x[a-fA-F0-9]+|[0-9]+)\b)?`)},
+		{Name: "Star", Pattern: `\*`},
+	})
+}
 
 type TopLevel struct {
 	Expression *Expression `parser:"@@ | All | Star"`
+	// None is set for $none, which matches no entity.
+	None bool `parser:"| @None"`
 }
 
 // Expression is the top-level rule.
 type Expression struct {
 	Or OrExpression `parser:"@@"`
+
+	// err is set by the builder functions if the expression is invalid
+	err error
 }
 
 // OrExpression handles expressions connected with ||.
@@ -71,84 +137,285 @@ type AndRHS struct {
 
 // EqualExpr can be either an equality or a parenthesized expression.
 type EqualExpr struct {
-	Paren     *Paren     `parser:"  @@"`
-	Assign    *Equality  `parser:"| @@"`
-	Inclusion *Inclusion `parser:"| @@"`
+	Paren *Paren     `parser:"  @@"`
+	Not   *EqualExpr `parser:"| Bang @@"`
+	// AttributeEqual comes first, so that the errors of equalities with an
+	// invalid value expect a value rather than an attribute
+	AttributeEqual *AttributeEquality `parser:"| @@"`
+	Assign         *Equality          `parser:"| @@"`
+	Inclusion      *Inclusion         `parser:"| @@"`
 
 	LessThan           *LessThan           `parser:"| @@"`
 	LessOrEqualThan    *LessOrEqualThan    `parser:"| @@"`
 	GreaterThan        *GreaterThan        `parser:"| @@"`
 	GreaterOrEqualThan *GreaterOrEqualThan `parser:"| @@"`
 	Glob               *Glob               `parser:"| @@"`
+	CaseEqual          *CaseEquality       `parser:"| @@"`
+	Prefix             *Prefix             `parser:"| @@"`
+	Regex              *Regex              `parser:"| @@"`
 }
 
 type Paren struct {
-	IsNot  bool       `parser:"@(Not | 'NOT' | 'not')?"`
+	IsNot  bool       `parser:"@(Not | Bang | 'NOT' | 'not')?"`
 	Nested Expression `parser:"LParen @@ RParen"`
 }
 
 type Glob struct {
-	Var   string `parser:"@Ident"`
+	Var   string `parser:"@(Ident | ContentType | PayloadHash)"`
 	IsNot bool   `parser:"((Glob | @NotGlob) | (@('NOT' | 'not')? ('GLOB' | 'glob')))"`
-	Value string `parser:"@String"`
+	Value string `parser:"(@String"`
+	Param *Param `parser:"| @@)"`
+}
+
+// CaseEquality compares string values ignoring case (e.g. name =* "Alice").
+type CaseEquality struct {
+	Var   string `parser:"@(Ident | Key | Owner | Creator | ContentType | PayloadHash)"`
+	IsNot bool   `parser:"((CaseEq | @NotCaseEq) | (@('NOT' | 'not')? ('ILIKE' | 'ilike')))"`
+	Value string `parser:"(@(String | EntityKey | Address)"`
+	Param *Param `parser:"| @@)"`
+}
+
+// Prefix matches string values starting with a prefix (e.g. name ^= "Al").
+type Prefix struct {
+	Var   string `parser:"@(Ident | Key | Owner | Creator | ContentType | PayloadHash)"`
+	IsNot bool   `parser:"(Prefix | @NotPrefix)"`
+	Value string `parser:"(@(String | EntityKey | Address)"`
+	Param *Param `parser:"| @@)"`
+}
+
+// Regex matches string values against an RE2 regular expression
+// (e.g. name =~ "^A.*e$").
+type Regex struct {
+	Var   string       `parser:"@(Ident | Key | Owner | Creator | ContentType | PayloadHash)"`
+	IsNot bool         `parser:"((Match | @NotMatch) | (@('NOT' | 'not')? ('REGEXP' | 'regexp')))"`
+	Value RegexPattern `parser:"(@String"`
+	Param *Param       `parser:"| @@)"`
+}
+
+// RegexPattern is an RE2 regular expression, validated when it is parsed.
+type RegexPattern string
+
+func (p *RegexPattern) Capture(values []string) error {
+	pattern := strings.Join(values, "")
+	if _, err := regexp.Compile(pattern); err != nil {
+		return err
+	}
+	*p = RegexPattern(pattern)
+	return nil
 }
 
 type LessThan struct {
-	Var   string `parser:"@Ident Lt"`
+	Var   string `parser:"@(Ident | Expiration | Sequence | PayloadSize | Version) Lt"`
 	Value Value  `parser:"@@"`
 }
 
 type LessOrEqualThan struct {
-	Var   string `parser:"@Ident Leqt"`
+	Var   string `parser:"@(Ident | Expiration | Sequence | PayloadSize | Version) Leqt"`
 	Value Value  `parser:"@@"`
 }
 
 type GreaterThan struct {
-	Var   string `parser:"@Ident Gt"`
+	Var   string `parser:"@(Ident | Expiration | Sequence | PayloadSize | Version) Gt"`
 	Value Value  `parser:"@@"`
 }
 
 type GreaterOrEqualThan struct {
-	Var   string `parser:"@Ident Geqt"`
+	Var   string `parser:"@(Ident | Expiration | Sequence | PayloadSize | Version) Geqt"`
 	Value Value  `parser:"@@"`
 }
 
 // Equality represents a simple equality (e.g. name = 123).
 type Equality struct {
-	Var   string `parser:"@(Ident | Key | Owner | Creator | Expiration | Sequence)"`
+	Var   string `parser:"@(Ident | Key | Owner | Creator | Expiration | Sequence | ContentType | PayloadSize | PayloadHash | Version)"`
 	IsNot bool   `parser:"(Eq | @Neq)"`
 	Value Value  `parser:"@@"`
 }
 
+// AttributeEquality compares the values of two attributes of an entity (e.g.
+// $owner = $creator). It matches the entities whose attributes have the same
+// type, and the same value or, if IsNot is set, different values.
+type AttributeEquality struct {
+	// Pos is the position of the comparison in the query, set by the parser.
+	Pos lexer.Position
+
+	Var   string `parser:"@(Ident | Key | Owner | Creator | Expiration | Sequence | ContentType | PayloadSize | PayloadHash | Version)"`
+	IsNot bool   `parser:"(Eq | @Neq)"`
+	Other string `parser:"@(Ident | Key | Owner | Creator | Expiration | Sequence | ContentType | PayloadSize | PayloadHash | Version)"`
+}
+
 type Inclusion struct {
-	Var    string `parser:"@(Ident | Key | Owner | Creator | Expiration | Sequence)"`
+	Var    string `parser:"@(Ident | Key | Owner | Creator | Expiration | Sequence | ContentType | PayloadSize | PayloadHash | Version)"`
 	IsNot  bool   `parser:"(@('NOT'|'not')? ('IN'|'in'))"`
 	Values Values `parser:"@@"`
 }
 
-// Value is a literal value (a number or a string).
+// Value is a literal value (a number or a string), a value relative to the
+// block height or a placeholder.
 type Value struct {
-	String *string `parser:"  (@String | @EntityKey | @Address)"`
-	Number *uint64 `parser:"| @Number"`
+	String *string             `parser:"  (@String | @EntityKey | @Address)"`
+	Number *store.NumericValue `parser:"| @Number"`
+	Head   *HeadOffset         `parser:"| @Head"`
+	Param  *Param              `parser:"| @@"`
 }
 
 type Values struct {
-	Strings []string `parser:"  '(' (@String | @EntityKey | @Address)+ ')'"`
-	Numbers []uint64 `parser:"| '(' @Number+ ')'"`
+	Strings  []string             `parser:"  '(' (@String | @EntityKey | @Address) (Comma? (@String | @EntityKey | @Address))* ')'"`
+	Numbers  []store.NumericValue `parser:"| '(' @Number (Comma? @Number)* ')'"`
+	Params   []*Param             `parser:"| '(' @@ (Comma? @@)* ')'"`
+	Subquery *Subquery            `parser:"| '(' @@ ')'"`
 }
 
-var Parser = participle.MustBuild[TopLevel](
-	participle.Lexer(lex),
-	participle.Elide("Whitespace"),
-	participle.Unquote("String"),
-)
+// Subquery selects the keys of the entities that match a nested query, so
+// that entities can be matched by the entities that they reference (e.g.
+// parent IN (select $key where type = "collection")).
+type Subquery struct {
+	// Pos is the position of the subquery in the query, set by the parser.
+	Pos lexer.Position
+
+	Select string      `parser:"('SELECT' | 'select') @Key"`
+	Where  *Expression `parser:"('WHERE' | 'where') (@@"`
+	// None is set for WHERE $none, Where is nil then.
+	None bool `parser:"| @None)"`
+
+	// Query is the normalised Where, set when the query is normalised.
+	Query *AST
+}
+
+// Param is a placeholder, either positional (?) or named (:name), for a value
+// that is bound when the query is run.
+type Param struct {
+	Name       string `parser:"  @NamedPlaceholder"`
+	Positional bool   `parser:"| @Placeholder"`
 
+	// Index is the position of a positional placeholder among the positional
+	// placeholders of the query.
+	Index int
+}
+
+func newParser(version GrammarVersion) *participle.Parser[TopLevel] {
+	lex := newLexer(version)
+	symbols := lex.Symbols()
+
+	// retype returns a mapper that gives tokens the type of another token
+	retype := func(name string) participle.Mapper {
+		return func(t lexer.Token) (lexer.Token, error) {
+			t.Type = symbols[name]
+			return t, nil
+		}
+	}
+
+	return participle.MustBuild[TopLevel](
+		participle.Lexer(lex),
+		participle.Elide("Whitespace", "Comment"),
+		participle.Unquote("String", "SingleString"),
+		participle.Map(retype("String"), "SingleString"),
+		participle.Map(unquoteIdent, "QuotedIdent"),
+		participle.Map(retype("Ident"), "QuotedIdent"),
+		participle.Map(func(t lexer.Token) (lexer.Token, error) {
+			t.Value = strings.TrimPrefix(t.Value, ":")
+			return t, nil
+		}, "NamedPlaceholder"),
+		// NOT can be followed by IN, GLOB, ILIKE or REGEXP
+		participle.UseLookahead(2),
+	)
+}
+
+// unquoteIdent removes the backticks around a quoted identifier. A backslash
+// escapes the next character.
+func unquoteIdent(t lexer.Token) (lexer.Token, error) {
+	var b strings.Builder
+	escaped := false
+	for _, r := range t.Value[1 : len(t.Value)-1] {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	t.Value = b.String()
+	return t, nil
+}
+
+// checkVersion returns a ParseError for the rules of the expression that are
+// newer than the grammar version. The lexer of a version already rejects the
+// tokens of newer versions, but subqueries and attribute comparisons are made
+// of tokens that GrammarV1 has.
+func (e *Expression) checkVersion(query string, version GrammarVersion) error {
+	if version >= GrammarV2 {
+		return nil
+	}
+
+	ands := []*AndExpression{&e.Or.Left}
+	for _, rhs := range e.Or.Right {
+		ands = append(ands, &rhs.Expr)
+	}
+
+	for _, and := range ands {
+		terms := []*EqualExpr{&and.Left}
+		for _, rhs := range and.Right {
+			terms = append(terms, &rhs.Expr)
+		}
+		for _, term := range terms {
+			if err := term.checkVersion(query, version); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *EqualExpr) checkVersion(query string, version GrammarVersion) error {
+	switch {
+	case e.Paren != nil:
+		return e.Paren.Nested.checkVersion(query, version)
+	case e.Not != nil:
+		return e.Not.checkVersion(query, version)
+	case e.AttributeEqual != nil:
+		return newVersionError(query, e.AttributeEqual.Pos, "attribute comparisons", version)
+	case e.Inclusion != nil && e.Inclusion.Values.Subquery != nil:
+		return newVersionError(query, e.Inclusion.Values.Subquery.Pos, "subqueries", version)
+	}
+	return nil
+}
+
+var parsers = map[GrammarVersion]*participle.Parser[TopLevel]{
+	GrammarV1: newParser(GrammarV1),
+	GrammarV2: newParser(GrammarV2),
+}
+
+// Parser parses queries in the latest grammar version.
+var Parser = parsers[LatestGrammarVersion]
+
+type ParseOptions struct {
+	// Version is the grammar version of the query, the latest if zero.
+	Version GrammarVersion
+	// MaxConjunctions limits the size of the disjunctive normal form of the
+	// query, DefaultMaxConjunctions if zero. Queries exceeding it fail with a
+	// TooManyConjunctionsError.
+	MaxConjunctions int
+	// Validation, if set, makes ParseWithOptions validate the query against
+	// a catalog of attributes, see AST.Validate.
+	Validation *Validation
+}
+
+// Parse parses a query in the latest grammar version.
 func Parse(s string) (*AST, error) {
+	return ParseWithOptions(s, ParseOptions{})
+}
 
-	v, err := Parser.ParseString("", s)
+// ParseWithOptions parses a query without placeholders.
+func ParseWithOptions(s string, options ParseOptions) (*AST, error) {
+	p, err := Compile(s, options)
 	if err != nil {
 		return nil, err
 	}
 
-	return v.Normalize(), nil
+	ast, err := p.Bind()
+	if err != nil || options.Validation == nil {
+		return ast, err
+	}
+
+	return ast.Validate(*options.Validation)
 }
@@ -2,8 +2,10 @@ package query
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 )
@@ -79,7 +81,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -93,6 +95,34 @@ func TestParse(t *testing.T) {
 		)
 	})
 
+	t.Run("hex number", func(t *testing.T) {
+		v, err := Parse(`name = 0xff`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			store.NewNumericValue(255),
+			*v.Expr.Or.Terms[0].Terms[0].Assign.Value.Number,
+		)
+	})
+
+	t.Run("uint256 number", func(t *testing.T) {
+		maxUint256 := "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+		v, err := Parse(`name < ` + maxUint256)
+		require.NoError(t, err)
+		require.Equal(t, maxUint256, v.Expr.Or.Terms[0].Terms[0].LessThan.Value.Number.String())
+
+		_, err = Parse(`name < ` + maxUint256 + `0`)
+		require.Error(t, err)
+	})
+
+	t.Run("signed decimal number", func(t *testing.T) {
+		v, err := Parse(`temperature > -1.5 && temperature < 20`)
+		require.NoError(t, err)
+
+		require.Equal(t, "-1.5", v.Expr.Or.Terms[0].Terms[0].GreaterThan.Value.Number.String())
+	})
+
 	t.Run("not parentheses", func(t *testing.T) {
 		v, err := Parse(`!(name = 123 || name = 456)`)
 		require.NoError(t, err)
@@ -110,7 +140,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: true,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -119,7 +149,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: true,
 											Value: Value{
-												Number: pointerOf(uint64(456)),
+												Number: pointerOf(store.NewNumericValue(456)),
 											},
 										},
 									},
@@ -150,7 +180,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: true,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -181,7 +211,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: true,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -211,7 +241,7 @@ func TestParse(t *testing.T) {
 										LessThan: &LessThan{
 											Var: "name",
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -267,7 +297,7 @@ func TestParse(t *testing.T) {
 										GreaterOrEqualThan: &GreaterOrEqualThan{
 											Var: "name",
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -297,7 +327,7 @@ func TestParse(t *testing.T) {
 										LessOrEqualThan: &LessOrEqualThan{
 											Var: "name",
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -355,7 +385,7 @@ func TestParse(t *testing.T) {
 										GreaterThan: &GreaterThan{
 											Var: "name",
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -413,7 +443,7 @@ func TestParse(t *testing.T) {
 										GreaterOrEqualThan: &GreaterOrEqualThan{
 											Var: "name",
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -654,7 +684,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -693,7 +723,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -740,7 +770,7 @@ func TestParse(t *testing.T) {
 											Var:   "n1",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(1)),
+												Number: pointerOf(store.NewNumericValue(1)),
 											},
 										},
 									},
@@ -753,7 +783,7 @@ func TestParse(t *testing.T) {
 											Var:   "n2",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(2)),
+												Number: pointerOf(store.NewNumericValue(2)),
 											},
 										},
 									},
@@ -762,7 +792,7 @@ func TestParse(t *testing.T) {
 											Var:   "n3",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(3)),
+												Number: pointerOf(store.NewNumericValue(3)),
 											},
 										},
 									},
@@ -771,7 +801,7 @@ func TestParse(t *testing.T) {
 											Var:   "n5",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(5)),
+												Number: pointerOf(store.NewNumericValue(5)),
 											},
 										},
 									},
@@ -784,7 +814,7 @@ func TestParse(t *testing.T) {
 											Var:   "n2",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(2)),
+												Number: pointerOf(store.NewNumericValue(2)),
 											},
 										},
 									},
@@ -793,7 +823,7 @@ func TestParse(t *testing.T) {
 											Var:   "n3",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(3)),
+												Number: pointerOf(store.NewNumericValue(3)),
 											},
 										},
 									},
@@ -802,7 +832,7 @@ func TestParse(t *testing.T) {
 											Var:   "n4",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(4)),
+												Number: pointerOf(store.NewNumericValue(4)),
 											},
 										},
 									},
@@ -835,7 +865,7 @@ func TestParse(t *testing.T) {
 											Var:   "name",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(123)),
+												Number: pointerOf(store.NewNumericValue(123)),
 											},
 										},
 									},
@@ -853,7 +883,7 @@ func TestParse(t *testing.T) {
 											Var:   "name5",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(5)),
+												Number: pointerOf(store.NewNumericValue(5)),
 											},
 										},
 									},
@@ -884,7 +914,7 @@ func TestParse(t *testing.T) {
 											Var:   "name5",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(5)),
+												Number: pointerOf(store.NewNumericValue(5)),
 											},
 										},
 									},
@@ -897,7 +927,7 @@ func TestParse(t *testing.T) {
 											Var:   "name4",
 											IsNot: false,
 											Value: Value{
-												Number: pointerOf(uint64(456)),
+												Number: pointerOf(store.NewNumericValue(456)),
 											},
 										},
 									},
@@ -916,9 +946,192 @@ func TestParse(t *testing.T) {
 		require.Error(t, err, `1:8: unexpected token "e"`)
 	})
 
+	t.Run("case insensitive equality", func(t *testing.T) {
+		v, err := Parse(`name =* "Foo" && other NOT ILIKE "bar"`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{CaseEqual: &CaseEquality{Var: "name", IsNot: false, Value: "Foo"}},
+				{CaseEqual: &CaseEquality{Var: "other", IsNot: true, Value: "bar"}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		v, err := Parse(`name ^= "fo" && $owner !^= "0xABC"`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{Prefix: &Prefix{Var: "name", IsNot: false, Value: "fo"}},
+				{Prefix: &Prefix{Var: "$owner", IsNot: true, Value: "0xabc"}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		v, err := Parse(`!(name =~ "^f.o$" || name regexp "x+")`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{Regex: &Regex{Var: "name", IsNot: true, Value: "^f.o$"}},
+				{Regex: &Regex{Var: "name", IsNot: true, Value: "x+"}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		_, err := Parse(`name =~ "(foo"`)
+		require.ErrorContains(t, err, "missing closing )")
+	})
+
 	t.Run("invalid expression", func(t *testing.T) {
 		_, err := Parse(`key = 8e`)
 		require.Error(t, err, `1:8: unexpected token "e"`)
 	})
 
+	t.Run("payload attributes", func(t *testing.T) {
+		hash := "0x" + strings.Repeat("AB", 32)
+		v, err := Parse(`$contentType ^= "image/" && $payloadSize <= 100 && $version IN (1 2) && $payloadHash = ` + hash)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{Prefix: &Prefix{Var: ContentTypeAttributeKey, Value: "image/"}},
+				{LessOrEqualThan: &LessOrEqualThan{Var: PayloadSizeAttributeKey, Value: Value{Number: pointerOf(store.NewNumericValue(100))}}},
+				{Inclusion: &Inclusion{Var: VersionAttributeKey, Values: Values{Numbers: []store.NumericValue{store.NewNumericValue(1), store.NewNumericValue(2)}}}},
+				{Assign: &Equality{Var: PayloadHashAttributeKey, Value: Value{String: pointerOf(strings.ToLower(hash))}}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+
+		p, err := Compile(`$payloadSize = ?`, ParseOptions{})
+		require.NoError(t, err)
+		_, err = p.Bind("large")
+		require.Error(t, err)
+	})
+
+}
+
+func TestGrammarV2(t *testing.T) {
+	parseV1 := func(s string) (*AST, error) {
+		return ParseWithOptions(s, ParseOptions{Version: GrammarV1})
+	}
+
+	t.Run("accepts v1 queries", func(t *testing.T) {
+		for _, q := range []string{
+			`name = "foo" && !(age < 5 || $owner = 0x1234567890123456789012345678901234567890)`,
+			`name in ("a" "b") || age not in (1 2 3)`,
+			`name ~ "f*" AND name NOT GLOB "g*" or x != -1.5`,
+			`$all`,
+			`*`,
+		} {
+			v1, err := parseV1(q)
+			require.NoError(t, err, q)
+			v2, err := Parse(q)
+			require.NoError(t, err, q)
+			require.Equal(t, v1, v2, q)
+		}
+	})
+
+	t.Run("quoted identifiers", func(t *testing.T) {
+		v, err := Parse("`content-type.v1:x` = \"a\" && `we\\`ird` = 1")
+		require.NoError(t, err)
+
+		require.Equal(t, "content-type.v1:x", v.Expr.Or.Terms[0].Terms[0].Assign.Var)
+		require.Equal(t, "we`ird", v.Expr.Or.Terms[0].Terms[1].Assign.Var)
+	})
+
+	t.Run("comma separated lists", func(t *testing.T) {
+		v, err := Parse(`name IN ('a', "b", 'c') && age IN (1, 2 3)`)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"a", "b", "c"}, v.Expr.Or.Terms[0].Terms[0].Inclusion.Values.Strings)
+		require.Len(t, v.Expr.Or.Terms[0].Terms[1].Inclusion.Values.Numbers, 3)
+	})
+
+	t.Run("single quoted strings", func(t *testing.T) {
+		v, err := Parse(`name = 'it\'s "quoted"'`)
+		require.NoError(t, err)
+
+		require.Equal(t, `it's "quoted"`, *v.Expr.Or.Terms[0].Terms[0].Assign.Value.String)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		v, err := Parse("name = \"a\" -- only a\n&& age = 1 -- and 1")
+		require.NoError(t, err)
+
+		require.Len(t, v.Expr.Or.Terms[0].Terms, 2)
+	})
+
+	t.Run("unary not", func(t *testing.T) {
+		v, err := Parse(`!name = "a" && !!age < 5 && !(x = 1)`)
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			[]ASTTerm{
+				{Assign: &Equality{Var: "name", IsNot: true, Value: Value{String: pointerOf("a")}}},
+				{LessThan: &LessThan{Var: "age", Value: Value{Number: pointerOf(store.NewNumericValue(5))}}},
+				{Assign: &Equality{Var: "x", IsNot: true, Value: Value{Number: pointerOf(store.NewNumericValue(1))}}},
+			},
+			v.Expr.Or.Terms[0].Terms,
+		)
+	})
+
+	t.Run("v1 rejects v2 syntax", func(t *testing.T) {
+		for _, q := range []string{
+			"`name` = 1",
+			`name IN (1, 2)`,
+			`name = 'a'`,
+			`name = 1 -- comment`,
+			`!name = 1`,
+			`name = ?`,
+			`name IN (:names)`,
+			`$none`,
+			`$contentType = "text/plain"`,
+			`$payloadSize > 10`,
+			`$payloadHash = "a"`,
+			`$version = 1`,
+			`$expiration < @head + 5`,
+			`parent IN (select $key where name = 1)`,
+			`$owner = $creator`,
+			`name != other`,
+		} {
+			_, err := parseV1(q)
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr, q)
+
+			_, err = Compile(q, ParseOptions{})
+			require.NoError(t, err, q)
+		}
+	})
+
+	t.Run("v1 error positions", func(t *testing.T) {
+		_, err := parseV1(`a = 1 && parent IN (select $key where a = 1)`)
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, 21, perr.Column)
+		require.Equal(t, "select", perr.Unexpected)
+		require.Equal(t, "subqueries are not supported in grammar version 1", perr.Message)
+
+		_, err = parseV1(`a = 1 || !(b = 2 && $owner != $creator)`)
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, 21, perr.Column)
+		require.Equal(t, "attribute comparisons are not supported in grammar version 1", perr.Message)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := ParseWithOptions(`name = 1`, ParseOptions{Version: 3})
+		require.ErrorContains(t, err, "unknown grammar version 3")
+	})
 }
@@ -7,6 +7,8 @@ import (
 
 type AST struct {
 	Expr *ASTExpr
+	// Warnings are the problems that Validate found in the query.
+	Warnings []ValidationWarning
 }
 type ASTExpr struct {
 	Or ASTOr
@@ -25,6 +27,11 @@ type ASTTerm struct {
 	GreaterThan        *GreaterThan
 	GreaterOrEqualThan *GreaterOrEqualThan
 	Glob               *Glob
+	CaseEqual          *CaseEquality
+	Prefix             *Prefix
+	Regex              *Regex
+	Range              *Range
+	AttributeEqual     *AttributeEquality
 }
 
 func (t *TopLevel) Normalize() *AST {
@@ -33,6 +40,10 @@ func (t *TopLevel) Normalize() *AST {
 			Expr: t.Expression.Normalize(),
 		}
 	}
+	if t.None {
+		// an empty disjunction matches nothing
+		return &AST{Expr: &ASTExpr{Or: ASTOr{Terms: []ASTAnd{}}}}
+	}
 	return &AST{}
 }
 
@@ -119,6 +130,11 @@ func (e *EqualExpr) convertToTerms() [][]ASTTerm {
 	// First level is OR, second level is AND
 	es := [][]ASTTerm{}
 
+	if e.Not != nil {
+		// Push the negation into the negated term
+		return e.Not.invert().convertToTerms()
+	}
+
 	if e.Paren != nil {
 		// This is where we recursively convert to DNF and also where negations
 		// get pushed down
@@ -209,6 +225,10 @@ func (e *EqualExpr) Normalize() ASTTerm {
 		panic("Called EqualExpr::Normalize on a paren, this is a bug!")
 	}
 
+	if e.Not != nil {
+		return e.Not.invert().Normalize()
+	}
+
 	if e.LessThan != nil {
 		return ASTTerm{LessThan: e.LessThan.Normalize()}
 	}
@@ -229,6 +249,18 @@ func (e *EqualExpr) Normalize() ASTTerm {
 		return ASTTerm{Glob: e.Glob.Normalize()}
 	}
 
+	if e.CaseEqual != nil {
+		return ASTTerm{CaseEqual: e.CaseEqual}
+	}
+
+	if e.Prefix != nil {
+		return ASTTerm{Prefix: e.Prefix.Normalize()}
+	}
+
+	if e.Regex != nil {
+		return ASTTerm{Regex: e.Regex}
+	}
+
 	if e.Assign != nil {
 		return ASTTerm{Assign: e.Assign.Normalize()}
 	}
@@ -237,6 +269,10 @@ func (e *EqualExpr) Normalize() ASTTerm {
 		return ASTTerm{Inclusion: e.Inclusion.Normalize()}
 	}
 
+	if e.AttributeEqual != nil {
+		return ASTTerm{AttributeEqual: e.AttributeEqual.Normalize()}
+	}
+
 	panic("This should not happen!")
 }
 
@@ -245,6 +281,10 @@ func (e *EqualExpr) invert() *EqualExpr {
 		return &EqualExpr{Paren: e.Paren.invert()}
 	}
 
+	if e.Not != nil {
+		return e.Not
+	}
+
 	if e.LessThan != nil {
 		return &EqualExpr{GreaterOrEqualThan: e.LessThan.invert()}
 	}
@@ -265,6 +305,18 @@ func (e *EqualExpr) invert() *EqualExpr {
 		return &EqualExpr{Glob: e.Glob.invert()}
 	}
 
+	if e.CaseEqual != nil {
+		return &EqualExpr{CaseEqual: e.CaseEqual.invert()}
+	}
+
+	if e.Prefix != nil {
+		return &EqualExpr{Prefix: e.Prefix.invert()}
+	}
+
+	if e.Regex != nil {
+		return &EqualExpr{Regex: e.Regex.invert()}
+	}
+
 	if e.Assign != nil {
 		return &EqualExpr{Assign: e.Assign.invert()}
 	}
@@ -273,6 +325,10 @@ func (e *EqualExpr) invert() *EqualExpr {
 		return &EqualExpr{Inclusion: e.Inclusion.invert()}
 	}
 
+	if e.AttributeEqual != nil {
+		return &EqualExpr{AttributeEqual: e.AttributeEqual.invert()}
+	}
+
 	panic("This should not happen!")
 }
 
@@ -303,12 +359,62 @@ func (e *Glob) invert() *Glob {
 		Var:   e.Var,
 		IsNot: !e.IsNot,
 		Value: e.Value,
+		Param: e.Param,
+	}
+}
+
+func (e *CaseEquality) invert() *CaseEquality {
+	return &CaseEquality{
+		Var:   e.Var,
+		IsNot: !e.IsNot,
+		Value: e.Value,
+		Param: e.Param,
+	}
+}
+
+func (e *Prefix) Normalize() *Prefix {
+	if e.Param != nil {
+		return e
+	}
+
+	switch e.Var {
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
+		return &Prefix{
+			Var:   e.Var,
+			IsNot: e.IsNot,
+			Value: strings.ToLower(e.Value),
+		}
+	default:
+		return e
+	}
+}
+
+func (e *Prefix) invert() *Prefix {
+	return &Prefix{
+		Var:   e.Var,
+		IsNot: !e.IsNot,
+		Value: e.Value,
+		Param: e.Param,
+	}
+}
+
+func (e *Regex) invert() *Regex {
+	return &Regex{
+		Var:   e.Var,
+		IsNot: !e.IsNot,
+		Value: e.Value,
+		Param: e.Param,
 	}
 }
 
 func (e *LessThan) Normalize() *LessThan {
+	if e.Value.String == nil {
+		// numbers and unbound placeholders are kept as they are
+		return e
+	}
+
 	switch e.Var {
-	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey:
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
 		val := strings.ToLower(*e.Value.String)
 		return &LessThan{
 			Var: e.Var,
@@ -329,8 +435,13 @@ func (e *LessThan) invert() *GreaterOrEqualThan {
 }
 
 func (e *LessOrEqualThan) Normalize() *LessOrEqualThan {
+	if e.Value.String == nil {
+		// numbers and unbound placeholders are kept as they are
+		return e
+	}
+
 	switch e.Var {
-	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey:
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
 		val := strings.ToLower(*e.Value.String)
 		return &LessOrEqualThan{
 			Var: e.Var,
@@ -351,8 +462,13 @@ func (e *LessOrEqualThan) invert() *GreaterThan {
 }
 
 func (e *GreaterThan) Normalize() *GreaterThan {
+	if e.Value.String == nil {
+		// numbers and unbound placeholders are kept as they are
+		return e
+	}
+
 	switch e.Var {
-	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey:
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
 		val := strings.ToLower(*e.Value.String)
 		return &GreaterThan{
 			Var: e.Var,
@@ -373,8 +489,13 @@ func (e *GreaterThan) invert() *LessOrEqualThan {
 }
 
 func (e *GreaterOrEqualThan) Normalize() *GreaterOrEqualThan {
+	if e.Value.String == nil {
+		// numbers and unbound placeholders are kept as they are
+		return e
+	}
+
 	switch e.Var {
-	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey:
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
 		val := strings.ToLower(*e.Value.String)
 		return &GreaterOrEqualThan{
 			Var: e.Var,
@@ -395,8 +516,13 @@ func (e *GreaterOrEqualThan) invert() *LessThan {
 }
 
 func (e *Equality) Normalize() *Equality {
+	if e.Value.String == nil {
+		// numbers and unbound placeholders are kept as they are
+		return e
+	}
+
 	switch e.Var {
-	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey:
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
 		val := strings.ToLower(*e.Value.String)
 		return &Equality{
 			Var:   e.Var,
@@ -419,14 +545,27 @@ func (e *Equality) invert() *Equality {
 }
 
 func (e *Inclusion) Normalize() *Inclusion {
+	if e.Values.Subquery != nil {
+		return &Inclusion{
+			Var:    e.Var,
+			IsNot:  e.IsNot,
+			Values: Values{Subquery: e.Values.Subquery.normalize()},
+		}
+	}
+
+	if len(e.Values.Params) != 0 {
+		return e
+	}
+
 	switch e.Var {
-	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey:
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, PayloadHashAttributeKey:
 		vals := make([]string, 0, len(e.Values.Strings))
 		for _, val := range e.Values.Strings {
 			vals = append(vals, strings.ToLower(val))
 		}
 		return &Inclusion{
-			Var: e.Var,
+			Var:   e.Var,
+			IsNot: e.IsNot,
 			Values: Values{
 				Strings: vals,
 			},
@@ -0,0 +1,308 @@
+package query
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// The terms of a conjunction are evaluated in the order of their estimated
+// selectivity, using the cardinality statistics of the bitmaps. Every term
+// requires the entity to have the attribute, so a term whose estimate is zero
+// matches nothing and the whole conjunction is empty without loading any
+// bitmap.
+
+// plannedTerm is a term with its estimated number of matching entities and
+// the number of bitmaps that evaluating it loads.
+type plannedTerm struct {
+	term      *ASTTerm
+	estimated bool
+	estimate  uint64
+	cost      uint64
+}
+
+// plan returns the terms of the conjunction in the order to evaluate them, or
+// false if the conjunction matches nothing.
+func (e *ASTAnd) plan(ctx context.Context, q *store.Queries, trace *ConjunctionTrace) ([]plannedTerm, bool, error) {
+	if len(e.Terms) == 1 {
+		planned := []plannedTerm{{term: &e.Terms[0]}}
+		trace.plan(planned)
+		return planned, true, nil
+	}
+
+	planned := make([]plannedTerm, 0, len(e.Terms))
+	for i := range e.Terms {
+		trace.term(&e.Terms[i])
+		p, err := e.Terms[i].plan(ctx, q)
+		if err != nil {
+			return nil, false, err
+		}
+		if p.estimate == 0 {
+			trace.plan([]plannedTerm{p})
+			return nil, false, nil
+		}
+		planned = append(planned, p)
+	}
+
+	slices.SortStableFunc(planned, func(a, b plannedTerm) int {
+		return cmp.Or(cmp.Compare(a.estimate, b.estimate), cmp.Compare(a.cost, b.cost))
+	})
+	trace.plan(planned)
+
+	return planned, true, nil
+}
+
+type attributeStatistics struct {
+	distinctValues uint64
+	cardinality    uint64
+}
+
+// attributeStats returns the statistics of an attribute, which are zero if no
+// entity has it.
+func attributeStats(ctx context.Context, q *store.Queries, name string, numeric bool) (attributeStatistics, error) {
+	if numeric {
+		row, err := q.GetNumericAttributeStats(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return attributeStatistics{}, nil
+		}
+		return attributeStatistics{distinctValues: row.DistinctValues, cardinality: row.Cardinality}, err
+	}
+
+	row, err := q.GetStringAttributeStats(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return attributeStatistics{}, nil
+	}
+	return attributeStatistics{distinctValues: row.DistinctValues, cardinality: row.Cardinality}, err
+}
+
+// plan estimates the number of entities that the term matches. Terms that
+// are not answered from the statistics are estimated by the number of
+// entities with the attribute.
+func (t *ASTTerm) plan(ctx context.Context, q *store.Queries) (plannedTerm, error) {
+	if t.AttributeEqual != nil {
+		// the compared attributes can have either type
+		return t.AttributeEqual.plan(ctx, q, plannedTerm{term: t, estimated: true})
+	}
+
+	name, numeric := t.variable()
+
+	stats, err := attributeStats(ctx, q, name, numeric)
+	if err != nil {
+		return plannedTerm{}, err
+	}
+
+	p := plannedTerm{term: t, estimated: true, estimate: stats.cardinality, cost: stats.distinctValues}
+	if stats.cardinality == 0 {
+		return p, nil
+	}
+
+	// negated terms match the entities with the attribute that the positive
+	// term does not match
+	var matching int64
+	negated := false
+
+	switch {
+	case name == KeyAttributeKey && !numeric && (t.Assign != nil || t.Inclusion != nil && t.subquery() == nil):
+		// keys are looked up in the index of the payloads, and every key
+		// matches at most one entity. Keys need not exist, so this only
+		// bounds the entities that a positive term matches.
+		if t.Assign != nil && t.Assign.IsNot || t.Inclusion != nil && t.Inclusion.IsNot {
+			return p, nil
+		}
+		keys := []string{}
+		for _, v := range t.values() {
+			keys = append(keys, *v.String)
+		}
+		p.estimate = min(uint64(len(entityKeys(keys))), stats.cardinality)
+		p.cost = 1
+		return p, nil
+	case t.Assign != nil:
+		matching, err = estimateInclusion(ctx, q, name, []Value{t.Assign.Value})
+		negated = t.Assign.IsNot
+		if !negated {
+			p.cost = 1
+		}
+	case t.Inclusion != nil && t.Inclusion.Values.Subquery != nil:
+		// the keys that a subquery selects are only known once it is
+		// evaluated
+		return p, nil
+	case t.Inclusion != nil:
+		matching, err = estimateInclusion(ctx, q, name, t.values())
+		negated = t.Inclusion.IsNot
+		if !negated {
+			p.cost = uint64(len(t.values()))
+		}
+	case t.LessThan != nil:
+		matching, err = estimateLowerThan(ctx, q, name, t.LessThan.Value)
+	case t.LessOrEqualThan != nil:
+		matching, err = estimateLessOrEqualThan(ctx, q, name, t.LessOrEqualThan.Value)
+	case t.GreaterThan != nil:
+		matching, err = estimateGreaterThan(ctx, q, name, t.GreaterThan.Value)
+	case t.GreaterOrEqualThan != nil:
+		matching, err = estimateGreaterOrEqualThan(ctx, q, name, t.GreaterOrEqualThan.Value)
+	case t.Range != nil:
+		matching, err = estimateRange(ctx, q, t.Range)
+	case t.Glob != nil:
+		matching, err = q.EstimateStringAttributeValueGlob(ctx, store.EstimateStringAttributeValueGlobParams{
+			Name:  name,
+			Value: t.Glob.Value,
+		})
+		negated = t.Glob.IsNot
+	case t.Prefix != nil:
+		matching, err = estimatePrefix(ctx, q, name, t.Prefix.Value)
+		negated = t.Prefix.IsNot
+	default:
+		// case insensitive equality and regular expressions match the values
+		// in Go, so the statistics cannot tell how many match
+		return p, nil
+	}
+	if err != nil {
+		return plannedTerm{}, err
+	}
+
+	if negated {
+		p.estimate = stats.cardinality - min(uint64(matching), stats.cardinality)
+	} else {
+		p.estimate = uint64(matching)
+	}
+
+	return p, nil
+}
+
+// variable returns the attribute of the term and whether it is numeric.
+func (t *ASTTerm) variable() (string, bool) {
+	switch {
+	case t.Assign != nil:
+		return t.Assign.Var, t.Assign.Value.numeric()
+	case t.Inclusion != nil:
+		return t.Inclusion.Var, len(t.Inclusion.Values.Numbers) != 0
+	case t.LessThan != nil:
+		return t.LessThan.Var, t.LessThan.Value.numeric()
+	case t.LessOrEqualThan != nil:
+		return t.LessOrEqualThan.Var, t.LessOrEqualThan.Value.numeric()
+	case t.GreaterThan != nil:
+		return t.GreaterThan.Var, t.GreaterThan.Value.numeric()
+	case t.GreaterOrEqualThan != nil:
+		return t.GreaterOrEqualThan.Var, t.GreaterOrEqualThan.Value.numeric()
+	case t.Range != nil:
+		return t.Range.Var, t.Range.From.numeric()
+	case t.Glob != nil:
+		return t.Glob.Var, false
+	case t.CaseEqual != nil:
+		return t.CaseEqual.Var, false
+	case t.Prefix != nil:
+		return t.Prefix.Var, false
+	case t.Regex != nil:
+		return t.Regex.Var, false
+	case t.AttributeEqual != nil:
+		return t.AttributeEqual.Var, false
+	}
+	return "", false
+}
+
+// numeric reports whether the value is a number. Values relative to the block
+// height are numbers once they are resolved.
+func (v Value) numeric() bool {
+	return v.Number != nil || v.Head != nil
+}
+
+// estimateInclusion sums the estimates of the values in batches, like
+// Inclusion.Evaluate.
+func estimateInclusion(ctx context.Context, q *store.Queries, name string, values []Value) (int64, error) {
+	var total int64
+	for batch := range slices.Chunk(values, inclusionBatchSize) {
+		var (
+			n   int64
+			err error
+		)
+		if batch[0].Number != nil {
+			numbers := make([]store.NumericValue, 0, len(batch))
+			for _, v := range batch {
+				numbers = append(numbers, *v.Number)
+			}
+			n, err = q.EstimateNumericAttributeValueInclusion(ctx, store.EstimateNumericAttributeValueInclusionParams{
+				Name:   name,
+				Values: numbers,
+			})
+		} else {
+			strs := make([]string, 0, len(batch))
+			for _, v := range batch {
+				strs = append(strs, *v.String)
+			}
+			n, err = q.EstimateStringAttributeValueInclusion(ctx, store.EstimateStringAttributeValueInclusionParams{
+				Name:   name,
+				Values: strs,
+			})
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func estimateLowerThan(ctx context.Context, q *store.Queries, name string, v Value) (int64, error) {
+	if v.Number != nil {
+		return q.EstimateNumericAttributeValueLowerThan(ctx, store.EstimateNumericAttributeValueLowerThanParams{Name: name, Value: *v.Number})
+	}
+	return q.EstimateStringAttributeValueLowerThan(ctx, store.EstimateStringAttributeValueLowerThanParams{Name: name, Value: *v.String})
+}
+
+func estimateLessOrEqualThan(ctx context.Context, q *store.Queries, name string, v Value) (int64, error) {
+	if v.Number != nil {
+		return q.EstimateNumericAttributeValueLessOrEqualThan(ctx, store.EstimateNumericAttributeValueLessOrEqualThanParams{Name: name, Value: *v.Number})
+	}
+	return q.EstimateStringAttributeValueLessOrEqualThan(ctx, store.EstimateStringAttributeValueLessOrEqualThanParams{Name: name, Value: *v.String})
+}
+
+func estimateGreaterThan(ctx context.Context, q *store.Queries, name string, v Value) (int64, error) {
+	if v.Number != nil {
+		return q.EstimateNumericAttributeValueGreaterThan(ctx, store.EstimateNumericAttributeValueGreaterThanParams{Name: name, Value: *v.Number})
+	}
+	return q.EstimateStringAttributeValueGreaterThan(ctx, store.EstimateStringAttributeValueGreaterThanParams{Name: name, Value: *v.String})
+}
+
+func estimateGreaterOrEqualThan(ctx context.Context, q *store.Queries, name string, v Value) (int64, error) {
+	if v.Number != nil {
+		return q.EstimateNumericAttributeValueGreaterOrEqualThan(ctx, store.EstimateNumericAttributeValueGreaterOrEqualThanParams{Name: name, Value: *v.Number})
+	}
+	return q.EstimateStringAttributeValueGreaterOrEqualThan(ctx, store.EstimateStringAttributeValueGreaterOrEqualThanParams{Name: name, Value: *v.String})
+}
+
+func estimateRange(ctx context.Context, q *store.Queries, r *Range) (int64, error) {
+	if r.From.Number != nil {
+		return q.EstimateNumericAttributeValueBetween(ctx, store.EstimateNumericAttributeValueBetweenParams{
+			Name:          r.Var,
+			FromValue:     *r.From.Number,
+			ToValue:       *r.To.Number,
+			FromInclusive: r.FromInclusive,
+			ToInclusive:   r.ToInclusive,
+		})
+	}
+	return q.EstimateStringAttributeValueBetween(ctx, store.EstimateStringAttributeValueBetweenParams{
+		Name:          r.Var,
+		FromValue:     *r.From.String,
+		ToValue:       *r.To.String,
+		FromInclusive: r.FromInclusive,
+		ToInclusive:   r.ToInclusive,
+	})
+}
+
+func estimatePrefix(ctx context.Context, q *store.Queries, name string, prefix string) (int64, error) {
+	upper, bounded := store.PrefixUpperBound(prefix)
+	if !bounded {
+		return q.EstimateStringAttributeValueGreaterOrEqualThan(ctx, store.EstimateStringAttributeValueGreaterOrEqualThanParams{Name: name, Value: prefix})
+	}
+	return q.EstimateStringAttributeValueBetween(ctx, store.EstimateStringAttributeValueBetweenParams{
+		Name:          name,
+		FromValue:     prefix,
+		ToValue:       upper,
+		FromInclusive: true,
+		ToInclusive:   false,
+	})
+}
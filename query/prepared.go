@@ -0,0 +1,522 @@
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// ParamKind is the kind of value that a placeholder accepts.
+type ParamKind int
+
+const (
+	// AnyParam accepts both strings and numbers.
+	AnyParam ParamKind = iota
+	StringParam
+	NumericParam
+)
+
+func (k ParamKind) String() string {
+	switch k {
+	case StringParam:
+		return "string"
+	case NumericParam:
+		return "numeric"
+	default:
+		return "any"
+	}
+}
+
+// NamedArg is the argument for a named placeholder.
+type NamedArg struct {
+	Name  string
+	Value any
+}
+
+// Named returns the argument for the placeholder :name.
+func Named(name string, value any) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// Prepared is a parsed and normalised query that can be run many times with
+// different arguments for its placeholders. It is safe for concurrent use.
+type Prepared struct {
+	ast *AST
+
+	positional []ParamKind
+	named      map[string]ParamKind
+}
+
+// Compile parses a query that may contain placeholders.
+func Compile(s string, options ParseOptions) (*Prepared, error) {
+
+	version := options.Version
+	if version == 0 {
+		version = LatestGrammarVersion
+	}
+
+	parser, ok := parsers[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown grammar version %d", version)
+	}
+
+	v, err := parser.ParseString("", s)
+	if err != nil {
+		return nil, newParseError(s, err)
+	}
+
+	p := &Prepared{
+		named: map[string]ParamKind{},
+	}
+
+	if v.Expression != nil {
+		err = v.Expression.checkVersion(s, version)
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.collectParams(&v.Expression.Or)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkConjunctions(v.Expression, options.MaxConjunctions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.ast = v.Normalize()
+
+	return p, nil
+}
+
+// Params returns the kinds of the positional placeholders, in order, and of
+// the named placeholders.
+func (p *Prepared) Params() ([]ParamKind, map[string]ParamKind) {
+	named := make(map[string]ParamKind, len(p.named))
+	for k, v := range p.named {
+		named[k] = v
+	}
+	return append([]ParamKind(nil), p.positional...), named
+}
+
+func (p *Prepared) collectParams(e *OrExpression) error {
+	ands := []*AndExpression{&e.Left}
+	for _, rhs := range e.Right {
+		ands = append(ands, &rhs.Expr)
+	}
+
+	for _, and := range ands {
+		terms := []*EqualExpr{&and.Left}
+		for _, rhs := range and.Right {
+			terms = append(terms, &rhs.Expr)
+		}
+		for _, term := range terms {
+			if err := p.collectTermParams(term); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Prepared) collectTermParams(e *EqualExpr) error {
+	switch {
+	case e.Paren != nil:
+		return p.collectParams(&e.Paren.Nested.Or)
+	case e.Not != nil:
+		return p.collectTermParams(e.Not)
+	case e.Assign != nil:
+		return p.addParam(e.Assign.Value.Param, varParamKind(e.Assign.Var))
+	case e.Inclusion != nil && e.Inclusion.Values.Subquery != nil:
+		if e.Inclusion.Values.Subquery.None {
+			return nil
+		}
+		return p.collectParams(&e.Inclusion.Values.Subquery.Where.Or)
+	case e.Inclusion != nil:
+		for _, param := range e.Inclusion.Values.Params {
+			if err := p.addParam(param, varParamKind(e.Inclusion.Var)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case e.LessThan != nil:
+		return p.addParam(e.LessThan.Value.Param, AnyParam)
+	case e.LessOrEqualThan != nil:
+		return p.addParam(e.LessOrEqualThan.Value.Param, AnyParam)
+	case e.GreaterThan != nil:
+		return p.addParam(e.GreaterThan.Value.Param, AnyParam)
+	case e.GreaterOrEqualThan != nil:
+		return p.addParam(e.GreaterOrEqualThan.Value.Param, AnyParam)
+	case e.Glob != nil:
+		return p.addParam(e.Glob.Param, StringParam)
+	case e.CaseEqual != nil:
+		return p.addParam(e.CaseEqual.Param, StringParam)
+	case e.Prefix != nil:
+		return p.addParam(e.Prefix.Param, StringParam)
+	case e.Regex != nil:
+		return p.addParam(e.Regex.Param, StringParam)
+	}
+	return nil
+}
+
+// varParamKind returns the kind of the values of an attribute, if it is known
+// from the name of the attribute.
+func varParamKind(name string) ParamKind {
+	switch name {
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, ContentTypeAttributeKey, PayloadHashAttributeKey:
+		return StringParam
+	case ExpirationAttributeKey, SequenceAttributeKey, PayloadSizeAttributeKey, VersionAttributeKey:
+		return NumericParam
+	default:
+		return AnyParam
+	}
+}
+
+func (p *Prepared) addParam(param *Param, kind ParamKind) error {
+	if param == nil {
+		return nil
+	}
+
+	if param.Positional {
+		param.Index = len(p.positional)
+		p.positional = append(p.positional, kind)
+		return nil
+	}
+
+	existing, ok := p.named[param.Name]
+	switch {
+	case !ok || existing == AnyParam:
+		p.named[param.Name] = kind
+	case kind != AnyParam && kind != existing:
+		return &ParseError{
+			Message: fmt.Sprintf("parameter :%s is used both as a %s and as a %s value", param.Name, existing, kind),
+		}
+	}
+
+	return nil
+}
+
+// Bind returns the query with the placeholders replaced by args. Positional
+// placeholders take the arguments in order, named placeholders take the
+// NamedArg arguments with their name. Placeholders in IN lists also accept
+// slices, which are expanded into the list. Arguments that do not match the
+// placeholders fail with a BindError.
+func (p *Prepared) Bind(args ...any) (*AST, error) {
+	b := binder{
+		positional: []any{},
+		named:      map[string]any{},
+	}
+
+	for _, arg := range args {
+		if named, ok := arg.(NamedArg); ok {
+			if _, ok := p.named[named.Name]; !ok {
+				return nil, newBindError(fmt.Errorf("query has no parameter :%s", named.Name))
+			}
+			b.named[named.Name] = named.Value
+			continue
+		}
+		b.positional = append(b.positional, arg)
+	}
+
+	if len(b.positional) != len(p.positional) {
+		return nil, newBindError(fmt.Errorf("query has %d positional parameters, got %d arguments", len(p.positional), len(b.positional)))
+	}
+
+	for name := range p.named {
+		if _, ok := b.named[name]; !ok {
+			return nil, newBindError(fmt.Errorf("missing argument for parameter :%s", name))
+		}
+	}
+
+	ast, err := b.bind(p.ast)
+	if err != nil {
+		return nil, newBindError(err)
+	}
+	return ast, nil
+}
+
+type binder struct {
+	positional []any
+	named      map[string]any
+}
+
+// bind returns a copy of the AST with its placeholders replaced.
+func (b *binder) bind(ast *AST) (*AST, error) {
+	if ast.Expr == nil {
+		return &AST{}, nil
+	}
+
+	or := ASTOr{Terms: make([]ASTAnd, 0, len(ast.Expr.Or.Terms))}
+	for _, and := range ast.Expr.Or.Terms {
+		terms := make([]ASTTerm, 0, len(and.Terms))
+		for _, term := range and.Terms {
+			bound, err := b.bindTerm(term)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, bound)
+		}
+		or.Terms = append(or.Terms, ASTAnd{Terms: terms})
+	}
+
+	return &AST{Expr: &ASTExpr{Or: or}}, nil
+}
+
+func (b *binder) arg(param *Param) (any, string) {
+	if param.Positional {
+		return b.positional[param.Index], fmt.Sprintf("parameter %d", param.Index+1)
+	}
+	return b.named[param.Name], fmt.Sprintf("parameter :%s", param.Name)
+}
+
+func (b *binder) bindTerm(t ASTTerm) (ASTTerm, error) {
+	switch {
+	case t.subquery() != nil:
+		return t.mapSubquery(b.bind)
+
+	case t.Assign != nil && t.Assign.Value.Param != nil:
+		e := *t.Assign
+		v, err := b.value(e.Value.Param, varParamKind(e.Var))
+		if err != nil {
+			return t, err
+		}
+		e.Value = v
+		return ASTTerm{Assign: e.Normalize()}, nil
+
+	case t.Inclusion != nil && len(t.Inclusion.Values.Params) != 0:
+		e := *t.Inclusion
+		v, err := b.values(e.Values.Params, varParamKind(e.Var))
+		if err != nil {
+			return t, err
+		}
+		e.Values = v
+		return ASTTerm{Inclusion: e.Normalize()}, nil
+
+	case t.LessThan != nil && t.LessThan.Value.Param != nil:
+		e := *t.LessThan
+		v, err := b.value(e.Value.Param, AnyParam)
+		if err != nil {
+			return t, err
+		}
+		e.Value = v
+		return ASTTerm{LessThan: e.Normalize()}, nil
+
+	case t.LessOrEqualThan != nil && t.LessOrEqualThan.Value.Param != nil:
+		e := *t.LessOrEqualThan
+		v, err := b.value(e.Value.Param, AnyParam)
+		if err != nil {
+			return t, err
+		}
+		e.Value = v
+		return ASTTerm{LessOrEqualThan: e.Normalize()}, nil
+
+	case t.GreaterThan != nil && t.GreaterThan.Value.Param != nil:
+		e := *t.GreaterThan
+		v, err := b.value(e.Value.Param, AnyParam)
+		if err != nil {
+			return t, err
+		}
+		e.Value = v
+		return ASTTerm{GreaterThan: e.Normalize()}, nil
+
+	case t.GreaterOrEqualThan != nil && t.GreaterOrEqualThan.Value.Param != nil:
+		e := *t.GreaterOrEqualThan
+		v, err := b.value(e.Value.Param, AnyParam)
+		if err != nil {
+			return t, err
+		}
+		e.Value = v
+		return ASTTerm{GreaterOrEqualThan: e.Normalize()}, nil
+
+	case t.Glob != nil && t.Glob.Param != nil:
+		e := *t.Glob
+		s, err := b.string(e.Param)
+		if err != nil {
+			return t, err
+		}
+		e.Value, e.Param = s, nil
+		return ASTTerm{Glob: &e}, nil
+
+	case t.CaseEqual != nil && t.CaseEqual.Param != nil:
+		e := *t.CaseEqual
+		s, err := b.string(e.Param)
+		if err != nil {
+			return t, err
+		}
+		e.Value, e.Param = s, nil
+		return ASTTerm{CaseEqual: &e}, nil
+
+	case t.Prefix != nil && t.Prefix.Param != nil:
+		e := *t.Prefix
+		s, err := b.string(e.Param)
+		if err != nil {
+			return t, err
+		}
+		e.Value, e.Param = s, nil
+		return ASTTerm{Prefix: e.Normalize()}, nil
+
+	case t.Regex != nil && t.Regex.Param != nil:
+		e := *t.Regex
+		s, err := b.string(e.Param)
+		if err != nil {
+			return t, err
+		}
+		if _, err := regexp.Compile(s); err != nil {
+			return t, fmt.Errorf("invalid regular expression %q: %w", s, err)
+		}
+		e.Value, e.Param = RegexPattern(s), nil
+		return ASTTerm{Regex: &e}, nil
+	}
+
+	return t, nil
+}
+
+func (b *binder) string(param *Param) (string, error) {
+	v, err := b.value(param, StringParam)
+	if err != nil {
+		return "", err
+	}
+	return *v.String, nil
+}
+
+func (b *binder) value(param *Param, kind ParamKind) (Value, error) {
+	arg, name := b.arg(param)
+
+	v, err := argValue(arg)
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if kind == StringParam && v.String == nil || kind == NumericParam && v.Number == nil {
+		return Value{}, fmt.Errorf("%s: expected a %s value, got %T", name, kind, arg)
+	}
+
+	return v, nil
+}
+
+func (b *binder) values(params []*Param, kind ParamKind) (Values, error) {
+	vs := []Value{}
+
+	for _, param := range params {
+		arg, name := b.arg(param)
+
+		for _, elem := range expandArg(arg) {
+			v, err := argValue(elem)
+			if err != nil {
+				return Values{}, fmt.Errorf("%s: %w", name, err)
+			}
+			if kind == StringParam && v.String == nil || kind == NumericParam && v.Number == nil {
+				return Values{}, fmt.Errorf("%s: expected a %s value, got %T", name, kind, elem)
+			}
+			vs = append(vs, v)
+		}
+	}
+
+	return listValues(vs)
+}
+
+// expandArg returns the elements of a slice argument, or the argument itself.
+// Byte slices are not expanded.
+func expandArg(arg any) []any {
+	rv := reflect.ValueOf(arg)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{arg}
+	}
+
+	elems := make([]any, 0, rv.Len())
+	for i := range rv.Len() {
+		elems = append(elems, rv.Index(i).Interface())
+	}
+	return elems
+}
+
+// listValues turns the values of an IN list into Values.
+func listValues(vs []Value) (Values, error) {
+	if len(vs) == 0 {
+		return Values{}, fmt.Errorf("empty IN list")
+	}
+
+	res := Values{}
+	for _, v := range vs {
+		if v.Head != nil {
+			return Values{}, fmt.Errorf("IN lists cannot hold values relative to the block height")
+		}
+		if (v.String != nil) != (vs[0].String != nil) {
+			return Values{}, fmt.Errorf("IN list mixes string and numeric values")
+		}
+		if v.String != nil {
+			res.Strings = append(res.Strings, *v.String)
+		} else {
+			res.Numbers = append(res.Numbers, *v.Number)
+		}
+	}
+
+	return res, nil
+}
+
+// argValue converts a bind argument into a literal value. Strings and
+// fmt.Stringer values other than numbers become strings, Go integer and float
+// types, *big.Int and store.NumericValue become numbers.
+func argValue(arg any) (Value, error) {
+	number := func(n store.NumericValue, err error) (Value, error) {
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Number: &n}, nil
+	}
+
+	switch a := arg.(type) {
+	case string:
+		return Value{String: &a}, nil
+	case store.NumericValue:
+		return Value{Number: &a}, nil
+	case *store.NumericValue:
+		if a == nil {
+			return Value{}, fmt.Errorf("nil argument")
+		}
+		return Value{Number: a}, nil
+	case *big.Int:
+		if a == nil {
+			return Value{}, fmt.Errorf("nil argument")
+		}
+		return number(store.NumericValueFromBig(a))
+	case int:
+		return number(store.NumericValueFromBig(big.NewInt(int64(a))))
+	case int8:
+		return number(store.NumericValueFromBig(big.NewInt(int64(a))))
+	case int16:
+		return number(store.NumericValueFromBig(big.NewInt(int64(a))))
+	case int32:
+		return number(store.NumericValueFromBig(big.NewInt(int64(a))))
+	case int64:
+		return number(store.NumericValueFromBig(big.NewInt(a)))
+	case uint:
+		return number(store.NewNumericValue(uint64(a)), nil)
+	case uint8:
+		return number(store.NewNumericValue(uint64(a)), nil)
+	case uint16:
+		return number(store.NewNumericValue(uint64(a)), nil)
+	case uint32:
+		return number(store.NewNumericValue(uint64(a)), nil)
+	case uint64:
+		return number(store.NewNumericValue(a), nil)
+	case float32:
+		return number(store.ParseNumericValue(strconv.FormatFloat(float64(a), 'f', -1, 32)))
+	case float64:
+		return number(store.ParseNumericValue(strconv.FormatFloat(a, 'f', -1, 64)))
+	case fmt.Stringer:
+		s := a.String()
+		return Value{String: &s}, nil
+	case nil:
+		return Value{}, fmt.Errorf("nil argument")
+	default:
+		return Value{}, fmt.Errorf("unsupported argument type %T", arg)
+	}
+}
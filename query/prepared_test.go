@@ -0,0 +1,98 @@
+package query
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	t.Run("positional and named parameters", func(t *testing.T) {
+		p, err := Compile(`name = ? && $owner = :owner && age IN (?, :ages) && $expiration = ?`, ParseOptions{})
+		require.NoError(t, err)
+
+		positional, named := p.Params()
+		require.Equal(t, []ParamKind{AnyParam, AnyParam, NumericParam}, positional)
+		require.Equal(t, map[string]ParamKind{"owner": StringParam, "ages": AnyParam}, named)
+
+		v, err := p.Bind(
+			`say "hi"`,
+			Named("owner", "0xABC"),
+			uint64(1),
+			Named("ages", []int{2, 3}),
+			big.NewInt(100),
+		)
+		require.NoError(t, err)
+
+		expected, err := Parse(`name = "say \"hi\"" && $owner = "0xabc" && age IN (1 2 3) && $expiration = 100`)
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+
+	t.Run("reuse", func(t *testing.T) {
+		p, err := Compile(`name ^= ? || !(name =~ ?)`, ParseOptions{})
+		require.NoError(t, err)
+
+		first, err := p.Bind("a", "^b")
+		require.NoError(t, err)
+		second, err := p.Bind("c", "d$")
+		require.NoError(t, err)
+
+		require.Equal(t, "a", first.Expr.Or.Terms[0].Terms[0].Prefix.Value)
+		require.Equal(t, RegexPattern("^b"), first.Expr.Or.Terms[1].Terms[0].Regex.Value)
+		require.True(t, first.Expr.Or.Terms[1].Terms[0].Regex.IsNot)
+		require.Equal(t, "c", second.Expr.Or.Terms[0].Terms[0].Prefix.Value)
+		require.Equal(t, RegexPattern("d$"), second.Expr.Or.Terms[1].Terms[0].Regex.Value)
+	})
+
+	t.Run("numeric arguments", func(t *testing.T) {
+		p, err := Compile(`price < ?`, ParseOptions{})
+		require.NoError(t, err)
+
+		for arg, expected := range map[any]string{
+			-3:                       "-3",
+			1.25:                     "1.25",
+			store.NewNumericValue(7): "7",
+		} {
+			v, err := p.Bind(arg)
+			require.NoError(t, err)
+			require.Equal(t, expected, v.Expr.Or.Terms[0].Terms[0].LessThan.Value.Number.String())
+		}
+	})
+
+	t.Run("type checking", func(t *testing.T) {
+		_, err := Compile(`$owner = :x || $expiration = :x`, ParseOptions{})
+		require.ErrorContains(t, err, "parameter :x is used both as a string and as a numeric value")
+
+		p, err := Compile(`name ~ ? && $sequence = :seq`, ParseOptions{})
+		require.NoError(t, err)
+
+		_, err = p.Bind(1, Named("seq", 1))
+		require.ErrorContains(t, err, "parameter 1: expected a string value, got int")
+
+		_, err = p.Bind("a*", Named("seq", "1"))
+		require.ErrorContains(t, err, "parameter :seq: expected a numeric value, got string")
+
+		_, err = p.Bind("a*")
+		require.ErrorContains(t, err, "missing argument for parameter :seq")
+
+		_, err = p.Bind("a*", "b*", Named("seq", 1))
+		require.ErrorContains(t, err, "query has 1 positional parameters, got 2 arguments")
+
+		_, err = p.Bind("a*", Named("seq", 1), Named("other", 1))
+		require.ErrorContains(t, err, "query has no parameter :other")
+
+		_, err = Parse(`name = ?`)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid regex argument", func(t *testing.T) {
+		p, err := Compile(`name =~ ?`, ParseOptions{})
+		require.NoError(t, err)
+
+		_, err = p.Bind("(")
+		require.ErrorContains(t, err, "invalid regular expression")
+	})
+}
@@ -0,0 +1,319 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// The String methods print queries in a canonical form of the latest grammar
+// version: the terms of conjunctions, the conjunctions of disjunctions and
+// the values of IN lists are sorted and deduplicated. Parsing the printed
+// query gives the Canonical form of the AST. Format prints them in another
+// grammar version.
+
+func (a *AST) String() string {
+	return newPrinter(LatestGrammarVersion).ast(a)
+}
+
+func (o *ASTOr) String() string {
+	return newPrinter(LatestGrammarVersion).or(o)
+}
+
+func (a *ASTAnd) String() string {
+	return newPrinter(LatestGrammarVersion).and(a)
+}
+
+func (t *ASTTerm) String() string {
+	return newPrinter(LatestGrammarVersion).term(t)
+}
+
+// String prints the values of an IN list, sorted and deduplicated.
+func (v Values) String() string {
+	return newPrinter(LatestGrammarVersion).values(v)
+}
+
+func (s *Subquery) String() string {
+	return newPrinter(LatestGrammarVersion).subquery(s)
+}
+
+func (p *Param) String() string {
+	if p.Positional {
+		return "?"
+	}
+	return ":" + p.Name
+}
+
+// Format prints the query like String, in a grammar version. It fails if the
+// query cannot be written in that version, like GrammarV1 queries with
+// placeholders, @head values, subqueries, attribute comparisons, the payload
+// attributes or attribute names that are not identifiers, or queries that
+// match nothing.
+func (a *AST) Format(version GrammarVersion) (string, error) {
+	if version == 0 {
+		version = LatestGrammarVersion
+	}
+	if _, ok := parsers[version]; !ok {
+		return "", fmt.Errorf("unknown grammar version %d", version)
+	}
+
+	p := newPrinter(version)
+	s := p.ast(a)
+	if p.err != nil {
+		return "", p.err
+	}
+	return s, nil
+}
+
+// printer prints queries in a grammar version. err is set to the first part
+// of the query that cannot be written in it.
+type printer struct {
+	version GrammarVersion
+	err     error
+}
+
+func newPrinter(version GrammarVersion) *printer {
+	return &printer{version: version}
+}
+
+// since records an error if the part of the query was introduced after the
+// version of the printer.
+func (p *printer) since(introduced GrammarVersion, part string) {
+	if p.version < introduced && p.err == nil {
+		p.err = fmt.Errorf("%s cannot be written in grammar version %d", part, p.version)
+	}
+}
+
+func (p *printer) ast(a *AST) string {
+	if a.Expr == nil {
+		return "$all"
+	}
+	return p.or(&a.Expr.Or)
+}
+
+func (p *printer) or(o *ASTOr) string {
+	if len(o.Terms) == 0 {
+		p.since(GrammarV2, "a query that matches nothing")
+		return "$none"
+	}
+	return joinSorted(o.Terms, p.and, " || ")
+}
+
+func (p *printer) and(a *ASTAnd) string {
+	return joinSorted(a.Terms, p.term, " && ")
+}
+
+// joinSorted prints the elements, sorts and deduplicates them and joins them
+// with sep.
+func joinSorted[T any](elems []T, print func(*T) string, sep string) string {
+	printed := make([]string, 0, len(elems))
+	for i := range elems {
+		printed = append(printed, print(&elems[i]))
+	}
+	slices.Sort(printed)
+	return strings.Join(slices.Compact(printed), sep)
+}
+
+func (p *printer) term(t *ASTTerm) string {
+	op := func(isNot bool, op, negated string) string {
+		if isNot {
+			return negated
+		}
+		return op
+	}
+
+	switch {
+	case t.Assign != nil:
+		return p.attribute(t.Assign.Var) + op(t.Assign.IsNot, " = ", " != ") + p.value(t.Assign.Value)
+	case t.Inclusion != nil:
+		return p.attribute(t.Inclusion.Var) + op(t.Inclusion.IsNot, " IN ", " NOT IN ") + p.values(t.Inclusion.Values)
+	case t.LessThan != nil:
+		return p.attribute(t.LessThan.Var) + " < " + p.value(t.LessThan.Value)
+	case t.LessOrEqualThan != nil:
+		return p.attribute(t.LessOrEqualThan.Var) + " <= " + p.value(t.LessOrEqualThan.Value)
+	case t.GreaterThan != nil:
+		return p.attribute(t.GreaterThan.Var) + " > " + p.value(t.GreaterThan.Value)
+	case t.GreaterOrEqualThan != nil:
+		return p.attribute(t.GreaterOrEqualThan.Var) + " >= " + p.value(t.GreaterOrEqualThan.Value)
+	case t.Glob != nil:
+		return p.attribute(t.Glob.Var) + op(t.Glob.IsNot, " ~ ", " !~ ") + p.string(t.Glob.Value, t.Glob.Param)
+	case t.CaseEqual != nil:
+		return p.attribute(t.CaseEqual.Var) + op(t.CaseEqual.IsNot, " =* ", " !=* ") + p.string(t.CaseEqual.Value, t.CaseEqual.Param)
+	case t.Prefix != nil:
+		return p.attribute(t.Prefix.Var) + op(t.Prefix.IsNot, " ^= ", " !^= ") + p.string(t.Prefix.Value, t.Prefix.Param)
+	case t.Regex != nil:
+		return p.attribute(t.Regex.Var) + op(t.Regex.IsNot, " =~ ", " !=~ ") + p.string(string(t.Regex.Value), t.Regex.Param)
+	case t.Range != nil:
+		return p.attribute(t.Range.Var) + op(t.Range.FromInclusive, " > ", " >= ") + p.value(t.Range.From) +
+			" && " + p.attribute(t.Range.Var) + op(t.Range.ToInclusive, " < ", " <= ") + p.value(t.Range.To)
+	case t.AttributeEqual != nil:
+		p.since(GrammarV2, "an attribute comparison")
+		return p.attribute(t.AttributeEqual.Var) + op(t.AttributeEqual.IsNot, " = ", " != ") + p.attribute(t.AttributeEqual.Other)
+	}
+
+	return ""
+}
+
+func (p *printer) value(v Value) string {
+	switch {
+	case v.String != nil:
+		return strconv.Quote(*v.String)
+	case v.Number != nil:
+		return v.Number.String()
+	case v.Head != nil:
+		p.since(GrammarV2, "a value relative to the block height")
+		return v.Head.String()
+	case v.Param != nil:
+		return p.param(v.Param)
+	}
+	return ""
+}
+
+func (p *printer) param(param *Param) string {
+	p.since(GrammarV2, "a placeholder")
+	return param.String()
+}
+
+// values prints the values of an IN list, sorted and deduplicated. They are
+// separated by commas from GrammarV2 on.
+func (p *printer) values(v Values) string {
+	printed := []string{}
+	switch {
+	case v.Strings != nil:
+		for _, s := range slices.Compact(slices.Sorted(slices.Values(v.Strings))) {
+			printed = append(printed, strconv.Quote(s))
+		}
+	case v.Subquery != nil:
+		p.since(GrammarV2, "a subquery")
+		return "(" + p.subquery(v.Subquery) + ")"
+	case v.Numbers != nil:
+		numbers := slices.SortedFunc(slices.Values(v.Numbers), store.NumericValue.Cmp)
+		numbers = slices.CompactFunc(numbers, func(a, b store.NumericValue) bool { return a.Cmp(b) == 0 })
+		for _, n := range numbers {
+			printed = append(printed, n.String())
+		}
+	default:
+		for _, param := range v.Params {
+			printed = append(printed, p.param(param))
+		}
+	}
+
+	sep := ", "
+	if p.version < GrammarV2 {
+		sep = " "
+	}
+	return "(" + strings.Join(printed, sep) + ")"
+}
+
+func (p *printer) subquery(s *Subquery) string {
+	return "SELECT " + s.Select + " WHERE " + p.ast(s.Query)
+}
+
+func (p *printer) string(s string, param *Param) string {
+	if param != nil {
+		return p.param(param)
+	}
+	return strconv.Quote(s)
+}
+
+var identRegex = regexp.MustCompile(`^` + AnnotationIdentRegex + `$`)
+
+// keywords are the identifiers that are quoted so that they are not taken
+// for operators.
+var keywords = []string{"and", "or", "not", "in", "glob", "ilike", "regexp"}
+
+// attribute prints an attribute name. From GrammarV2 on, names that are not
+// identifiers or are keywords are quoted. GrammarV1 cannot quote names, there
+// keywords are printed as they are, the parser tells them from operators by
+// their position, and other names cannot be printed. The synthetic
+// attributes are printed as they are.
+func (p *printer) attribute(name string) string {
+	switch name {
+	case KeyAttributeKey, OwnerAttributeKey, CreatorAttributeKey, ExpirationAttributeKey, SequenceAttributeKey:
+		return name
+	case ContentTypeAttributeKey, PayloadSizeAttributeKey, PayloadHashAttributeKey, VersionAttributeKey:
+		p.since(GrammarV2, "the attribute "+name)
+		return name
+	}
+
+	ident := identRegex.MatchString(name)
+
+	if p.version < GrammarV2 {
+		if !ident && p.err == nil {
+			p.err = fmt.Errorf("the attribute name %q cannot be written in grammar version %d", name, p.version)
+		}
+		return name
+	}
+
+	if ident && !slices.Contains(keywords, strings.ToLower(name)) {
+		return name
+	}
+
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// Canonical returns the AST that parsing String gives: the terms of the
+// conjunctions, the conjunctions and the values of IN lists are sorted by
+// their printed form and deduplicated.
+func (a *AST) Canonical() *AST {
+	if a.Expr == nil {
+		return &AST{}
+	}
+
+	ands := []ASTAnd{}
+	for _, and := range a.Expr.Or.Terms {
+		terms := []ASTTerm{}
+		for _, t := range and.Terms {
+			if t.Inclusion != nil {
+				t = canonicalInclusion(t)
+			}
+			t, _ = t.mapSubquery(func(q *AST) (*AST, error) {
+				return q.Canonical(), nil
+			})
+			terms = append(terms, t)
+		}
+		ands = append(ands, ASTAnd{Terms: sortedByString(terms, (*ASTTerm).String)})
+	}
+
+	return &AST{Expr: &ASTExpr{Or: ASTOr{Terms: sortedByString(ands, (*ASTAnd).String)}}}
+}
+
+func canonicalInclusion(t ASTTerm) ASTTerm {
+	inclusion := *t.Inclusion
+	values := inclusion.Values
+
+	if values.Strings != nil {
+		values.Strings = slices.Compact(slices.Sorted(slices.Values(values.Strings)))
+	}
+	if values.Numbers != nil {
+		numbers := slices.SortedFunc(slices.Values(values.Numbers), store.NumericValue.Cmp)
+		values.Numbers = slices.CompactFunc(numbers, func(a, b store.NumericValue) bool { return a.Cmp(b) == 0 })
+	}
+
+	inclusion.Values = values
+	return ASTTerm{Inclusion: &inclusion}
+}
+
+func sortedByString[T any](elems []T, print func(*T) string) []T {
+	type printed struct {
+		s    string
+		elem T
+	}
+
+	ps := make([]printed, 0, len(elems))
+	for i := range elems {
+		ps = append(ps, printed{s: print(&elems[i]), elem: elems[i]})
+	}
+	slices.SortStableFunc(ps, func(a, b printed) int { return strings.Compare(a.s, b.s) })
+	ps = slices.CompactFunc(ps, func(a, b printed) bool { return a.s == b.s })
+
+	res := make([]T, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, p.elem)
+	}
+	return res
+}
@@ -0,0 +1,125 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		for _, q := range []string{
+			`$all`,
+			`type = "nft"`,
+			`b = 2 && a = 1 && b = 2`,
+			`(a = 1 || b != "x") && !(c < -2.5 || d >= 0x10)`,
+			`name IN ("b" "a" "b") || age NOT IN (3 1 2 1) || $owner IN ("0xABC")`,
+			`name ~ "a*" && name !~ "b*" && name =* "C" && name !=* "d"`,
+			`name ^= "e" || name !^= "f" || name =~ "^g\\." || name !=~ "h$"`,
+			`x <= 5 && y = "quote \" and \\ backslash\n"`,
+			"`content-type` = 'text/plain' && `in` = 1 && `we\\`ird` > 2",
+			`$expiration = 10 && $sequence IN (1 2) && $key = "0x01"`,
+			`$contentType ~ "image/*" && $payloadSize > 1024 && $version >= 2 && $payloadHash != "0xab"`,
+			`$none`,
+			`parent IN (SELECT $key WHERE $none) || a = 1`,
+		} {
+			v, err := Parse(q)
+			require.NoError(t, err, q)
+
+			printed := v.String()
+			parsed, err := Parse(printed)
+			require.NoError(t, err, printed)
+			require.Equal(t, v.Canonical(), parsed, printed)
+			require.Equal(t, printed, parsed.String())
+		}
+	})
+
+	t.Run("canonical form", func(t *testing.T) {
+		v, err := Parse(`z = 1 && !(b = "x" || a IN (3 1 3)) || c ~ "*"`)
+		require.NoError(t, err)
+
+		require.Equal(t, `a NOT IN (1, 3) && b != "x" && z = 1 || c ~ "*"`, v.String())
+	})
+
+	t.Run("same query in another order", func(t *testing.T) {
+		a, err := Parse(`a = 1 && (b = 2 || c = 3)`)
+		require.NoError(t, err)
+		b, err := Parse(`(c = 3 || b = 2) && a = 1`)
+		require.NoError(t, err)
+
+		require.Equal(t, a.String(), b.String())
+	})
+
+	t.Run("queries that match nothing", func(t *testing.T) {
+		v, err := Parse(`a = 1 && a = 2 || parent IN (SELECT $key WHERE b < 1 && b > 2)`)
+		require.NoError(t, err)
+
+		simplified := v.Simplify()
+		require.Equal(t, `parent IN (SELECT $key WHERE $none)`, simplified.String())
+
+		v, err = Parse(`a = 1 && a = 2`)
+		require.NoError(t, err)
+		require.Equal(t, `$none`, v.Simplify().String())
+
+		parsed, err := Parse(v.Simplify().String())
+		require.NoError(t, err)
+		require.True(t, parsed.MatchesNothing())
+	})
+
+	t.Run("grammar versions", func(t *testing.T) {
+		v, err := Parse("`in` IN ('b', 'a') && `not` = 1 && x IN (2, 1)")
+		require.NoError(t, err)
+
+		v1, err := v.Format(GrammarV1)
+		require.NoError(t, err)
+		require.Equal(t, `in IN ("a" "b") && not = 1 && x IN (1 2)`, v1)
+
+		parsed, err := ParseWithOptions(v1, ParseOptions{Version: GrammarV1})
+		require.NoError(t, err)
+		require.Equal(t, v.Canonical(), parsed)
+
+		v2, err := v.Format(GrammarV2)
+		require.NoError(t, err)
+		require.Equal(t, v.String(), v2)
+
+		v, err = Parse("`content-type` = \"text/plain\"")
+		require.NoError(t, err)
+		_, err = v.Format(GrammarV1)
+		require.ErrorContains(t, err, `the attribute name "content-type" cannot be written in grammar version 1`)
+
+		_, err = v.Format(3)
+		require.ErrorContains(t, err, "unknown grammar version 3")
+	})
+
+	t.Run("v2 syntax in grammar version 1", func(t *testing.T) {
+		for q, msg := range map[string]string{
+			`a = 1 && a = 2`:                          "a query that matches nothing",
+			`$expiration < @head + 5`:                 "a value relative to the block height",
+			`a = ?`:                                   "a placeholder",
+			`a IN (:list)`:                            "a placeholder",
+			`a ~ ?`:                                   "a placeholder",
+			`parent IN (SELECT $key WHERE a = 1)`:     "a subquery",
+			`$owner = $creator`:                       "an attribute comparison",
+			`$contentType = "text/plain"`:             "the attribute $contentType",
+			`a = 1 && $payloadSize > 10`:              "the attribute $payloadSize",
+			`a = 1 || !(b = 1 && $version IN (1, 2))`: "the attribute $version",
+		} {
+			p, err := Compile(q, ParseOptions{})
+			require.NoError(t, err, q)
+			v := p.ast.Simplify()
+
+			_, err = v.Format(GrammarV1)
+			require.ErrorContains(t, err, msg+" cannot be written in grammar version 1", q)
+
+			_, err = v.Format(GrammarV2)
+			require.NoError(t, err, q)
+		}
+	})
+
+	t.Run("placeholders", func(t *testing.T) {
+		p, err := Compile(`a = ? && b IN (:list) && c ~ ?`, ParseOptions{})
+		require.NoError(t, err)
+
+		require.Equal(t, `a = ? && b IN (:list) && c ~ ?`, p.ast.String())
+	})
+}
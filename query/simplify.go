@@ -0,0 +1,540 @@
+package query
+
+import (
+	"slices"
+	"strings"
+)
+
+// Range matches the values between two bounds with a single scan. It is not
+// part of the grammar, Simplify merges a lower and an upper bound on the same
+// attribute into a range.
+type Range struct {
+	Var           string
+	From          Value
+	FromInclusive bool
+	To            Value
+	ToInclusive   bool
+}
+
+// Simplify returns an equivalent AST that is cheaper to evaluate. The AST must
+// be bound.
+//
+// An entity has at most one value per attribute name and type, and every term
+// on an attribute restricts that value, so the terms of a conjunction on the
+// same attribute are merged into a single set of values:
+//
+//   - conjunctions whose terms contradict each other, like a = 1 && a = 2,
+//     are dropped,
+//   - lower and upper bounds are merged into the tightest bound, or into a
+//     Range if there are both,
+//   - equalities and IN lists are intersected and filtered by the other
+//     terms, and inequalities and NOT IN lists are merged into one NOT IN.
+//
+// Conjunctions that imply another conjunction of the disjunction are dropped,
+// and conjunctions that are a single equality or IN list on the same
+// attribute are merged into one IN list. If no conjunction is left, the AST
+// matches nothing, see MatchesNothing.
+func (a *AST) Simplify() *AST {
+	if a.Expr == nil {
+		return a
+	}
+
+	ands := []ASTAnd{}
+	for _, and := range a.Expr.Or.Terms {
+		if simplified, ok := and.simplify(); ok {
+			ands = append(ands, simplified)
+		}
+	}
+
+	ands = dropImplying(mergeEqualities(ands))
+
+	return &AST{Expr: &ASTExpr{Or: ASTOr{Terms: ands}}, Warnings: a.Warnings}
+}
+
+// MatchesNothing reports whether the AST is an empty disjunction, which
+// Simplify returns for queries that cannot match any entity.
+func (a *AST) MatchesNothing() bool {
+	return a.Expr != nil && len(a.Expr.Or.Terms) == 0
+}
+
+// simplify merges the terms on the same attribute. It returns false if the
+// terms contradict each other.
+func (a *ASTAnd) simplify() (ASTAnd, bool) {
+	constraints := map[attribute]*constraint{}
+	attributes := []attribute{}
+	terms := []ASTTerm{}
+
+	for _, t := range a.Terms {
+		attr, ok := t.attribute()
+		if !ok {
+			t, _ = t.mapSubquery(func(q *AST) (*AST, error) {
+				return q.Simplify(), nil
+			})
+			terms = append(terms, t)
+			continue
+		}
+
+		c, ok := constraints[attr]
+		if !ok {
+			c = &constraint{attribute: attr}
+			constraints[attr] = c
+			attributes = append(attributes, attr)
+		}
+		c.add(t)
+	}
+
+	for _, attr := range attributes {
+		merged, ok := constraints[attr].terms()
+		if !ok {
+			return ASTAnd{}, false
+		}
+		terms = append(terms, merged...)
+	}
+
+	return ASTAnd{Terms: compactByString(terms, (*ASTTerm).String)}, true
+}
+
+// mergeEqualities merges the conjunctions that are a single equality or IN
+// list on the same attribute into one IN list, in place of the first of them.
+func mergeEqualities(ands []ASTAnd) []ASTAnd {
+	merged := map[attribute]*constraint{}
+	res := []ASTAnd{}
+
+	for _, and := range ands {
+		if attr, ok := and.equality(); ok {
+			c, ok := merged[attr]
+			if ok {
+				c.allowed = append(c.allowed, and.Terms[0].values()...)
+				continue
+			}
+			merged[attr] = &constraint{attribute: attr, allowed: and.Terms[0].values()}
+		}
+		res = append(res, and)
+	}
+
+	for i, and := range res {
+		if attr, ok := and.equality(); ok && len(merged[attr].allowed) > 1 {
+			res[i] = ASTAnd{Terms: []ASTTerm{merged[attr].equality(sortedValues(merged[attr].allowed))}}
+		}
+	}
+
+	return res
+}
+
+// equality returns the attribute of a conjunction that is a single equality
+// or IN list with literal values.
+func (a *ASTAnd) equality() (attribute, bool) {
+	if len(a.Terms) != 1 {
+		return attribute{}, false
+	}
+	t := a.Terms[0]
+	if t.Assign != nil && t.Assign.IsNot || t.Inclusion != nil && t.Inclusion.IsNot {
+		return attribute{}, false
+	}
+	if t.Assign == nil && t.Inclusion == nil {
+		return attribute{}, false
+	}
+	return t.attribute()
+}
+
+// maxImplicationChecks is the largest disjunction whose conjunctions are
+// checked for implying each other, the check is quadratic.
+const maxImplicationChecks = 512
+
+// dropImplying drops the conjunctions that imply another conjunction: if A
+// implies B then A || B is B. Of equivalent conjunctions the first one is
+// kept.
+func dropImplying(ands []ASTAnd) []ASTAnd {
+	if len(ands) > maxImplicationChecks {
+		return ands
+	}
+
+	conjunctions := make([]conjunction, 0, len(ands))
+	for _, and := range ands {
+		conjunctions = append(conjunctions, newConjunction(and))
+	}
+
+	res := []ASTAnd{}
+	for i, c := range conjunctions {
+		redundant := false
+		for j, other := range conjunctions {
+			if i == j || len(other.terms) > len(c.terms) || !c.implies(other) {
+				continue
+			}
+			if j < i || !other.implies(c) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			res = append(res, ands[i])
+		}
+	}
+
+	return res
+}
+
+// conjunction is a simplified conjunction prepared for implication checks.
+type conjunction struct {
+	terms       []conjunctionTerm
+	printed     map[string]bool
+	constraints map[attribute]*constraint
+}
+
+type conjunctionTerm struct {
+	printed string
+	// constraint is the set of values that the term allows, nil if the term
+	// is not merged by Simplify
+	constraint *constraint
+}
+
+func newConjunction(and ASTAnd) conjunction {
+	c := conjunction{
+		printed:     map[string]bool{},
+		constraints: map[attribute]*constraint{},
+	}
+
+	for _, t := range and.Terms {
+		term := conjunctionTerm{printed: t.String()}
+		if attr, ok := t.attribute(); ok {
+			term.constraint = &constraint{attribute: attr}
+			term.constraint.add(t)
+
+			if c.constraints[attr] == nil {
+				c.constraints[attr] = &constraint{attribute: attr}
+			}
+			c.constraints[attr].add(t)
+		}
+		c.terms = append(c.terms, term)
+		c.printed[term.printed] = true
+	}
+
+	return c
+}
+
+// implies reports whether every entity matching c matches other, which is the
+// case if c restricts every attribute at least as much as other does.
+func (c conjunction) implies(other conjunction) bool {
+	for _, t := range other.terms {
+		if c.printed[t.printed] {
+			continue
+		}
+		if t.constraint == nil {
+			return false
+		}
+		mine, ok := c.constraints[t.constraint.attribute]
+		if !ok || !mine.subsetOf(t.constraint) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attribute identifies the values that terms compare, string and numeric
+// attributes with the same name are distinct.
+type attribute struct {
+	name    string
+	numeric bool
+}
+
+// attribute returns the attribute of the terms that Simplify merges: the
+// equalities, IN lists and comparisons with literal values.
+func (t *ASTTerm) attribute() (attribute, bool) {
+	var name string
+	var v Value
+
+	switch {
+	case t.Assign != nil:
+		name, v = t.Assign.Var, t.Assign.Value
+	case t.Inclusion != nil:
+		return attribute{name: t.Inclusion.Var, numeric: len(t.Inclusion.Values.Numbers) != 0},
+			len(t.Inclusion.Values.Strings) != 0 || len(t.Inclusion.Values.Numbers) != 0
+	case t.LessThan != nil:
+		name, v = t.LessThan.Var, t.LessThan.Value
+	case t.LessOrEqualThan != nil:
+		name, v = t.LessOrEqualThan.Var, t.LessOrEqualThan.Value
+	case t.GreaterThan != nil:
+		name, v = t.GreaterThan.Var, t.GreaterThan.Value
+	case t.GreaterOrEqualThan != nil:
+		name, v = t.GreaterOrEqualThan.Var, t.GreaterOrEqualThan.Value
+	case t.Range != nil:
+		name, v = t.Range.Var, t.Range.From
+	default:
+		return attribute{}, false
+	}
+
+	return attribute{name: name, numeric: v.Number != nil}, v.String != nil || v.Number != nil
+}
+
+// values returns the values of an equality or IN list.
+func (t *ASTTerm) values() []Value {
+	if t.Assign != nil {
+		return []Value{t.Assign.Value}
+	}
+
+	values := []Value{}
+	for _, s := range t.Inclusion.Values.Strings {
+		values = append(values, Value{String: &s})
+	}
+	for _, n := range t.Inclusion.Values.Numbers {
+		values = append(values, Value{Number: &n})
+	}
+	return values
+}
+
+type bound struct {
+	value     Value
+	inclusive bool
+}
+
+// constraint is the set of values of an attribute that the terms on it allow:
+// the allowed values if there is an equality or IN list, otherwise the values
+// between the bounds, minus the excluded values. The allowed and excluded
+// values are sorted and distinct, so that long IN lists are searched quickly.
+type constraint struct {
+	attribute
+
+	allowed      []Value
+	hasAllowed   bool
+	excluded     []Value
+	lower, upper *bound
+}
+
+func (c *constraint) add(t ASTTerm) {
+	switch {
+	case t.Assign != nil && t.Assign.IsNot, t.Inclusion != nil && t.Inclusion.IsNot:
+		c.excluded = sortedValues(append(c.excluded, t.values()...))
+
+	case t.Assign != nil, t.Inclusion != nil:
+		values := sortedValues(t.values())
+		if c.hasAllowed {
+			values = slices.DeleteFunc(values, func(v Value) bool { return !containsValue(c.allowed, v) })
+		}
+		c.allowed, c.hasAllowed = values, true
+
+	case t.LessThan != nil:
+		c.tightenUpper(bound{value: t.LessThan.Value})
+	case t.LessOrEqualThan != nil:
+		c.tightenUpper(bound{value: t.LessOrEqualThan.Value, inclusive: true})
+	case t.GreaterThan != nil:
+		c.tightenLower(bound{value: t.GreaterThan.Value})
+	case t.GreaterOrEqualThan != nil:
+		c.tightenLower(bound{value: t.GreaterOrEqualThan.Value, inclusive: true})
+	case t.Range != nil:
+		c.tightenLower(bound{value: t.Range.From, inclusive: t.Range.FromInclusive})
+		c.tightenUpper(bound{value: t.Range.To, inclusive: t.Range.ToInclusive})
+	}
+}
+
+func (c *constraint) tightenLower(b bound) {
+	if c.lower == nil {
+		c.lower = &b
+		return
+	}
+	cmp := compareValues(b.value, c.lower.value)
+	if cmp > 0 || cmp == 0 && !b.inclusive {
+		c.lower = &b
+	}
+}
+
+func (c *constraint) tightenUpper(b bound) {
+	if c.upper == nil {
+		c.upper = &b
+		return
+	}
+	cmp := compareValues(b.value, c.upper.value)
+	if cmp < 0 || cmp == 0 && !b.inclusive {
+		c.upper = &b
+	}
+}
+
+// contains reports whether v is in the set of values.
+func (c *constraint) contains(v Value) bool {
+	if c.hasAllowed && !containsValue(c.allowed, v) {
+		return false
+	}
+	return c.withinBounds(v) && !containsValue(c.excluded, v)
+}
+
+func (c *constraint) withinBounds(v Value) bool {
+	if c.lower != nil {
+		cmp := compareValues(v, c.lower.value)
+		if cmp < 0 || cmp == 0 && !c.lower.inclusive {
+			return false
+		}
+	}
+	if c.upper != nil {
+		cmp := compareValues(v, c.upper.value)
+		if cmp > 0 || cmp == 0 && !c.upper.inclusive {
+			return false
+		}
+	}
+	return true
+}
+
+// subsetOf reports whether every value in c is in other. Sets that are not
+// finite are compared by their bounds only, so the result can be a false
+// negative but never a false positive.
+func (c *constraint) subsetOf(other *constraint) bool {
+	if c.hasAllowed {
+		for _, v := range c.allowed {
+			if !c.contains(v) {
+				continue
+			}
+			if !other.contains(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if other.hasAllowed {
+		return false
+	}
+
+	if other.lower != nil {
+		if c.lower == nil {
+			return false
+		}
+		cmp := compareValues(c.lower.value, other.lower.value)
+		if cmp < 0 || cmp == 0 && c.lower.inclusive && !other.lower.inclusive {
+			return false
+		}
+	}
+	if other.upper != nil {
+		if c.upper == nil {
+			return false
+		}
+		cmp := compareValues(c.upper.value, other.upper.value)
+		if cmp > 0 || cmp == 0 && c.upper.inclusive && !other.upper.inclusive {
+			return false
+		}
+	}
+
+	for _, v := range other.excluded {
+		if c.contains(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// terms returns the terms that match the set of values, or false if it is
+// empty.
+func (c *constraint) terms() ([]ASTTerm, bool) {
+	if c.hasAllowed {
+		allowed := slices.DeleteFunc(slices.Clone(c.allowed), func(v Value) bool { return !c.contains(v) })
+		if len(allowed) == 0 {
+			return nil, false
+		}
+		return []ASTTerm{c.equality(allowed)}, true
+	}
+
+	if c.lower != nil && c.upper != nil {
+		cmp := compareValues(c.lower.value, c.upper.value)
+		switch {
+		case cmp > 0, cmp == 0 && !(c.lower.inclusive && c.upper.inclusive):
+			return nil, false
+		case cmp == 0:
+			if !c.contains(c.lower.value) {
+				return nil, false
+			}
+			return []ASTTerm{c.equality([]Value{c.lower.value})}, true
+		}
+	}
+
+	terms := []ASTTerm{}
+
+	switch {
+	case c.lower != nil && c.upper != nil:
+		terms = append(terms, ASTTerm{Range: &Range{
+			Var:           c.name,
+			From:          c.lower.value,
+			FromInclusive: c.lower.inclusive,
+			To:            c.upper.value,
+			ToInclusive:   c.upper.inclusive,
+		}})
+	case c.lower != nil && c.lower.inclusive:
+		terms = append(terms, ASTTerm{GreaterOrEqualThan: &GreaterOrEqualThan{Var: c.name, Value: c.lower.value}})
+	case c.lower != nil:
+		terms = append(terms, ASTTerm{GreaterThan: &GreaterThan{Var: c.name, Value: c.lower.value}})
+	case c.upper != nil && c.upper.inclusive:
+		terms = append(terms, ASTTerm{LessOrEqualThan: &LessOrEqualThan{Var: c.name, Value: c.upper.value}})
+	case c.upper != nil:
+		terms = append(terms, ASTTerm{LessThan: &LessThan{Var: c.name, Value: c.upper.value}})
+	}
+
+	// excluded values outside the bounds do not change the result
+	excluded := slices.DeleteFunc(slices.Clone(c.excluded), func(v Value) bool { return !c.withinBounds(v) })
+	if len(excluded) != 0 {
+		terms = append(terms, c.inequality(excluded))
+	}
+
+	return terms, true
+}
+
+// equality returns the term matching the values, which must be sorted and
+// distinct.
+func (c *constraint) equality(values []Value) ASTTerm {
+	if len(values) == 1 {
+		return ASTTerm{Assign: &Equality{Var: c.name, Value: values[0]}}
+	}
+	return ASTTerm{Inclusion: &Inclusion{Var: c.name, Values: c.list(values)}}
+}
+
+// inequality returns the term matching all values but the given ones, which
+// must be sorted and distinct.
+func (c *constraint) inequality(values []Value) ASTTerm {
+	if len(values) == 1 {
+		return ASTTerm{Assign: &Equality{Var: c.name, IsNot: true, Value: values[0]}}
+	}
+	return ASTTerm{Inclusion: &Inclusion{Var: c.name, IsNot: true, Values: c.list(values)}}
+}
+
+func (c *constraint) list(values []Value) Values {
+	var list Values
+	for _, v := range values {
+		if c.numeric {
+			list.Numbers = append(list.Numbers, *v.Number)
+		} else {
+			list.Strings = append(list.Strings, *v.String)
+		}
+	}
+	return list
+}
+
+// compareValues compares two literal values of the same type, like SQLite
+// compares them.
+func compareValues(a, b Value) int {
+	if a.Number != nil {
+		return a.Number.Cmp(*b.Number)
+	}
+	return strings.Compare(*a.String, *b.String)
+}
+
+func sortedValues(values []Value) []Value {
+	sorted := slices.SortedFunc(slices.Values(values), compareValues)
+	return slices.CompactFunc(sorted, func(a, b Value) bool { return compareValues(a, b) == 0 })
+}
+
+// compactByString removes the elements that print like an earlier element.
+func compactByString[T any](elems []T, print func(*T) string) []T {
+	seen := map[string]bool{}
+	res := make([]T, 0, len(elems))
+	for i := range elems {
+		s := print(&elems[i])
+		if !seen[s] {
+			seen[s] = true
+			res = append(res, elems[i])
+		}
+	}
+	return res
+}
+
+// containsValue reports whether the sorted values contain v.
+func containsValue(sorted []Value, v Value) bool {
+	_, found := slices.BinarySearchFunc(sorted, v, compareValues)
+	return found
+}
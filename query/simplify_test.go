@@ -0,0 +1,115 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplify(t *testing.T) {
+	for _, tc := range []struct {
+		query      string
+		simplified string
+	}{
+		// duplicates
+		{`a = 1 && a = 1 && b ~ "x*" && b ~ "x*"`, `a = 1 && b ~ "x*"`},
+		{`a = 1 || a = 1`, `a = 1`},
+
+		// contradictions
+		{`a = 1 && a = 2`, `$none`},
+		{`a = 1 && a != 1`, `$none`},
+		{`a IN (1 2) && a NOT IN (1 2)`, `$none`},
+		{`p > 5 && p < 3`, `$none`},
+		{`p > 5 && p <= 5`, `$none`},
+		{`p >= "b" && p < "a"`, `$none`},
+		{`a = 1 && a = 2 || b = 1`, `b = 1`},
+
+		// string and numeric attributes with the same name are distinct
+		{`a = 1 && a = "1"`, `a = "1" && a = 1`},
+
+		// ranges
+		{`p > 5 && p > 10`, `p > 10`},
+		{`p >= 10 && p > 10`, `p > 10`},
+		{`p < 5 && p <= 3`, `p <= 3`},
+		{`p > 5 && p <= 10`, `p > 5 && p <= 10`},
+		{`p > 5 && p < 10 && p >= 7 && p < 20`, `p >= 7 && p < 10`},
+		{`p >= 5 && p <= 5`, `p = 5`},
+		{`p >= 5 && p <= 5 && p != 5`, `$none`},
+		{`name >= "a" && name < "c" && name > "b"`, `name > "b" && name < "c"`},
+
+		// equalities and IN lists are filtered by the other terms
+		{`a IN (1 2 3) && a IN (2 3 4)`, `a IN (2, 3)`},
+		{`a IN (1 2 3) && a > 1 && a != 3`, `a = 2`},
+		{`a = 2 && a < 10 && b = 1`, `a = 2 && b = 1`},
+
+		// inequalities
+		{`a != 1 && a != 2 && a NOT IN (3)`, `a NOT IN (1, 2, 3)`},
+		{`a != 1 && a > 5`, `a > 5`},
+		{`a NOT IN (1 7) && a > 5 && a < 10`, `a != 7 && a > 5 && a < 10`},
+		{`$owner NOT IN ("0xABC" "0xdef") && $owner != "0xAB"`, `$owner NOT IN ("0xab", "0xabc", "0xdef")`},
+
+		// equality chains
+		{`a = 1 || a = 2 || a IN (3 1)`, `a IN (1, 2, 3)`},
+		{`a = 1 || b = 1 || a = "x" || a = 2`, `a = "x" || a IN (1, 2) || b = 1`},
+		{`a = 1 || a != 2`, `a != 2`},
+
+		// redundant disjunctions
+		{`a = 1 || a = 1 && b = 2`, `a = 1`},
+		{`p > 5 || p > 10 && b = 1`, `p > 5`},
+		{`p > 5 && p < 7 || p >= 0`, `p >= 0`},
+		{`a IN (1 2 3) || a = 3 && c = 1`, `a IN (1, 2, 3)`},
+		{`a = 3 && b = 1 || a > 1 && b ~ "*"`, `a = 3 && b = 1 || a > 1 && b ~ "*"`},
+		{`name ~ "a*" || name ~ "a*" && x = 1`, `name ~ "a*"`},
+		{`a != 1 || a NOT IN (1 2)`, `a != 1`},
+
+		// terms that are not merged
+		{`name ~ "a*" && name ^= "b" && name =~ "c"`, `name =~ "c" && name ^= "b" && name ~ "a*"`},
+		{`$all`, `$all`},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			v, err := Parse(tc.query)
+			require.NoError(t, err)
+
+			simplified := v.Simplify()
+			require.Equal(t, tc.simplified, simplified.String())
+			require.Equal(t, tc.simplified == `$none`, simplified.MatchesNothing())
+		})
+	}
+
+	t.Run("long IN lists", func(t *testing.T) {
+		// the lists are intersected without comparing every pair of values
+		allowed, excluded := []any{}, []any{}
+		for i := range 100000 {
+			allowed = append(allowed, i)
+			if i%2 == 1 {
+				excluded = append(excluded, i+1)
+			}
+		}
+
+		v, err := Build(And(In("a", allowed...), NotIn("a", excluded...), Lt("a", 4)))
+		require.NoError(t, err)
+		require.Equal(t, `a IN (0, 1, 3)`, v.Simplify().String())
+	})
+
+	t.Run("range term", func(t *testing.T) {
+		v, err := Parse(`p > 5 && p <= 10`)
+		require.NoError(t, err)
+
+		terms := v.Simplify().Expr.Or.Terms
+		require.Len(t, terms, 1)
+		require.Len(t, terms[0].Terms, 1)
+
+		r := terms[0].Terms[0].Range
+		require.NotNil(t, r)
+		require.Equal(t, "p", r.Var)
+		require.Equal(t, "5", r.From.Number.String())
+		require.False(t, r.FromInclusive)
+		require.Equal(t, "10", r.To.Number.String())
+		require.True(t, r.ToInclusive)
+
+		require.Equal(t, Filter{And: []Filter{
+			{Attribute: "p", Op: ">", Value: &r.From},
+			{Attribute: "p", Op: "<=", Value: &r.To},
+		}}, terms[0].Terms[0].Filter())
+	})
+}
@@ -0,0 +1,98 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+)
+
+// subqueryBatchSize is the number of entities whose keys are looked up at a
+// time when a subquery is evaluated, which keeps the statements below the
+// SQLite limit on the number of parameters.
+const subqueryBatchSize = 1000
+
+// normalize returns the subquery with its Where normalised into Query.
+func (s *Subquery) normalize() *Subquery {
+	if s.Query != nil {
+		return s
+	}
+	if s.None {
+		return &Subquery{Select: s.Select, Query: &AST{Expr: &ASTExpr{Or: ASTOr{Terms: []ASTAnd{}}}}}
+	}
+	return &Subquery{Select: s.Select, Query: &AST{Expr: s.Where.Normalize()}}
+}
+
+// subquery returns the subquery of an IN list, if it has one.
+func (t *ASTTerm) subquery() *Subquery {
+	if t.Inclusion == nil {
+		return nil
+	}
+	return t.Inclusion.Values.Subquery
+}
+
+// mapSubquery returns the term with the query of its subquery replaced by
+// the result of fn. Terms without a subquery are returned unchanged.
+func (t ASTTerm) mapSubquery(fn func(*AST) (*AST, error)) (ASTTerm, error) {
+	sub := t.subquery()
+	if sub == nil {
+		return t, nil
+	}
+
+	q, err := fn(sub.Query)
+	if err != nil {
+		return t, err
+	}
+
+	e := *t.Inclusion
+	e.Values = Values{Subquery: &Subquery{Select: sub.Select, Query: q}}
+	return ASTTerm{Inclusion: &e}, nil
+}
+
+// evaluateSubquery evaluates the subquery to the keys of the entities that it
+// matches and then matches the entities whose attribute has one of these keys
+// as its value. Keys are the lower case hex encoding of the entity keys, like
+// the values of $key.
+func (e *Inclusion) evaluateSubquery(
+	ctx context.Context,
+	q *store.Queries,
+) (*roaring64.Bitmap, error) {
+
+	// the trace describes the outer query, the subquery is one of its terms
+	matched, err := e.Values.Subquery.Query.Evaluate(WithTrace(ctx, nil), q)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating subquery: %w", err)
+	}
+
+	res := roaring64.New()
+
+	it := matched.ManyIterator()
+	ids := make([]uint64, subqueryBatchSize)
+	for n := it.NextMany(ids); n > 0; n = it.NextMany(ids) {
+		keys, err := q.GetEntityKeys(ctx, ids[:n])
+		if err != nil {
+			return nil, fmt.Errorf("error getting entity keys: %w", err)
+		}
+
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, "0x"+hex.EncodeToString(key))
+		}
+
+		bm, err := evaluateStringInclusion(ctx, q, e.Var, values)
+		if err != nil {
+			return nil, err
+		}
+		res.Or(bm)
+	}
+
+	if !e.IsNot {
+		return res, nil
+	}
+
+	// NOT IN matches the entities with the attribute whose value is not one of
+	// the keys
+	return evaluateExclusion(ctx, q, e.Var, false, res)
+}
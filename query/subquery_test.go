@@ -0,0 +1,151 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubquery(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		v, err := Parse(`parent IN (select $key where type = "collection" && (owner = "a" || owner = "b")) && name = "x"`)
+		require.NoError(t, err)
+
+		sub := v.Expr.Or.Terms[0].Terms[0].Inclusion.Values.Subquery
+		require.NotNil(t, sub)
+		require.Equal(t, KeyAttributeKey, sub.Select)
+
+		inner, err := Parse(`type = "collection" && (owner = "a" || owner = "b")`)
+		require.NoError(t, err)
+		require.Equal(t, inner, sub.Query)
+
+		require.Equal(
+			t,
+			`name = "x" && parent IN (SELECT $key WHERE owner = "a" && type = "collection" || owner = "b" && type = "collection")`,
+			v.String(),
+		)
+
+		reparsed, err := Parse(v.String())
+		require.NoError(t, err)
+		require.Equal(t, v.String(), reparsed.String())
+	})
+
+	t.Run("negation", func(t *testing.T) {
+		v, err := Parse(`!(parent IN (SELECT $key WHERE type = "collection"))`)
+		require.NoError(t, err)
+		require.Equal(t, `parent NOT IN (SELECT $key WHERE type = "collection")`, v.String())
+
+		v, err = Parse(`!(parent NOT IN (SELECT $key WHERE !(type = "collection")))`)
+		require.NoError(t, err)
+		require.Equal(t, `parent IN (SELECT $key WHERE type != "collection")`, v.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, q := range []string{
+			`parent IN (select $owner where type = "collection")`,
+			`parent IN (select $key)`,
+			`parent IN (select $key where)`,
+			`parent = (select $key where type = "collection")`,
+		} {
+			_, err := Parse(q)
+			require.Error(t, err, q)
+		}
+	})
+
+	t.Run("parameters", func(t *testing.T) {
+		p, err := Compile(`name = ? && parent IN (select $key where type = ? && size > :size) && kind = ?`, ParseOptions{})
+		require.NoError(t, err)
+
+		positional, named := p.Params()
+		require.Equal(t, []ParamKind{AnyParam, AnyParam, AnyParam}, positional)
+		require.Equal(t, map[string]ParamKind{"size": AnyParam}, named)
+
+		v, err := p.Bind("a", "collection", Named("size", 3), "b")
+		require.NoError(t, err)
+
+		expected, err := Parse(`name = "a" && parent IN (select $key where type = "collection" && size > 3) && kind = "b"`)
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+
+	t.Run("simplify", func(t *testing.T) {
+		v, err := Parse(`parent IN (select $key where size > 1 && size > 5 && size < 10)`)
+		require.NoError(t, err)
+		require.Equal(t, `parent IN (SELECT $key WHERE size > 5 && size < 10)`, v.Simplify().String())
+	})
+
+	t.Run("complexity", func(t *testing.T) {
+		_, err := ParseWithOptions(
+			`parent IN (select $key where (a = 1 || a = 2) && (b = 1 || b = 2) && (c = 1 || c = 2))`,
+			ParseOptions{MaxConjunctions: 4},
+		)
+		var tooMany *TooManyConjunctionsError
+		require.True(t, errors.As(err, &tooMany))
+		require.Equal(t, uint64(8), tooMany.Conjunctions)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		catalog := Catalog{
+			"parent": {StringEntities: 1},
+			"type":   {StringEntities: 1},
+			"size":   {NumericEntities: 1},
+		}
+
+		v, err := ParseWithOptions(
+			`parent IN (select $key where type = "collection" && size = "3")`,
+			ParseOptions{Validation: &Validation{Catalog: catalog, Coerce: true}},
+		)
+		require.NoError(t, err)
+		require.Empty(t, v.Warnings)
+		require.Equal(t, []string{"parent", "size", "type"}, v.Attributes())
+		require.Equal(t, `parent IN (SELECT $key WHERE size = 3 && type = "collection")`, v.String())
+
+		v, err = ParseWithOptions(
+			`size IN (select $key where colour = "red")`,
+			ParseOptions{Validation: &Validation{Catalog: catalog, Coerce: true}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []ValidationWarning{
+			{Term: `colour = "red"`, Attribute: "colour", Message: `no entity has the attribute "colour"`},
+			{Term: `size IN (SELECT $key WHERE colour = "red")`, Attribute: "size", Message: `the attribute "size" only has numeric values`},
+		}, v.Warnings)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		v, err := Parse(`parent IN (select $key where $expiration < @head + 10)`)
+		require.NoError(t, err)
+		require.True(t, v.UsesHead())
+
+		resolved, err := v.AtHead(100)
+		require.NoError(t, err)
+		require.False(t, resolved.UsesHead())
+		require.Equal(t, `parent IN (SELECT $key WHERE $expiration < 110)`, resolved.String())
+	})
+
+	t.Run("JSON filters and builder", func(t *testing.T) {
+		v, err := Parse(`parent NOT IN (select $key where type = "collection" || size > 2)`)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"attribute": "parent", "op": "not in", "select": "$key", "where": {"or": [
+			{"attribute": "type", "op": "=", "value": "collection"},
+			{"attribute": "size", "op": ">", "value": 2}
+		]}}`, string(data))
+
+		parsed, err := ParseJSON(data)
+		require.NoError(t, err)
+		require.Equal(t, v, parsed)
+
+		built, err := Build(NotInKeysOf("parent", Or(Eq("type", "collection"), Gt("size", 2))))
+		require.NoError(t, err)
+		require.Equal(t, v, built)
+
+		_, err = ParseJSON([]byte(`{"attribute": "parent", "op": "in", "select": "$owner", "where": {"attribute": "a", "op": "=", "value": 1}}`))
+		require.Error(t, err)
+		_, err = ParseJSON([]byte(`{"attribute": "parent", "op": "=", "select": "$key", "where": {"attribute": "a", "op": "=", "value": 1}}`))
+		require.Error(t, err)
+	})
+}
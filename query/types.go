@@ -6,3 +6,7 @@ var OwnerAttributeKey = "$owner"
 var ExpirationAttributeKey = "$expiration"
 var CreatedAtBlockKey = "$createdAtBlock"
 var SequenceAttributeKey = "$sequence"
+var ContentTypeAttributeKey = "$contentType"
+var PayloadSizeAttributeKey = "$payloadSize"
+var PayloadHashAttributeKey = "$payloadHash"
+var VersionAttributeKey = "$version"
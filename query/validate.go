@@ -0,0 +1,271 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// AttributeTypes are the numbers of entities that have a string and a numeric
+// value for an attribute.
+type AttributeTypes struct {
+	StringEntities  uint64 `json:"stringEntities"`
+	NumericEntities uint64 `json:"numericEntities"`
+}
+
+// Catalog are the types of the attributes that entities have, by name.
+// Attributes that no entity has are not in it.
+type Catalog map[string]AttributeTypes
+
+// Validation selects how a query is validated against a catalog, see
+// AST.Validate.
+type Validation struct {
+	Catalog Catalog
+	// Coerce converts the values of terms to the type of their attribute
+	// instead of warning about them, where the conversion is well defined.
+	Coerce bool
+	// RejectUnknown makes queries that use an attribute that is not in the
+	// catalog fail with an UnknownAttributeError instead of warning about it.
+	RejectUnknown bool
+}
+
+// ValidationWarning is a term that validation found not to match any entity.
+type ValidationWarning struct {
+	Term      string `json:"term"`
+	Attribute string `json:"attribute"`
+	Message   string `json:"message"`
+}
+
+func (w ValidationWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Term, w.Message)
+}
+
+// UnknownAttributeError is returned by Validate for attributes that no entity
+// has, if the validation rejects them.
+type UnknownAttributeError struct {
+	Attribute string
+}
+
+func (e *UnknownAttributeError) Error() string {
+	return fmt.Sprintf("unknown attribute %q", e.Attribute)
+}
+
+// Attributes returns the names of the attributes that the query uses, without
+// the special attributes, in order.
+func (a *AST) Attributes() []string {
+	if a.Expr == nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, and := range a.Expr.Or.Terms {
+		for i := range and.Terms {
+			name, _ := and.Terms[i].variable()
+			if !strings.HasPrefix(name, "$") {
+				names = append(names, name)
+			}
+			if sub := and.Terms[i].subquery(); sub != nil {
+				names = append(names, sub.Query.Attributes()...)
+			}
+			if e := and.Terms[i].AttributeEqual; e != nil && !strings.HasPrefix(e.Other, "$") {
+				names = append(names, e.Other)
+			}
+		}
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Validate checks the terms of a bound query against the catalog. A term
+// whose attribute never has a value of the term's type matches nothing, so it
+// is coerced to the type of the attribute if v.Coerce is set and otherwise
+// reported in the Warnings of the returned AST, like the terms on attributes
+// that are not in the catalog. Equalities and IN lists are coerced both ways,
+// while comparisons only from strings to numbers, since numbers and their
+// decimal strings are not ordered alike. The special attributes are not
+// validated, subqueries are validated like the query.
+func (a *AST) Validate(v Validation) (*AST, error) {
+	if a.Expr == nil {
+		return a, nil
+	}
+
+	warnings := slices.Clone(a.Warnings)
+	warn := func(t *ASTTerm, name string, format string, args ...any) {
+		warnings = append(warnings, ValidationWarning{
+			Term:      t.String(),
+			Attribute: name,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	ands := make([]ASTAnd, 0, len(a.Expr.Or.Terms))
+	for _, and := range a.Expr.Or.Terms {
+		terms := make([]ASTTerm, 0, len(and.Terms))
+		for _, t := range and.Terms {
+			t, err := t.mapSubquery(func(q *AST) (*AST, error) {
+				validated, err := q.Validate(v)
+				if err != nil {
+					return nil, err
+				}
+				warnings = append(warnings, validated.Warnings...)
+				return &AST{Expr: validated.Expr}, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if t.AttributeEqual != nil {
+				if err := validateAttributeEquality(&t, v, warn); err != nil {
+					return nil, err
+				}
+				terms = append(terms, t)
+				continue
+			}
+
+			name, numeric := t.variable()
+			if strings.HasPrefix(name, "$") {
+				terms = append(terms, t)
+				continue
+			}
+
+			types := v.Catalog[name]
+			switch {
+			case types.StringEntities == 0 && types.NumericEntities == 0:
+				if v.RejectUnknown {
+					return nil, &UnknownAttributeError{Attribute: name}
+				}
+				warn(&t, name, "no entity has the attribute %q", name)
+			case numeric && types.NumericEntities == 0:
+				if coerced, ok := t.coerce(false); v.Coerce && ok {
+					t = coerced
+					break
+				}
+				warn(&t, name, "the attribute %q only has string values", name)
+			case !numeric && types.StringEntities == 0:
+				if coerced, ok := t.coerce(true); v.Coerce && ok {
+					t = coerced
+					break
+				}
+				warn(&t, name, "the attribute %q only has numeric values", name)
+			}
+			terms = append(terms, t)
+		}
+		ands = append(ands, ASTAnd{Terms: terms})
+	}
+
+	return &AST{Expr: &ASTExpr{Or: ASTOr{Terms: ands}}, Warnings: warnings}, nil
+}
+
+// validateAttributeEquality checks that the compared attributes can have
+// values of the same type. The special attributes always can.
+func validateAttributeEquality(t *ASTTerm, v Validation, warn func(*ASTTerm, string, string, ...any)) error {
+	e := t.AttributeEqual
+
+	types := []AttributeTypes{}
+	for _, name := range []string{e.Var, e.Other} {
+		if strings.HasPrefix(name, "$") {
+			return nil
+		}
+		if v.Catalog[name] == (AttributeTypes{}) {
+			if v.RejectUnknown {
+				return &UnknownAttributeError{Attribute: name}
+			}
+			warn(t, name, "no entity has the attribute %q", name)
+			return nil
+		}
+		types = append(types, v.Catalog[name])
+	}
+
+	strs := types[0].StringEntities != 0 && types[1].StringEntities != 0
+	numbers := types[0].NumericEntities != 0 && types[1].NumericEntities != 0
+	if !strs && !numbers {
+		warn(t, e.Var, "the attributes %q and %q never have values of the same type", e.Var, e.Other)
+	}
+
+	return nil
+}
+
+// coerce returns the term with its values converted to numbers if numeric is
+// set, or to strings otherwise. It returns false if a value cannot be
+// converted or the conversion would change the meaning of the term.
+func (t ASTTerm) coerce(numeric bool) (ASTTerm, bool) {
+	value := func(v Value) (Value, bool) {
+		if v.Head != nil {
+			// the block height is always a number
+			return v, false
+		}
+		if numeric {
+			n, err := store.ParseNumericValue(*v.String)
+			return Value{Number: &n}, err == nil
+		}
+		s := v.Number.String()
+		return Value{String: &s}, true
+	}
+
+	var ok bool
+	switch {
+	case t.Assign != nil:
+		e := *t.Assign
+		e.Value, ok = value(e.Value)
+		return ASTTerm{Assign: &e}, ok
+	case t.subquery() != nil:
+		// subqueries select keys, which are strings
+		return t, false
+	case t.Inclusion != nil:
+		e := *t.Inclusion
+		e.Values, ok = t.Inclusion.Values.coerce(numeric)
+		return ASTTerm{Inclusion: &e}, ok
+	case !numeric:
+		// numbers and their decimal strings are not ordered alike
+		return t, false
+	case t.LessThan != nil:
+		e := *t.LessThan
+		e.Value, ok = value(e.Value)
+		return ASTTerm{LessThan: &e}, ok
+	case t.LessOrEqualThan != nil:
+		e := *t.LessOrEqualThan
+		e.Value, ok = value(e.Value)
+		return ASTTerm{LessOrEqualThan: &e}, ok
+	case t.GreaterThan != nil:
+		e := *t.GreaterThan
+		e.Value, ok = value(e.Value)
+		return ASTTerm{GreaterThan: &e}, ok
+	case t.GreaterOrEqualThan != nil:
+		e := *t.GreaterOrEqualThan
+		e.Value, ok = value(e.Value)
+		return ASTTerm{GreaterOrEqualThan: &e}, ok
+	case t.Range != nil:
+		e := *t.Range
+		from, fromOK := value(e.From)
+		to, toOK := value(e.To)
+		e.From, e.To = from, to
+		return ASTTerm{Range: &e}, fromOK && toOK
+	default:
+		// globs, prefixes, case insensitive equalities and regular
+		// expressions only apply to strings
+		return t, false
+	}
+}
+
+func (v Values) coerce(numeric bool) (Values, bool) {
+	if numeric {
+		numbers := make([]store.NumericValue, 0, len(v.Strings))
+		for _, s := range v.Strings {
+			n, err := store.ParseNumericValue(s)
+			if err != nil {
+				return v, false
+			}
+			numbers = append(numbers, n)
+		}
+		return Values{Numbers: numbers}, true
+	}
+
+	strs := make([]string, 0, len(v.Numbers))
+	for _, n := range v.Numbers {
+		strs = append(strs, n.String())
+	}
+	return Values{Strings: strs}, true
+}
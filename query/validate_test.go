@@ -0,0 +1,82 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	catalog := Catalog{
+		"price": {NumericEntities: 10},
+		"name":  {StringEntities: 10},
+		"mixed": {StringEntities: 2, NumericEntities: 3},
+	}
+
+	validate := func(t *testing.T, q string, v Validation) (*AST, error) {
+		t.Helper()
+		v.Catalog = catalog
+		return ParseWithOptions(q, ParseOptions{Validation: &v})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		ast, err := validate(t, `price > 5 && name = "a" && (mixed = 1 || mixed = "b") && $owner = "0x01"`, Validation{})
+		require.NoError(t, err)
+		require.Empty(t, ast.Warnings)
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		ast, err := validate(t, `price = "100" || name < 5 || name = 5 || price ~ "1*" || missing = 1`, Validation{})
+		require.NoError(t, err)
+		require.Equal(t, []ValidationWarning{
+			{Term: `price = "100"`, Attribute: "price", Message: `the attribute "price" only has numeric values`},
+			{Term: `name < 5`, Attribute: "name", Message: `the attribute "name" only has string values`},
+			{Term: `name = 5`, Attribute: "name", Message: `the attribute "name" only has string values`},
+			{Term: `price ~ "1*"`, Attribute: "price", Message: `the attribute "price" only has numeric values`},
+			{Term: `missing = 1`, Attribute: "missing", Message: `no entity has the attribute "missing"`},
+		}, ast.Warnings)
+		require.Equal(t, `missing = 1 || name < 5 || name = 5 || price = "100" || price ~ "1*"`, ast.String())
+	})
+
+	t.Run("coerce", func(t *testing.T) {
+		ast, err := validate(t, `price = "100" && price IN ("1" "0x10") && price <= "7.5" && name = 5 && name IN (1 2)`, Validation{Coerce: true})
+		require.NoError(t, err)
+		require.Empty(t, ast.Warnings)
+		require.Equal(t, `name = "5" && name IN ("1", "2") && price <= 7.5 && price = 100 && price IN (1, 16)`, ast.String())
+	})
+
+	t.Run("coerce what is well defined", func(t *testing.T) {
+		ast, err := validate(t, `price = "abc" || name < 5 || price ~ "1*"`, Validation{Coerce: true})
+		require.NoError(t, err)
+		require.Len(t, ast.Warnings, 3)
+		require.Equal(t, `name < 5 || price = "abc" || price ~ "1*"`, ast.String())
+	})
+
+	t.Run("coerce bound arguments", func(t *testing.T) {
+		p, err := Compile(`price = ?`, ParseOptions{})
+		require.NoError(t, err)
+
+		bound, err := p.Bind("42")
+		require.NoError(t, err)
+
+		ast, err := bound.Validate(Validation{Catalog: catalog, Coerce: true})
+		require.NoError(t, err)
+		require.Equal(t, `price = 42`, ast.String())
+		require.Equal(t, `price = "42"`, bound.String())
+	})
+
+	t.Run("reject unknown", func(t *testing.T) {
+		_, err := validate(t, `price = 1 && missing = 1`, Validation{RejectUnknown: true})
+		var unknown *UnknownAttributeError
+		require.True(t, errors.As(err, &unknown))
+		require.Equal(t, "missing", unknown.Attribute)
+		require.EqualError(t, err, `unknown attribute "missing"`)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		ast, err := Parse(`b = 1 || (a = 2 && $owner = "0x01" && b > 3)`)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b"}, ast.Attributes())
+	})
+}
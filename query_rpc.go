@@ -29,6 +29,87 @@ type IncludeData struct {
 	LastModifiedAtBlock         bool `json:"lastModifiedAtBlock"`
 	TransactionIndexInBlock     bool `json:"transactionIndexInBlock"`
 	OperationIndexInTransaction bool `json:"operationIndexInTransaction"`
+	PayloadSize                 bool `json:"payloadSize"`
+	PayloadHash                 bool `json:"payloadHash"`
+	Version                     bool `json:"version"`
+
+	// StringAttributeNames and NumericAttributeNames restrict the returned
+	// attributes to the ones whose name matches one of the glob patterns. When
+	// set, they take precedence over Attributes and SyntheticAttributes for
+	// that kind of attribute.
+	StringAttributeNames  []string `json:"stringAttributeNames,omitempty"`
+	NumericAttributeNames []string `json:"numericAttributeNames,omitempty"`
+}
+
+const (
+	nonSyntheticAttributesPattern = "[^$]*"
+	syntheticAttributesPattern    = "$*"
+)
+
+func (i IncludeData) switchedAttributePatterns() []string {
+	patterns := []string{}
+	if i.Attributes {
+		patterns = append(patterns, nonSyntheticAttributesPattern)
+	}
+	if i.SyntheticAttributes {
+		patterns = append(patterns, syntheticAttributesPattern)
+	}
+	return patterns
+}
+
+func (i IncludeData) stringAttributePatterns() []string {
+	if i.StringAttributeNames != nil {
+		return i.StringAttributeNames
+	}
+	return i.switchedAttributePatterns()
+}
+
+func (i IncludeData) numericAttributePatterns() []string {
+	if i.NumericAttributeNames != nil {
+		return i.NumericAttributeNames
+	}
+	return i.switchedAttributePatterns()
+}
+
+// projection returns the parts of the payload rows that toPayload needs,
+// including the synthetic attributes that back the dedicated fields.
+func (i IncludeData) projection() store.PayloadProjection {
+	stringAttributes := slices.Clone(i.stringAttributePatterns())
+	if i.Owner {
+		stringAttributes = append(stringAttributes, query.OwnerAttributeKey)
+	}
+	if i.PayloadHash {
+		stringAttributes = append(stringAttributes, query.PayloadHashAttributeKey)
+	}
+
+	numericAttributes := slices.Clone(i.numericAttributePatterns())
+	if i.Expiration {
+		numericAttributes = append(numericAttributes, query.ExpirationAttributeKey)
+	}
+	if i.CreatedAtBlock {
+		numericAttributes = append(numericAttributes, query.CreatedAtBlockKey)
+	}
+	if i.LastModifiedAtBlock {
+		numericAttributes = append(numericAttributes, "$lastModifiedAtBlock")
+	}
+	if i.TransactionIndexInBlock {
+		numericAttributes = append(numericAttributes, "$txIndex")
+	}
+	if i.OperationIndexInTransaction {
+		numericAttributes = append(numericAttributes, "$opIndex")
+	}
+	if i.PayloadSize {
+		numericAttributes = append(numericAttributes, query.PayloadSizeAttributeKey)
+	}
+	if i.Version {
+		numericAttributes = append(numericAttributes, query.VersionAttributeKey)
+	}
+
+	return store.PayloadProjection{
+		Payload:           i.Payload,
+		StringAttributes:  stringAttributes,
+		NumericAttributes: numericAttributes,
+	}
 }
 
 type Options struct {
@@ -36,6 +117,35 @@ type Options struct {
 	IncludeData    *IncludeData `json:"includeData,omitempty"`
 	ResultsPerPage *uint64      `json:"resultsPerPage,omitempty"`
 	Cursor         string       `json:"cursor,omitempty"`
+	// IncludeTotalCount makes QueryEntities report the total number of
+	// matching entities, across all pages.
+	IncludeTotalCount bool `json:"includeTotalCount,omitempty"`
+	// GrammarVersion is the version of the query language that the query is
+	// written in, the latest if not set.
+	GrammarVersion query.GrammarVersion `json:"grammarVersion,omitempty"`
+	// Explain makes QueryEntities report how the query was evaluated.
+	Explain bool `json:"explain,omitempty"`
+	// Validation, if set, validates the query against the attribute catalog,
+	// see AttributeCatalog.
+	Validation *Validation `json:"validation,omitempty"`
+	// MatchedBranches makes QueryEntities annotate every entity with the
+	// conjunctions of the normalized query that it matches.
+	MatchedBranches bool `json:"matchedBranches,omitempty"`
+	// Collapse, if set, returns at most one entity per value of an
+	// attribute. The cursor and the counts apply to the collapsed results.
+	Collapse *Collapse `json:"collapse,omitempty"`
+}
+
+// Validation selects how queries are validated against the attribute catalog.
+// Terms whose values have a type that their attribute never has, and terms on
+// attributes that no entity has, are reported in the warnings of the response.
+type Validation struct {
+	// Coerce converts the values of terms to the type of their attribute
+	// instead of warning about them, see query.AST.Validate.
+	Coerce bool `json:"coerce,omitempty"`
+	// RejectUnknown makes queries that use attributes that no entity has
+	// fail with a query.UnknownAttributeError.
+	RejectUnknown bool `json:"rejectUnknown,omitempty"`
 }
 
 func (o *Options) GetAtBlock() uint64 {
@@ -45,6 +155,20 @@ func (o *Options) GetAtBlock() uint64 {
 	return *o.AtBlock
 }
 
+func (o *Options) GetParseOptions() query.ParseOptions {
+	if o == nil {
+		return query.ParseOptions{}
+	}
+	options := query.ParseOptions{Version: o.GrammarVersion}
+	if o.Validation != nil {
+		options.Validation = &query.Validation{
+			Coerce:        o.Validation.Coerce,
+			RejectUnknown: o.Validation.RejectUnknown,
+		}
+	}
+	return options
+}
+
 func (o *Options) GetResultsPerPage() uint64 {
 	if o == nil || o.ResultsPerPage == nil || *o.ResultsPerPage > QueryResultCountLimit {
 		return QueryResultCountLimit
@@ -66,6 +190,13 @@ func (o *Options) GetIncludeData() IncludeData {
 	return *o.IncludeData
 }
 
+func (o *Options) GetCollapse() *Collapse {
+	if o == nil {
+		return nil
+	}
+	return o.Collapse
+}
+
 func (o *Options) GetCursor() (*uint64, error) {
 	if o == nil || o.Cursor == "" {
 		return nil, nil
@@ -83,6 +214,41 @@ type QueryResponse struct {
 	Data        []json.RawMessage `json:"data"`
 	BlockNumber uint64            `json:"blockNumber"`
 	Cursor      *string           `json:"cursor,omitempty"`
+	TotalCount  *uint64           `json:"totalCount,omitempty"`
+	Explain     *Explain          `json:"explain,omitempty"`
+	// Warnings are the problems that the validation found in the query.
+	Warnings []query.ValidationWarning `json:"warnings,omitempty"`
+}
+
+// Explain describes how QueryEntities answered a query. Durations are in
+// nanoseconds.
+type Explain struct {
+	// Query is the normalized query, in disjunctive normal form.
+	Query      string       `json:"query"`
+	Evaluation *query.Trace `json:"evaluation"`
+	// Matching is the number of entities matching the query, Collapsed the
+	// number of them that remain after Options.Collapse, AfterCursor the
+	// number of those before the cursor, and Retrieved the number of them
+	// returned in this page.
+	Matching    uint64         `json:"matching"`
+	Collapsed   uint64         `json:"collapsed"`
+	AfterCursor uint64         `json:"afterCursor"`
+	Retrieved   uint64         `json:"retrieved"`
+	Timings     ExplainTimings `json:"timings"`
+}
+
+// ExplainTimings are the durations of the stages of a query.
+type ExplainTimings struct {
+	Parse    time.Duration `json:"parse"`
+	Evaluate time.Duration `json:"evaluate"`
+	Cursor   time.Duration `json:"cursor"`
+	Retrieve time.Duration `json:"retrieve"`
+}
+
+type CountResponse struct {
+	Count       uint64                    `json:"count"`
+	BlockNumber uint64                    `json:"blockNumber"`
+	Warnings    []query.ValidationWarning `json:"warnings,omitempty"`
 }
 
 type EntityData struct {
@@ -95,9 +261,26 @@ type EntityData struct {
 	LastModifiedAtBlock         *uint64         `json:"lastModifiedAtBlock,omitempty"`
 	TransactionIndexInBlock     *uint64         `json:"transactionIndexInBlock,omitempty"`
 	OperationIndexInTransaction *uint64         `json:"operationIndexInTransaction,omitempty"`
+	PayloadSize                 *uint64         `json:"payloadSize,omitempty"`
+	PayloadHash                 *common.Hash    `json:"payloadHash,omitempty"`
+	Version                     *uint64         `json:"version,omitempty"`
 
-	StringAttributes  []Attribute[string] `json:"stringAttributes,omitempty"`
-	NumericAttributes []Attribute[uint64] `json:"numericAttributes,omitempty"`
+	StringAttributes  []Attribute[string]             `json:"stringAttributes,omitempty"`
+	NumericAttributes []Attribute[store.NumericValue] `json:"numericAttributes,omitempty"`
+
+	// MatchedBranches are the conjunctions of the query that the entity
+	// matches, see Options.MatchedBranches.
+	MatchedBranches []MatchedBranch `json:"matchedBranches,omitempty"`
+}
+
+// MatchedBranch is a conjunction of the disjunctive normal form of a query.
+type MatchedBranch struct {
+	// Index is the position of the conjunction in the canonical form of the
+	// query, the form of Explain.Query.
+	Index int `json:"index"`
+	// Terms are the terms of the conjunction in canonical form, all of
+	// which the entity matches.
+	Terms []string `json:"terms"`
 }
 
 type Attribute[T any] struct {
@@ -107,51 +290,152 @@ type Attribute[T any] struct {
 
 const maxResultBytes = 512 * 1024 * 1024
 
+const compiledQueryCacheSize = 1024
+
+type compiledQueryKey struct {
+	query   string
+	version query.GrammarVersion
+}
+
+// prepareQuery validates q against the attribute catalog if options ask for
+// it, simplifies it, see query.AST.Simplify, and resolves the values that are
+// relative to the block height at head. It runs in the read transaction that
+// evaluates q, so that the catalog and the block height are the ones of the
+// data that is read.
+func prepareQuery(
+	ctx context.Context,
+	queries *store.Queries,
+	q *query.AST,
+	options query.ParseOptions,
+	head uint64,
+) (*query.AST, error) {
+
+	if options.Validation != nil {
+		validation := *options.Validation
+		catalog, err := attributeCatalog(ctx, queries, q.Attributes())
+		if err != nil {
+			return nil, fmt.Errorf("error loading the attribute catalog: %w", err)
+		}
+		validation.Catalog = catalog
+
+		q, err = q.Validate(validation)
+		if err != nil {
+			return nil, fmt.Errorf("error validating query: %w", err)
+		}
+	}
+
+	q = q.Simplify()
+
+	if !q.UsesHead() {
+		return q, nil
+	}
+
+	resolved, err := q.AtHead(head)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving query at block %d: %w", head, err)
+	}
+
+	return resolved.Simplify(), nil
+}
+
+// parseQuery parses a query string or JSON filter and binds args to its
+// placeholders. Compiled queries are cached, so running the same query with
+// different arguments only parses it once. A queryStr starting with { is a
+// JSON filter, see query.Filter. The query is prepared for evaluation by
+// prepareQuery.
+func (s *SQLiteStore) parseQuery(queryStr string, options query.ParseOptions, args []any) (*query.AST, error) {
+	options.MaxConjunctions = s.maxQueryConjunctions
+
+	if strings.HasPrefix(strings.TrimSpace(queryStr), "{") {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("JSON filters do not take bind arguments")
+		}
+		return query.ParseJSONWithOptions([]byte(queryStr), options)
+	}
+
+	key := compiledQueryKey{query: queryStr, version: options.Version}
+
+	prepared, ok := s.compiledQueries.Get(key)
+	if !ok {
+		var err error
+		prepared, err = query.Compile(queryStr, options)
+		if err != nil {
+			return nil, err
+		}
+		s.compiledQueries.Add(key, prepared)
+	}
+
+	return prepared.Bind(args...)
+}
+
 func (s *SQLiteStore) QueryEntities(
 	ctx context.Context,
 	queryStr string,
 	options *Options,
+	args ...any,
 ) (*QueryResponse, error) {
 
-	// TODO: wait for the block height
-
 	res := &QueryResponse{
 		Data:        []json.RawMessage{},
 		BlockNumber: 0,
 		Cursor:      nil,
 	}
 
-	{
-		q := s.NewQueries()
-		timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-		defer cancel()
-
-		for {
-			lastBlock, err := q.GetLastBlock(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("error getting last block: %w", err)
-			}
-			if lastBlock >= options.GetAtBlock() {
-				break
-			}
-			select {
-			case <-timeoutCtx.Done():
-				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
-			case <-time.After(100 * time.Millisecond):
-				continue
-			}
-		}
-		cancel()
+	err := s.waitForBlock(ctx, options.GetAtBlock())
+	if err != nil {
+		return nil, err
 	}
 
-	q, err := query.Parse(queryStr)
+	start := time.Now()
+	q, err := s.parseQuery(queryStr, options.GetParseOptions(), args)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing query: %w", err)
 	}
 
-	err = s.ReadTransaction(ctx, func(queries *store.Queries) error {
+	s.log.Debug("normalized query", "query", q)
+
+	var wrap func(store.DBTX) store.DBTX
+	explain := options != nil && options.Explain
+	matchedBranches := options != nil && options.MatchedBranches
+	if explain {
+		trace := &query.Trace{}
+		res.Explain = &Explain{
+			Query:      q.String(),
+			Evaluation: trace,
+			Timings:    ExplainTimings{Parse: time.Since(start)},
+		}
+		ctx = query.WithTrace(ctx, trace)
+		wrap = func(db store.DBTX) store.DBTX {
+			return query.TraceDB(db, trace)
+		}
+	}
 
-		bitmap, err := q.Evaluate(
+	err = s.readTransaction(ctx, wrap, func(queries *store.Queries) error {
+
+		lastBlock, err := queries.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		res.BlockNumber = lastBlock
+
+		q, err = prepareQuery(ctx, queries, q, options.GetParseOptions(), lastBlock)
+		if err != nil {
+			return err
+		}
+		res.Warnings = q.Warnings
+		if explain {
+			res.Explain.Query = q.String()
+		}
+
+		var branches []MatchedBranch
+		if matchedBranches {
+			// the branches are numbered in the order that the query prints
+			q = q.Canonical()
+			branches = queryBranches(q)
+		}
+
+		start := time.Now()
+		bitmap, branchBitmaps, err := q.EvaluateBranches(
 			ctx,
 			queries,
 		)
@@ -159,6 +443,27 @@ func (s *SQLiteStore) QueryEntities(
 			return fmt.Errorf("error evaluating query: %w", err)
 		}
 
+		if explain {
+			res.Explain.Matching = bitmap.GetCardinality()
+		}
+
+		if collapse := options.GetCollapse(); collapse != nil {
+			bitmap, err = collapse.collapse(ctx, queries, bitmap)
+			if err != nil {
+				return fmt.Errorf("error collapsing results: %w", err)
+			}
+		}
+
+		if explain {
+			res.Explain.Timings.Evaluate = time.Since(start)
+			res.Explain.Collapsed = bitmap.GetCardinality()
+		}
+
+		if options != nil && options.IncludeTotalCount {
+			res.TotalCount = pointerOf(bitmap.GetCardinality())
+		}
+
+		start = time.Now()
 		cursor, err := options.GetCursor()
 		if err != nil {
 			return fmt.Errorf("error decoding cursor: %w", err)
@@ -175,6 +480,16 @@ func (s *SQLiteStore) QueryEntities(
 			bitmap.And(cursorMask)
 		}
 
+		if explain {
+			res.Explain.Timings.Cursor = time.Since(start)
+			res.Explain.AfterCursor = bitmap.GetCardinality()
+			start = time.Now()
+			defer func() {
+				res.Explain.Timings.Retrieve = time.Since(start)
+				res.Explain.Retrieved = uint64(len(res.Data))
+			}()
+		}
+
 		it := bitmap.ReverseIterator()
 
 		maxResults := options.GetResultsPerPage()
@@ -190,6 +505,9 @@ func (s *SQLiteStore) QueryEntities(
 			return ids
 		}
 
+		includeData := options.GetIncludeData()
+		projection := includeData.projection()
+
 		totalBytes := uint64(0)
 		finished := true
 		var lastID *uint64
@@ -201,7 +519,7 @@ func (s *SQLiteStore) QueryEntities(
 
 			nextIDs := nextIDs(nextBatchSize)
 
-			payloads, err := queries.RetrievePayloads(ctx, nextIDs)
+			payloads, err := queries.RetrieveProjectedPayloads(ctx, nextIDs, projection)
 			if err != nil {
 				return fmt.Errorf("error retrieving payloads: %w", err)
 			}
@@ -210,7 +528,12 @@ func (s *SQLiteStore) QueryEntities(
 
 				lastID = &payload.ID
 
-				ed := toPayload(payload, options.GetIncludeData())
+				ed := toPayload(payload, includeData)
+				for i, branch := range branches {
+					if branchBitmaps[i].Contains(payload.ID) {
+						ed.MatchedBranches = append(ed.MatchedBranches, branch)
+					}
+				}
 				d, err := json.Marshal(ed)
 				if err != nil {
 					return fmt.Errorf("error marshalling entity data: %w", err)
@@ -247,6 +570,107 @@ func (s *SQLiteStore) QueryEntities(
 
 }
 
+// CountEntities returns the number of entities matching queryStr without
+// retrieving any of them.
+func (s *SQLiteStore) CountEntities(
+	ctx context.Context,
+	queryStr string,
+	options *Options,
+	args ...any,
+) (*CountResponse, error) {
+
+	err := s.waitForBlock(ctx, options.GetAtBlock())
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := s.parseQuery(queryStr, options.GetParseOptions(), args)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing query: %w", err)
+	}
+
+	res := &CountResponse{}
+
+	err = s.ReadTransaction(ctx, func(queries *store.Queries) error {
+
+		lastBlock, err := queries.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		res.BlockNumber = lastBlock
+
+		q, err = prepareQuery(ctx, queries, q, options.GetParseOptions(), lastBlock)
+		if err != nil {
+			return err
+		}
+		res.Warnings = q.Warnings
+
+		bitmap, err := q.Evaluate(ctx, queries)
+		if err != nil {
+			return fmt.Errorf("error evaluating query: %w", err)
+		}
+
+		if collapse := options.GetCollapse(); collapse != nil {
+			bitmap, err = collapse.collapse(ctx, queries, bitmap)
+			if err != nil {
+				return fmt.Errorf("error collapsing results: %w", err)
+			}
+		}
+
+		res.Count = bitmap.GetCardinality()
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error counting entities: %w", err)
+	}
+
+	return res, nil
+}
+
+// queryBranches returns the conjunctions of q in the order of
+// q.Expr.Or.Terms. A query without an expression is a single branch.
+func queryBranches(q *query.AST) []MatchedBranch {
+	if q.Expr == nil {
+		return []MatchedBranch{{Index: 0, Terms: []string{q.String()}}}
+	}
+
+	branches := make([]MatchedBranch, 0, len(q.Expr.Or.Terms))
+	for i, and := range q.Expr.Or.Terms {
+		terms := make([]string, 0, len(and.Terms))
+		for _, t := range and.Terms {
+			terms = append(terms, t.String())
+		}
+		branches = append(branches, MatchedBranch{Index: i, Terms: terms})
+	}
+	return branches
+}
+
+// waitForBlock blocks until the store has processed atBlock, giving up after
+// a few seconds.
+func (s *SQLiteStore) waitForBlock(ctx context.Context, atBlock uint64) error {
+	q := s.NewQueries()
+	timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	for {
+		lastBlock, err := q.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting last block: %w", err)
+		}
+		if lastBlock >= atBlock {
+			return nil
+		}
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("context cancelled: %w", ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+			continue
+		}
+	}
+}
+
 func pointerOf[T any](v T) *T {
 	return &v
 }
@@ -268,16 +692,12 @@ func filterAttributes[T any](predicate func(string) bool, m map[string]T) []Attr
 	return res
 }
 
-func syntheticPredicate(k string) bool {
-	return strings.HasPrefix(k, "$")
-}
-
-func nonSyntheticPredicate(k string) bool {
-	return !strings.HasPrefix(k, "$")
-}
-
-func anyPredicate(k string) bool {
-	return true
+func globPredicate(patterns []string) func(string) bool {
+	return func(k string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			return store.MatchGlob(pattern, k)
+		})
+	}
 }
 
 func toPayload(r store.RetrievePayloadsRow, includeData IncludeData) *EntityData {
@@ -293,20 +713,16 @@ func toPayload(r store.RetrievePayloadsRow, includeData IncludeData) *EntityData
 		res.ContentType = &r.ContentType
 	}
 
-	switch {
-	case includeData.Attributes && includeData.SyntheticAttributes:
-		res.StringAttributes = filterAttributes(anyPredicate, r.StringAttributes.Values)
-		res.NumericAttributes = filterAttributes(anyPredicate, r.NumericAttributes.Values)
-	case includeData.Attributes:
-		res.StringAttributes = filterAttributes(nonSyntheticPredicate, r.StringAttributes.Values)
-		res.NumericAttributes = filterAttributes(nonSyntheticPredicate, r.NumericAttributes.Values)
-	case includeData.SyntheticAttributes:
-		res.StringAttributes = filterAttributes(syntheticPredicate, r.StringAttributes.Values)
-		res.NumericAttributes = filterAttributes(syntheticPredicate, r.NumericAttributes.Values)
+	if patterns := includeData.stringAttributePatterns(); len(patterns) > 0 {
+		res.StringAttributes = filterAttributes(globPredicate(patterns), r.StringAttributes.Values)
+	}
+
+	if patterns := includeData.numericAttributePatterns(); len(patterns) > 0 {
+		res.NumericAttributes = filterAttributes(globPredicate(patterns), r.NumericAttributes.Values)
 	}
 
 	if includeData.Expiration {
-		res.ExpiresAt = pointerOf(r.NumericAttributes.Values["$expiration"])
+		res.ExpiresAt = pointerOf(r.NumericAttributes.Values["$expiration"].Uint64())
 	}
 
 	if includeData.Owner {
@@ -314,19 +730,31 @@ func toPayload(r store.RetrievePayloadsRow, includeData IncludeData) *EntityData
 	}
 
 	if includeData.CreatedAtBlock {
-		res.CreatedAtBlock = pointerOf(r.NumericAttributes.Values["$createdAtBlock"])
+		res.CreatedAtBlock = pointerOf(r.NumericAttributes.Values["$createdAtBlock"].Uint64())
 	}
 
 	if includeData.LastModifiedAtBlock {
-		res.LastModifiedAtBlock = pointerOf(r.NumericAttributes.Values["$lastModifiedAtBlock"])
+		res.LastModifiedAtBlock = pointerOf(r.NumericAttributes.Values["$lastModifiedAtBlock"].Uint64())
 	}
 
 	if includeData.TransactionIndexInBlock {
-		res.TransactionIndexInBlock = pointerOf(r.NumericAttributes.Values["$txIndex"])
+		res.TransactionIndexInBlock = pointerOf(r.NumericAttributes.Values["$txIndex"].Uint64())
 	}
 
 	if includeData.OperationIndexInTransaction {
-		res.OperationIndexInTransaction = pointerOf(r.NumericAttributes.Values["$opIndex"])
+		res.OperationIndexInTransaction = pointerOf(r.NumericAttributes.Values["$opIndex"].Uint64())
+	}
+
+	if includeData.PayloadSize {
+		res.PayloadSize = pointerOf(r.NumericAttributes.Values[query.PayloadSizeAttributeKey].Uint64())
+	}
+
+	if includeData.PayloadHash {
+		res.PayloadHash = pointerOf(common.HexToHash(r.StringAttributes.Values[query.PayloadHashAttributeKey]))
+	}
+
+	if includeData.Version {
+		res.Version = pointerOf(r.NumericAttributes.Values[query.VersionAttributeKey].Uint64())
 	}
 
 	return res
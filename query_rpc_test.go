@@ -0,0 +1,932 @@
+package sqlitebitmapstore_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitebitmapstore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/pusher"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// followBlocks feeds the blocks to the store as a single batch.
+func followBlocks(ctx context.Context, sqlStore *sqlitebitmapstore.SQLiteStore, blocks ...events.Block) {
+	iterator := pusher.NewPushIterator()
+
+	go func() {
+		defer GinkgoRecover()
+		iterator.Push(ctx, events.BlockBatch{Blocks: blocks})
+		iterator.Close()
+	}()
+
+	err := sqlStore.FollowEvents(ctx, arkivevents.BatchIterator(iterator.Iterator()))
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func createOperation(i int, stringAttributes map[string]string, numericAttributes map[string]uint64) events.Operation {
+	return events.Operation{
+		TxIndex: 0,
+		OpIndex: uint64(i),
+		Create: &events.OPCreate{
+			Key:               common.BigToHash(big.NewInt(int64(i + 1))),
+			ContentType:       "text/plain",
+			BTL:               100,
+			Owner:             common.HexToAddress("0x1234567890123456789012345678901234567890"),
+			Content:           []byte("content"),
+			StringAttributes:  stringAttributes,
+			NumericAttributes: numericAttributes,
+		},
+	}
+}
+
+func decodeEntities(res *sqlitebitmapstore.QueryResponse) []sqlitebitmapstore.EntityData {
+	entities := []sqlitebitmapstore.EntityData{}
+	for _, d := range res.Data {
+		var ed sqlitebitmapstore.EntityData
+		Expect(json.Unmarshal(d, &ed)).To(Succeed())
+		entities = append(entities, ed)
+	}
+	return entities
+}
+
+var _ = Describe("QueryEntities", func() {
+	var (
+		sqlStore *sqlitebitmapstore.SQLiteStore
+		tmpDir   string
+		ctx      context.Context
+		cancel   context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "sqlitestore_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := slog.New(slog.NewTextHandler(GinkgoWriter, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel = context.WithCancel(context.Background())
+
+		operations := []events.Operation{}
+		for i := range 5 {
+			kind := "even"
+			if i%2 == 1 {
+				kind = "odd"
+			}
+			operations = append(operations, createOperation(
+				i,
+				map[string]string{"kind": kind},
+				map[string]uint64{"index": uint64(i)},
+			))
+		}
+
+		followBlocks(ctx, sqlStore, events.Block{Number: 100, Operations: operations})
+	})
+
+	AfterEach(func() {
+		cancel()
+		if sqlStore != nil {
+			sqlStore.Close()
+		}
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("CountEntities", func() {
+		It("should count the matching entities at the current block", func() {
+			res, err := sqlStore.CountEntities(ctx, `kind = "even"`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(3)))
+			Expect(res.BlockNumber).To(Equal(uint64(100)))
+
+			res, err = sqlStore.CountEntities(ctx, `$all`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(5)))
+		})
+
+		It("should report the total count across pages", func() {
+			resultsPerPage := uint64(2)
+			res, err := sqlStore.QueryEntities(ctx, `index >= 1`, &sqlitebitmapstore.Options{
+				ResultsPerPage:    &resultsPerPage,
+				IncludeTotalCount: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Data).To(HaveLen(2))
+			Expect(res.Cursor).NotTo(BeNil())
+			Expect(*res.TotalCount).To(Equal(uint64(4)))
+			Expect(res.BlockNumber).To(Equal(uint64(100)))
+
+			res, err = sqlStore.QueryEntities(ctx, `index >= 1`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.TotalCount).To(BeNil())
+		})
+	})
+
+	Describe("attribute projection", func() {
+		It("should only return the requested attributes", func() {
+			res, err := sqlStore.QueryEntities(ctx, `kind = "odd"`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{
+					Key:                   true,
+					Expiration:            true,
+					StringAttributeNames:  []string{"ki*", "$owner"},
+					NumericAttributeNames: []string{},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			entities := decodeEntities(res)
+			Expect(entities).To(HaveLen(2))
+			for _, ed := range entities {
+				Expect(ed.Key).NotTo(BeNil())
+				Expect(ed.Value).To(BeEmpty())
+				Expect(*ed.ExpiresAt).To(Equal(uint64(200)))
+				Expect(ed.StringAttributes).To(Equal([]sqlitebitmapstore.Attribute[string]{
+					{Key: "$owner", Value: "0x1234567890123456789012345678901234567890"},
+					{Key: "kind", Value: "odd"},
+				}))
+				Expect(ed.NumericAttributes).To(BeEmpty())
+			}
+		})
+
+		It("should keep the attribute switches working", func() {
+			res, err := sqlStore.QueryEntities(ctx, `kind = "odd"`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{
+					Attributes: true,
+					Payload:    true,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, ed := range decodeEntities(res) {
+				Expect(ed.Value).To(Equal(hexutil.Bytes("content")))
+				Expect(ed.StringAttributes).To(HaveLen(1))
+				Expect(ed.NumericAttributes).To(HaveLen(1))
+				Expect(ed.NumericAttributes[0].Key).To(Equal("index"))
+			}
+		})
+	})
+
+	Describe("full range numeric attributes", func() {
+		It("should compare values above 2^63 correctly", func() {
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 101,
+				Operations: []events.Operation{
+					createOperation(10, map[string]string{"kind": "big"}, map[string]uint64{"amount": math.MaxUint64}),
+					createOperation(11, map[string]string{"kind": "big"}, map[string]uint64{"amount": 1<<63 + 1}),
+					createOperation(12, map[string]string{"kind": "big"}, map[string]uint64{"amount": 7}),
+				},
+			})
+
+			res, err := sqlStore.CountEntities(ctx, `amount > 1000`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+
+			res, err = sqlStore.CountEntities(ctx, `amount >= 0x8000000000000001 && amount < 18446744073709551615`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(1)))
+
+			qr, err := sqlStore.QueryEntities(ctx, `amount = 18446744073709551615`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{Attributes: true},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			entities := decodeEntities(qr)
+			Expect(entities).To(HaveLen(1))
+			Expect(entities[0].NumericAttributes).To(Equal([]sqlitebitmapstore.Attribute[store.NumericValue]{
+				{Key: "amount", Value: store.NewNumericValue(math.MaxUint64)},
+			}))
+			// large values are returned as strings to keep their precision
+			Expect(string(qr.Data[0])).To(ContainSubstring(`{"key":"amount","value":"18446744073709551615"}`))
+		})
+	})
+
+	Describe("string operators", func() {
+		BeforeEach(func() {
+			operations := []events.Operation{}
+			for i, name := range []string{"Alice", "alice", "Alicia", "Bob", "bobby"} {
+				operations = append(operations, createOperation(10+i, map[string]string{"name": name}, nil))
+			}
+			followBlocks(ctx, sqlStore, events.Block{Number: 101, Operations: operations})
+		})
+
+		DescribeTable("should match the distinct values",
+			func(q string, expected int) {
+				res, err := sqlStore.CountEntities(ctx, q, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.Count).To(Equal(uint64(expected)))
+			},
+			Entry("case insensitive equality", `name =* "ALICE"`, 2),
+			Entry("negated case insensitive equality", `name !=* "alice"`, 3),
+			Entry("prefix", `name ^= "Ali"`, 2),
+			Entry("negated prefix", `name !^= "Ali"`, 3),
+			Entry("empty prefix", `name ^= ""`, 5),
+			Entry("regex", `name =~ "(?i)^bob"`, 2),
+			Entry("negated regex", `!(name =~ "^[A-Z]")`, 2),
+		)
+	})
+
+	Describe("query simplification", func() {
+		BeforeEach(func() {
+			operations := []events.Operation{}
+			for i, name := range []string{"a", "b", "c", "d", "e"} {
+				operations = append(operations, createOperation(20+i, map[string]string{"name": name}, map[string]uint64{"n": uint64(i)}))
+			}
+			followBlocks(ctx, sqlStore, events.Block{Number: 101, Operations: operations})
+		})
+
+		DescribeTable("should match the same entities as the query",
+			func(q string, expected int) {
+				res, err := sqlStore.CountEntities(ctx, q, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.Count).To(Equal(uint64(expected)))
+			},
+			Entry("contradiction", `n = 1 && n = 2`, 0),
+			Entry("contradicting bounds", `n > 3 && n < 2`, 0),
+			Entry("numeric range", `n > 0 && n <= 3`, 3),
+			Entry("numeric range with exclusive bounds", `n > 0 && n < 3`, 2),
+			Entry("numeric range with inclusive bounds", `n >= 1 && n <= 3 && n >= 0`, 3),
+			Entry("numeric range with exclusions", `n >= 1 && n <= 3 && n NOT IN (2 4)`, 2),
+			Entry("string range", `name >= "b" && name < "d"`, 2),
+			Entry("string range with exclusive bounds", `name > "a" && name <= "e" && name > "b"`, 3),
+			Entry("equality chain", `n = 0 || n = 1 || name = "e" || n = 4`, 3),
+			Entry("redundant branch", `n > 1 || n > 3 && name = "e"`, 3),
+			Entry("nothing", `$none`, 0),
+			Entry("subquery that matches nothing", `$key NOT IN (SELECT $key WHERE $none)`, 10),
+		)
+	})
+
+	Describe("grammar versions", func() {
+		It("should query attribute names that need quoting", func() {
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 101,
+				Operations: []events.Operation{
+					createOperation(10, map[string]string{"content-type": "image/png"}, nil),
+				},
+			})
+
+			res, err := sqlStore.CountEntities(ctx, "`content-type` IN ('image/png', 'image/jpeg') -- images", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(1)))
+
+			_, err = sqlStore.CountEntities(ctx, "`content-type` = 'image/png'", &sqlitebitmapstore.Options{
+				GrammarVersion: query.GrammarV1,
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("bind arguments", func() {
+		It("should bind positional and named arguments", func() {
+			res, err := sqlStore.CountEntities(ctx, `kind = ? && index IN (:indexes)`, nil, "even", query.Named("indexes", []uint64{0, 1, 2}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+
+			// the compiled query is reused with other arguments
+			res, err = sqlStore.CountEntities(ctx, `kind = ? && index IN (:indexes)`, nil, "odd", query.Named("indexes", []uint64{0, 1, 2}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(1)))
+
+			qr, err := sqlStore.QueryEntities(ctx, `kind = ?`, nil, `" || kind = "odd`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qr.Data).To(BeEmpty())
+		})
+
+		It("should reject missing and mistyped arguments", func() {
+			_, err := sqlStore.CountEntities(ctx, `kind = ?`, nil)
+			Expect(err).To(MatchError(ContainSubstring("query has 1 positional parameters, got 0 arguments")))
+
+			_, err = sqlStore.CountEntities(ctx, `kind ~ ?`, nil, 5)
+			Expect(err).To(MatchError(ContainSubstring("expected a string value")))
+		})
+	})
+
+	Describe("attribute catalog", func() {
+		It("should keep the catalog up to date", func() {
+			catalog, err := sqlStore.AttributeCatalog(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog).To(HaveKeyWithValue("kind", query.AttributeTypes{StringEntities: 5}))
+			Expect(catalog).To(HaveKeyWithValue("index", query.AttributeTypes{NumericEntities: 5}))
+
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 101,
+				Operations: []events.Operation{
+					createOperation(5, map[string]string{"index": "five"}, nil),
+				},
+			})
+
+			catalog, err = sqlStore.AttributeCatalog(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog).To(HaveKeyWithValue("index", query.AttributeTypes{StringEntities: 1, NumericEntities: 5}))
+		})
+
+		It("should validate queries against the catalog", func() {
+			res, err := sqlStore.CountEntities(ctx, `index = "2" && kind = "even"`, &sqlitebitmapstore.Options{
+				Validation: &sqlitebitmapstore.Validation{},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(BeZero())
+			Expect(res.Warnings).To(Equal([]query.ValidationWarning{
+				{Term: `index = "2"`, Attribute: "index", Message: `the attribute "index" only has numeric values`},
+			}))
+
+			qr, err := sqlStore.QueryEntities(ctx, `index = "2" && kind = "even"`, &sqlitebitmapstore.Options{
+				Validation: &sqlitebitmapstore.Validation{Coerce: true},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qr.Data).To(HaveLen(1))
+			Expect(qr.Warnings).To(BeEmpty())
+
+			res, err = sqlStore.CountEntities(ctx, `index = "2"`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(BeZero())
+			Expect(res.Warnings).To(BeEmpty())
+
+			_, err = sqlStore.CountEntities(ctx, `kind = "even" || colour = "red"`, &sqlitebitmapstore.Options{
+				Validation: &sqlitebitmapstore.Validation{RejectUnknown: true},
+			})
+			var unknown *query.UnknownAttributeError
+			Expect(errors.As(err, &unknown)).To(BeTrue())
+			Expect(unknown.Attribute).To(Equal("colour"))
+		})
+	})
+
+	Describe("parse errors", func() {
+		It("should return positioned parse errors", func() {
+			_, err := sqlStore.QueryEntities(ctx, "kind = \"even\"\n  && index >", nil)
+
+			var perr *query.ParseError
+			Expect(errors.As(err, &perr)).To(BeTrue())
+			Expect(perr.Line).To(Equal(2))
+			Expect(perr.Column).To(Equal(13))
+			Expect(perr.Unexpected).To(BeEmpty())
+			Expect(perr.Expected).To(Equal([]string{"Value"}))
+
+			_, err = sqlStore.CountEntities(ctx, `kind = ?`, nil)
+			Expect(errors.As(err, &perr)).To(BeFalse())
+			var berr *query.BindError
+			Expect(errors.As(err, &berr)).To(BeTrue())
+
+			_, err = sqlStore.QueryEntities(ctx, `kind = ? && index = ?`, nil, "even", struct{}{})
+			Expect(errors.As(err, &berr)).To(BeTrue())
+			Expect(berr.Message).To(ContainSubstring("unsupported argument type"))
+
+			// JSON filters fail with parse errors too, positioned for invalid JSON
+			_, err = sqlStore.QueryEntities(ctx, "{\"attribute\": \"kind\",\n \"op\": \"=\" \"value\": \"even\"}", nil)
+			Expect(errors.As(err, &perr)).To(BeTrue())
+			Expect(perr.Line).To(Equal(2))
+			Expect(perr.Column).To(Equal(12))
+
+			_, err = sqlStore.QueryEntities(ctx, `{"attribute": "kind", "op": "<>", "value": "even"}`, nil)
+			Expect(errors.As(err, &perr)).To(BeTrue())
+			Expect(perr.Line).To(BeZero())
+			Expect(perr.Message).To(Equal(`invalid JSON filter: unknown op "<>"`))
+		})
+	})
+
+	Describe("head-relative values", func() {
+		It("should resolve @head at the block of the read transaction", func() {
+			// the entities were created at block 100 and expire at block 200
+			res, err := sqlStore.CountEntities(ctx, `$expiration < @head + 101`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(5)))
+			Expect(res.BlockNumber).To(Equal(uint64(100)))
+
+			res, err = sqlStore.CountEntities(ctx, `$expiration < @head + 100`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(BeZero())
+
+			followBlocks(ctx, sqlStore, events.Block{Number: 150})
+
+			qr, err := sqlStore.QueryEntities(ctx, `$expiration <= @head + 50 && kind = "odd"`, &sqlitebitmapstore.Options{
+				Explain: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qr.Data).To(HaveLen(2))
+			Expect(qr.BlockNumber).To(Equal(uint64(150)))
+			Expect(qr.Explain.Query).To(Equal(`$expiration <= 200 && kind = "odd"`))
+		})
+	})
+
+	Describe("subqueries", func() {
+		It("should match entities by the entities that they reference", func() {
+			keyOf := func(i int) string {
+				return common.BigToHash(big.NewInt(int64(i + 1))).Hex()
+			}
+
+			// entities 5 to 9 reference entities 0 to 4
+			operations := []events.Operation{}
+			for i := range 5 {
+				operations = append(operations, createOperation(
+					i+5,
+					map[string]string{"kind": "child", "parent": keyOf(i)},
+					nil,
+				))
+			}
+			followBlocks(ctx, sqlStore, events.Block{Number: 101, Operations: operations})
+
+			res, err := sqlStore.CountEntities(ctx, `parent IN (select $key where kind = "even")`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(3)))
+
+			qr, err := sqlStore.QueryEntities(
+				ctx,
+				`parent NOT IN (select $key where kind = ? && index > 0)`,
+				&sqlitebitmapstore.Options{IncludeData: &sqlitebitmapstore.IncludeData{Key: true}},
+				"even",
+			)
+			Expect(err).NotTo(HaveOccurred())
+			keys := []string{}
+			for _, e := range decodeEntities(qr) {
+				keys = append(keys, e.Key.Hex())
+			}
+			Expect(keys).To(ConsistOf(keyOf(5), keyOf(6), keyOf(8)))
+
+			res, err = sqlStore.CountEntities(ctx, `parent IN (select $key where kind = "none")`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(BeZero())
+		})
+	})
+
+	Describe("matched branches", func() {
+		It("should annotate the entities with the conjunctions that they match", func() {
+			qr, err := sqlStore.QueryEntities(ctx, `kind = "even" && index > 1 || index <= 2`, &sqlitebitmapstore.Options{
+				IncludeData:     &sqlitebitmapstore.IncludeData{Key: true},
+				MatchedBranches: true,
+				Explain:         true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qr.Explain.Query).To(Equal(`index <= 2 || index > 1 && kind = "even"`))
+
+			lower := sqlitebitmapstore.MatchedBranch{Index: 0, Terms: []string{`index <= 2`}}
+			even := sqlitebitmapstore.MatchedBranch{Index: 1, Terms: []string{`index > 1`, `kind = "even"`}}
+
+			matched := map[common.Hash][]sqlitebitmapstore.MatchedBranch{}
+			for _, e := range decodeEntities(qr) {
+				matched[*e.Key] = e.MatchedBranches
+			}
+			keyOf := func(i int) common.Hash {
+				return common.BigToHash(big.NewInt(int64(i + 1)))
+			}
+			Expect(matched).To(Equal(map[common.Hash][]sqlitebitmapstore.MatchedBranch{
+				keyOf(0): {lower},
+				keyOf(1): {lower},
+				keyOf(2): {lower, even},
+				keyOf(4): {even},
+			}))
+
+			qr, err = sqlStore.QueryEntities(ctx, `$all`, &sqlitebitmapstore.Options{MatchedBranches: true})
+			Expect(err).NotTo(HaveOccurred())
+			for _, e := range decodeEntities(qr) {
+				Expect(e.MatchedBranches).To(Equal([]sqlitebitmapstore.MatchedBranch{{Index: 0, Terms: []string{`$all`}}}))
+			}
+
+			qr, err = sqlStore.QueryEntities(ctx, `kind = "even"`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			for _, e := range decodeEntities(qr) {
+				Expect(e.MatchedBranches).To(BeNil())
+			}
+		})
+	})
+
+	Describe("collapse", func() {
+		keyOf := func(i int) common.Hash {
+			return common.BigToHash(big.NewInt(int64(i + 1)))
+		}
+
+		keysOf := func(qr *sqlitebitmapstore.QueryResponse) []common.Hash {
+			keys := []common.Hash{}
+			for _, e := range decodeEntities(qr) {
+				keys = append(keys, *e.Key)
+			}
+			return keys
+		}
+
+		BeforeEach(func() {
+			// entities 5 and 6 have no index and entity 6 has no kind
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 101,
+				Operations: []events.Operation{
+					createOperation(5, map[string]string{"kind": "odd"}, nil),
+					createOperation(6, map[string]string{"colour": "red"}, nil),
+				},
+			})
+		})
+
+		It("should return the last created entity of every value", func() {
+			qr, err := sqlStore.QueryEntities(ctx, `$all`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{Key: true},
+				Collapse:    &sqlitebitmapstore.Collapse{Attribute: "kind"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keysOf(qr)).To(Equal([]common.Hash{keyOf(5), keyOf(4)}))
+
+			res, err := sqlStore.CountEntities(ctx, `index > 0`, &sqlitebitmapstore.Options{
+				Collapse: &sqlitebitmapstore.Collapse{Attribute: "kind"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+		})
+
+		It("should order the entities of a value by an attribute", func() {
+			qr, err := sqlStore.QueryEntities(ctx, `$all`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{Key: true},
+				Collapse:    &sqlitebitmapstore.Collapse{Attribute: "kind", OrderBy: "index"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keysOf(qr)).To(Equal([]common.Hash{keyOf(4), keyOf(3)}))
+
+			qr, err = sqlStore.QueryEntities(ctx, `$all`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{Key: true},
+				Collapse:    &sqlitebitmapstore.Collapse{Attribute: "kind", OrderBy: "index", Ascending: true},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keysOf(qr)).To(Equal([]common.Hash{keyOf(1), keyOf(0)}))
+
+			// entities without the attribute to order by come last
+			qr, err = sqlStore.QueryEntities(ctx, `kind = "odd" && index != 1 || $key = ?`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{Key: true},
+				Collapse:    &sqlitebitmapstore.Collapse{Attribute: "kind", OrderBy: "index", Ascending: true},
+			}, keyOf(5).Hex())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keysOf(qr)).To(Equal([]common.Hash{keyOf(3)}))
+		})
+
+		It("should paginate the collapsed results", func() {
+			resultsPerPage := uint64(1)
+			options := &sqlitebitmapstore.Options{
+				IncludeData:       &sqlitebitmapstore.IncludeData{Key: true},
+				ResultsPerPage:    &resultsPerPage,
+				IncludeTotalCount: true,
+				Collapse:          &sqlitebitmapstore.Collapse{Attribute: "kind", OrderBy: "index", Ascending: true},
+			}
+
+			keys := []common.Hash{}
+			for {
+				qr, err := sqlStore.QueryEntities(ctx, `$all`, options)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*qr.TotalCount).To(Equal(uint64(2)))
+				keys = append(keys, keysOf(qr)...)
+				if qr.Cursor == nil {
+					break
+				}
+				options.Cursor = *qr.Cursor
+			}
+			Expect(keys).To(Equal([]common.Hash{keyOf(1), keyOf(0)}))
+		})
+
+		It("should require an attribute", func() {
+			_, err := sqlStore.QueryEntities(ctx, `$all`, &sqlitebitmapstore.Options{
+				Collapse: &sqlitebitmapstore.Collapse{},
+			})
+			Expect(err).To(MatchError(ContainSubstring("the attribute to collapse on is required")))
+		})
+	})
+
+	Describe("large IN lists", func() {
+		// more values than SQLite accepts bind parameters in one statement
+		const n = 40000
+
+		It("should match attributes with any of the values", func() {
+			kinds := []string{"even"}
+			indexes := []uint64{}
+			for i := range n {
+				kinds = append(kinds, fmt.Sprintf("kind-%d", i))
+				indexes = append(indexes, uint64(i+3))
+			}
+
+			res, err := sqlStore.CountEntities(ctx, `kind IN (?)`, nil, kinds)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(3)))
+
+			res, err = sqlStore.CountEntities(ctx, `kind NOT IN (?)`, nil, kinds)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+
+			res, err = sqlStore.CountEntities(ctx, `index IN (?) && kind = "even"`, nil, indexes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(1)))
+
+			res, err = sqlStore.CountEntities(ctx, `index NOT IN (?)`, nil, indexes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(3)))
+		})
+
+		It("should look entity keys up directly", func() {
+			keys := []string{}
+			for i := range n {
+				keys = append(keys, common.BigToHash(big.NewInt(int64(i+2))).Hex())
+			}
+
+			res, err := sqlStore.CountEntities(ctx, `$key IN (?)`, nil, keys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(4)))
+
+			res, err = sqlStore.CountEntities(ctx, `$key NOT IN (?) && kind = "even"`, nil, keys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(1)))
+
+			key := common.BigToHash(big.NewInt(3)).Hex()
+			qr, err := sqlStore.QueryEntities(ctx, `$key = ?`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{Key: true},
+			}, key)
+			Expect(err).NotTo(HaveOccurred())
+			entities := decodeEntities(qr)
+			Expect(entities).To(HaveLen(1))
+			Expect(entities[0].Key.Hex()).To(Equal(key))
+
+			res, err = sqlStore.CountEntities(ctx, `$key != ?`, nil, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(4)))
+
+			// values that are not keys match nothing, like in the index
+			res, err = sqlStore.CountEntities(ctx, `$key IN ("0x03", "three")`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(BeZero())
+		})
+	})
+
+	Describe("attribute comparisons", func() {
+		It("should compare the values of two attributes", func() {
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 101,
+				Operations: []events.Operation{
+					createOperation(5, map[string]string{"name": "a", "title": "a"}, map[string]uint64{"min": 1, "max": 1}),
+					createOperation(6, map[string]string{"name": "a", "title": "b"}, map[string]uint64{"min": 1, "max": 2}),
+					createOperation(7, map[string]string{"name": "c"}, map[string]uint64{"min": 3}),
+					createOperation(8, map[string]string{"title": "1"}, map[string]uint64{"name": 1}),
+				},
+			})
+
+			for q, expected := range map[string]uint64{
+				`name = title`:               1,
+				`name != title`:              1,
+				`min = max`:                  1,
+				`min != max`:                 1,
+				`name = min`:                 0,
+				`name = title || min != max`: 2,
+				`$owner = $creator`:          9,
+				`$owner != $creator`:         0,
+			} {
+				res, err := sqlStore.CountEntities(ctx, q, nil)
+				Expect(err).NotTo(HaveOccurred(), q)
+				Expect(res.Count).To(Equal(expected), q)
+			}
+		})
+
+		It("should scan the attributes of attributes with many values", func() {
+			operations := []events.Operation{}
+			for i := range query.AttributeComparisonMaxValues + 1 {
+				other := uint64(i)
+				if i%1000 == 0 {
+					other++
+				}
+				operations = append(operations, createOperation(i+5, map[string]string{}, map[string]uint64{"a": uint64(i), "b": other}))
+			}
+			// entities with only one of the attributes are not scanned
+			for i := range 2000 {
+				operations = append(operations, createOperation(i+20000, map[string]string{}, map[string]uint64{"a": uint64(i)}))
+			}
+			followBlocks(ctx, sqlStore, events.Block{Number: 101, Operations: operations})
+
+			res, err := sqlStore.CountEntities(ctx, `a != b`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(11)))
+
+			qr, err := sqlStore.QueryEntities(ctx, `a = b`, &sqlitebitmapstore.Options{Explain: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qr.Explain.Matching).To(Equal(uint64(query.AttributeComparisonMaxValues + 1 - 11)))
+			scans := 0
+			for _, statement := range qr.Explain.Evaluation.Conjunctions[0].Terms[0].SQL {
+				if strings.Contains(statement, "ScanAttributes") {
+					scans++
+				}
+			}
+			Expect(scans).To(Equal(11))
+
+			res, err = sqlStore.CountEntities(ctx, `a = b && index = 3`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(BeZero())
+		})
+	})
+
+	Describe("JSON filters", func() {
+		It("should accept a JSON filter instead of a query string", func() {
+			res, err := sqlStore.CountEntities(ctx, `{"and": [
+				{"attribute": "kind", "op": "=", "value": "even"},
+				{"not": {"attribute": "index", "op": "in", "values": [0]}}
+			]}`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+
+			_, err = sqlStore.CountEntities(ctx, `{"attribute": "kind", "op": "="}`, nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid JSON filter")))
+		})
+	})
+
+	Describe("statistics", func() {
+		It("should keep the bitmap statistics up to date", func() {
+			q := sqlStore.NewQueries()
+
+			kind, err := q.GetStringAttributeStats(ctx, "kind")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kind).To(Equal(store.GetStringAttributeStatsRow{DistinctValues: 2, Cardinality: 5}))
+
+			even, err := q.EstimateStringAttributeValueInclusion(ctx, store.EstimateStringAttributeValueInclusionParams{Name: "kind", Values: []string{"even"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(even).To(Equal(int64(3)))
+
+			deleted := events.OPDelete(common.BigToHash(big.NewInt(2)))
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 101,
+				Operations: []events.Operation{
+					{Delete: &deleted},
+				},
+			})
+
+			kind, err = q.GetStringAttributeStats(ctx, "kind")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kind).To(Equal(store.GetStringAttributeStatsRow{DistinctValues: 2, Cardinality: 4}))
+
+			index, err := q.GetNumericAttributeStats(ctx, "index")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(Equal(store.GetNumericAttributeStatsRow{DistinctValues: 4, Cardinality: 4}))
+		})
+
+		It("should backfill the statistics of existing databases", func() {
+			Expect(sqlStore.Close()).To(Succeed())
+
+			db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "test.db"))
+			Expect(err).NotTo(HaveOccurred())
+			for _, stmt := range []string{
+				`UPDATE string_attributes_values_bitmaps SET cardinality = NULL`,
+				`UPDATE numeric_attributes_values_bitmaps SET cardinality = NULL`,
+				`DELETE FROM string_attributes_stats`,
+				`DELETE FROM numeric_attributes_stats`,
+			} {
+				_, err = db.Exec(stmt)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(db.Close()).To(Succeed())
+
+			logger := slog.New(slog.NewTextHandler(GinkgoWriter, nil))
+			sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+			Expect(err).NotTo(HaveOccurred())
+
+			q := sqlStore.NewQueries()
+
+			kind, err := q.GetStringAttributeStats(ctx, "kind")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kind).To(Equal(store.GetStringAttributeStatsRow{DistinctValues: 2, Cardinality: 5}))
+
+			index, err := q.GetNumericAttributeStats(ctx, "index")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(Equal(store.GetNumericAttributeStatsRow{DistinctValues: 5, Cardinality: 5}))
+
+			odd, err := q.EstimateStringAttributeValueInclusion(ctx, store.EstimateStringAttributeValueInclusionParams{Name: "kind", Values: []string{"odd"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(odd).To(Equal(int64(2)))
+		})
+
+		It("should resume an interrupted backfill", func() {
+			Expect(sqlStore.Close()).To(Succeed())
+
+			// the string bitmaps were backfilled and committed, the numeric
+			// ones and the statistics were not
+			db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "test.db"))
+			Expect(err).NotTo(HaveOccurred())
+			for _, stmt := range []string{
+				`UPDATE numeric_attributes_values_bitmaps SET cardinality = NULL`,
+				`DELETE FROM string_attributes_stats`,
+				`DELETE FROM numeric_attributes_stats`,
+			} {
+				_, err = db.Exec(stmt)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(db.Close()).To(Succeed())
+
+			logger := slog.New(slog.NewTextHandler(GinkgoWriter, nil))
+			sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+			Expect(err).NotTo(HaveOccurred())
+
+			q := sqlStore.NewQueries()
+
+			kind, err := q.GetStringAttributeStats(ctx, "kind")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kind).To(Equal(store.GetStringAttributeStatsRow{DistinctValues: 2, Cardinality: 5}))
+
+			index, err := q.GetNumericAttributeStats(ctx, "index")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(Equal(store.GetNumericAttributeStatsRow{DistinctValues: 5, Cardinality: 5}))
+		})
+
+		DescribeTable("should plan conjunctions by selectivity",
+			func(q string, expected int) {
+				res, err := sqlStore.CountEntities(ctx, q, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.Count).To(Equal(uint64(expected)))
+			},
+			Entry("attribute that no entity has", `kind = "even" && missing = 1`, 0),
+			Entry("value that no entity has", `kind != "odd" && kind ~ "*" && index = 7`, 0),
+			Entry("empty intersection", `kind = "odd" && index IN (0 2 4) && index != 1`, 0),
+			Entry("rare equality and expensive terms", `index != 0 && kind ~ "e*" && kind =* "EVEN" && index = 2`, 1),
+			Entry("negations", `kind != "odd" && index NOT IN (0 1) && kind !~ "o*"`, 2),
+		)
+	})
+
+	Describe("query complexity", func() {
+		It("should reject queries whose normal form is too large", func() {
+			sqlStore.SetMaxQueryConjunctions(4)
+
+			res, err := sqlStore.CountEntities(ctx, `(kind = "even" || kind = "odd") && (index = 0 || index = 1)`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+
+			_, err = sqlStore.CountEntities(ctx, `(kind = "even" || kind = "odd") && (index = 0 || index = 1 || index = 2)`, nil)
+			var tooMany *query.TooManyConjunctionsError
+			Expect(errors.As(err, &tooMany)).To(BeTrue())
+			Expect(tooMany.Conjunctions).To(Equal(uint64(6)))
+
+			_, err = sqlStore.CountEntities(ctx, `{"and": [
+				{"or": [{"attribute": "kind", "op": "=", "value": "even"}, {"attribute": "kind", "op": "=", "value": "odd"}]},
+				{"or": [{"attribute": "index", "op": "=", "value": 0}, {"attribute": "index", "op": "=", "value": 1}, {"attribute": "index", "op": "=", "value": 2}]}
+			]}`, nil)
+			Expect(errors.As(err, &tooMany)).To(BeTrue())
+		})
+	})
+	Describe("explain", func() {
+		It("should report how the query was evaluated", func() {
+			resultsPerPage := uint64(1)
+			res, err := sqlStore.QueryEntities(ctx, `index >= 1 && kind = "even"`, &sqlitebitmapstore.Options{
+				ResultsPerPage: &resultsPerPage,
+				Explain:        true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Data).To(HaveLen(1))
+
+			explain := res.Explain
+			Expect(explain).NotTo(BeNil())
+			Expect(explain.Query).To(Equal(`index >= 1 && kind = "even"`))
+			Expect(explain.Matching).To(Equal(uint64(2)))
+			Expect(explain.AfterCursor).To(Equal(uint64(2)))
+			Expect(explain.Retrieved).To(Equal(uint64(1)))
+			Expect(explain.Evaluation.Cardinality).To(Equal(uint64(2)))
+
+			Expect(explain.Evaluation.Conjunctions).To(HaveLen(1))
+			conjunction := explain.Evaluation.Conjunctions[0]
+			Expect(conjunction.Cardinality).To(Equal(uint64(2)))
+			Expect(conjunction.Terms).To(HaveLen(2))
+
+			kind, index := conjunction.Terms[0], conjunction.Terms[1]
+			Expect(kind.Term).To(Equal(`kind = "even"`))
+			Expect(*kind.Estimate).To(Equal(uint64(3)))
+			Expect(kind.Evaluated).To(BeTrue())
+			Expect(kind.Bitmaps).To(Equal(uint64(1)))
+			Expect(kind.BitmapBytes).NotTo(BeZero())
+			Expect(kind.Cardinality).To(Equal(uint64(3)))
+			Expect(kind.Intersection).To(Equal(uint64(3)))
+			Expect(kind.SQL).NotTo(BeEmpty())
+
+			Expect(index.Term).To(Equal(`index >= 1`))
+			Expect(*index.Estimate).To(Equal(uint64(4)))
+			Expect(index.Bitmaps).To(Equal(uint64(4)))
+			Expect(index.Cardinality).To(Equal(uint64(4)))
+			Expect(index.Intersection).To(Equal(uint64(2)))
+
+			res, err = sqlStore.QueryEntities(ctx, `index >= 1`, &sqlitebitmapstore.Options{
+				Cursor: *res.Cursor,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Explain).To(BeNil())
+		})
+
+		It("should report conjunctions that the statistics show to be empty", func() {
+			res, err := sqlStore.QueryEntities(ctx, `kind = "even" && index = 7`, &sqlitebitmapstore.Options{Explain: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Data).To(BeEmpty())
+
+			conjunction := res.Explain.Evaluation.Conjunctions[0]
+			Expect(conjunction.EmptyByStatistics).To(BeTrue())
+			Expect(conjunction.Cardinality).To(BeZero())
+			for _, term := range conjunction.Terms {
+				Expect(term.Evaluated).To(BeFalse())
+				Expect(term.Bitmaps).To(BeZero())
+			}
+		})
+	})
+})
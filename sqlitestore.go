@@ -12,8 +12,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
 	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
@@ -27,6 +29,10 @@ type SQLiteStore struct {
 	writePool *sql.DB
 	readPool  *sql.DB
 	log       *slog.Logger
+
+	compiledQueries *lru.Cache[compiledQueryKey, *query.Prepared]
+
+	maxQueryConjunctions int
 }
 
 func NewSQLiteStore(
@@ -65,7 +71,33 @@ func NewSQLiteStore(
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	return &SQLiteStore{writePool: writePool, readPool: readPool, log: log}, nil
+	err = backfillStatistics(context.Background(), writePool, log)
+	if err != nil {
+		writePool.Close()
+		readPool.Close()
+		return nil, fmt.Errorf("failed to backfill statistics: %w", err)
+	}
+
+	err = backfillPayloadAttributes(context.Background(), writePool, log)
+	if err != nil {
+		writePool.Close()
+		readPool.Close()
+		return nil, fmt.Errorf("failed to backfill payload attributes: %w", err)
+	}
+
+	return &SQLiteStore{
+		writePool:       writePool,
+		readPool:        readPool,
+		log:             log,
+		compiledQueries: lru.NewCache[compiledQueryKey, *query.Prepared](compiledQueryCacheSize),
+	}, nil
+}
+
+// SetMaxQueryConjunctions limits the size of the disjunctive normal form of
+// the queries that the store runs, see query.ParseOptions. It must be called
+// before the store serves queries.
+func (s *SQLiteStore) SetMaxQueryConjunctions(n int) {
+	s.maxQueryConjunctions = n
 }
 
 func runMigrations(db *sql.DB) error {
@@ -180,15 +212,18 @@ func (s *SQLiteStore) FollowEvents(ctx context.Context, iterator arkivevents.Bat
 						stringAttributes["$key"] = strings.ToLower(key.Hex())
 
 						untilBlock := block.Number + operation.Create.BTL
-						numericAttributes := maps.Clone(operation.Create.NumericAttributes)
-						numericAttributes["$expiration"] = uint64(untilBlock)
-						numericAttributes["$createdAtBlock"] = uint64(block.Number)
-						numericAttributes["$lastModifiedAtBlock"] = uint64(block.Number)
+						numericAttributes := store.NewNumericValues(operation.Create.NumericAttributes)
+						numericAttributes["$expiration"] = store.NewNumericValue(untilBlock)
+						numericAttributes["$createdAtBlock"] = store.NewNumericValue(block.Number)
+						numericAttributes["$lastModifiedAtBlock"] = store.NewNumericValue(block.Number)
 
 						sequence := block.Number<<32 | operation.TxIndex<<16 | operation.OpIndex
-						numericAttributes["$sequence"] = sequence
-						numericAttributes["$txIndex"] = uint64(operation.TxIndex)
-						numericAttributes["$opIndex"] = uint64(operation.OpIndex)
+						numericAttributes["$sequence"] = store.NewNumericValue(sequence)
+						numericAttributes["$txIndex"] = store.NewNumericValue(operation.TxIndex)
+						numericAttributes["$opIndex"] = store.NewNumericValue(operation.OpIndex)
+						numericAttributes["$version"] = store.NewNumericValue(1)
+
+						setPayloadAttributes(stringAttributes, numericAttributes, operation.Create.ContentType, operation.Create.Content)
 
 						id, err := st.UpsertPayload(
 							ctx,
@@ -252,14 +287,21 @@ func (s *SQLiteStore) FollowEvents(ctx context.Context, iterator arkivevents.Bat
 						stringAttributes["$key"] = strings.ToLower(operation.Update.Key.Hex())
 
 						untilBlock := block.Number + operation.Update.BTL
-						numericAttributes := maps.Clone(operation.Update.NumericAttributes)
-						numericAttributes["$expiration"] = uint64(untilBlock)
+						numericAttributes := store.NewNumericValues(operation.Update.NumericAttributes)
+						numericAttributes["$expiration"] = store.NewNumericValue(untilBlock)
 						numericAttributes["$createdAtBlock"] = oldNumericAttributes.Values["$createdAtBlock"]
 
 						numericAttributes["$sequence"] = oldNumericAttributes.Values["$sequence"]
 						numericAttributes["$txIndex"] = oldNumericAttributes.Values["$txIndex"]
 						numericAttributes["$opIndex"] = oldNumericAttributes.Values["$opIndex"]
-						numericAttributes["$lastModifiedAtBlock"] = uint64(block.Number)
+						numericAttributes["$lastModifiedAtBlock"] = store.NewNumericValue(block.Number)
+
+						// only the last update of the block is applied, but all of
+						// them count
+						version := oldNumericAttributes.Values["$version"].Uint64() + uint64(len(updates))
+						numericAttributes["$version"] = store.NewNumericValue(version)
+
+						setPayloadAttributes(stringAttributes, numericAttributes, operation.Update.ContentType, operation.Update.Content)
 
 						id, err := st.UpsertPayload(
 							ctx,
@@ -374,10 +416,10 @@ func (s *SQLiteStore) FollowEvents(ctx context.Context, iterator arkivevents.Bat
 
 						oldNumericAttributes := latestPayload.NumericAttributes
 
-						newToBlock := block.Number + operation.ExtendBTL.BTL
+						newToBlock := store.NewNumericValue(block.Number + operation.ExtendBTL.BTL)
 
 						numericAttributes := maps.Clone(oldNumericAttributes.Values)
-						numericAttributes["$expiration"] = uint64(newToBlock)
+						numericAttributes["$expiration"] = newToBlock
 
 						oldExpiration := oldNumericAttributes.Values["$expiration"]
 
@@ -489,6 +531,12 @@ func (s *SQLiteStore) NewQueries() *store.Queries {
 }
 
 func (s *SQLiteStore) ReadTransaction(ctx context.Context, fn func(q *store.Queries) error) error {
+	return s.readTransaction(ctx, nil, fn)
+}
+
+// readTransaction is ReadTransaction with the queries running through
+// wrap(tx), unless wrap is nil.
+func (s *SQLiteStore) readTransaction(ctx context.Context, wrap func(store.DBTX) store.DBTX, fn func(q *store.Queries) error) error {
 	tx, err := s.readPool.BeginTx(ctx, &sql.TxOptions{
 		ReadOnly: true,
 	})
@@ -497,7 +545,12 @@ func (s *SQLiteStore) ReadTransaction(ctx context.Context, fn func(q *store.Quer
 	}
 	defer tx.Rollback()
 
-	st := store.New(tx)
+	var db store.DBTX = tx
+	if wrap != nil {
+		db = wrap(tx)
+	}
+
+	st := store.New(db)
 
 	return fn(st)
 }
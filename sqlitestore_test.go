@@ -2,13 +2,16 @@ package sqlitebitmapstore_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"log/slog"
+	"math/big"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/Arkiv-Network/arkiv-events/events"
 	sqlitebitmapstore "github.com/Arkiv-Network/sqlite-bitmap-store"
 	"github.com/Arkiv-Network/sqlite-bitmap-store/pusher"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
 	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
 )
 
@@ -163,7 +167,7 @@ var _ = Describe("SQLiteStore", func() {
 				// Query by numeric attribute: version = 1
 				version1Bitmap, err := q.EvaluateNumericAttributeValueEqual(ctx, store.EvaluateNumericAttributeValueEqualParams{
 					Name:  "version",
-					Value: 1,
+					Value: store.NewNumericValue(1),
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(version1Bitmap).NotTo(BeNil())
@@ -174,12 +178,12 @@ var _ = Describe("SQLiteStore", func() {
 				version1Payloads, err := q.RetrievePayloads(ctx, version1IDs)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(version1Payloads).To(HaveLen(1))
-				Expect(version1Payloads[0].NumericAttributes.Values["version"]).To(Equal(uint64(1)))
+				Expect(version1Payloads[0].NumericAttributes.Values["version"].Uint64()).To(Equal(uint64(1)))
 
 				// Query by numeric attribute: version > 1
 				versionGT1Bitmaps, err := q.EvaluateNumericAttributeValueGreaterThan(ctx, store.EvaluateNumericAttributeValueGreaterThanParams{
 					Name:  "version",
-					Value: 1,
+					Value: store.NewNumericValue(1),
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(versionGT1Bitmaps).To(HaveLen(1))
@@ -196,12 +200,12 @@ var _ = Describe("SQLiteStore", func() {
 				versionGT1Payloads, err := q.RetrievePayloads(ctx, versionGT1IDs)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(versionGT1Payloads).To(HaveLen(1))
-				Expect(versionGT1Payloads[0].NumericAttributes.Values["version"]).To(Equal(uint64(2)))
+				Expect(versionGT1Payloads[0].NumericAttributes.Values["version"].Uint64()).To(Equal(uint64(2)))
 
 				// Query by numeric attribute: priority >= 10
 				priorityGTE10Bitmaps, err := q.EvaluateNumericAttributeValueGreaterOrEqualThan(ctx, store.EvaluateNumericAttributeValueGreaterOrEqualThanParams{
 					Name:  "priority",
-					Value: 10,
+					Value: store.NewNumericValue(10),
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(priorityGTE10Bitmaps).To(HaveLen(2))
@@ -313,10 +317,10 @@ var _ = Describe("SQLiteStore", func() {
 				Expect(row.Payload).To(Equal([]byte(`{"updated": true}`)))
 				Expect(row.ContentType).To(Equal("application/json"))
 				Expect(row.StringAttributes.Values["status"]).To(Equal("published"))
-				Expect(row.NumericAttributes.Values["version"]).To(Equal(uint64(2)))
-				Expect(row.NumericAttributes.Values["$lastModifiedAtBlock"]).To(Equal(uint64(101)))
+				Expect(row.NumericAttributes.Values["version"].Uint64()).To(Equal(uint64(2)))
+				Expect(row.NumericAttributes.Values["$lastModifiedAtBlock"].Uint64()).To(Equal(uint64(101)))
 				// $createdAtBlock should be preserved
-				Expect(row.NumericAttributes.Values["$createdAtBlock"]).To(Equal(uint64(100)))
+				Expect(row.NumericAttributes.Values["$createdAtBlock"].Uint64()).To(Equal(uint64(100)))
 
 				// Verify old bitmap index is removed
 				oldStatusBitmap, err := q.EvaluateStringAttributeValueEqual(ctx, store.EvaluateStringAttributeValueEqualParams{
@@ -558,7 +562,7 @@ var _ = Describe("SQLiteStore", func() {
 			err = sqlStore.ReadTransaction(ctx, func(q *store.Queries) error {
 				row, err := q.GetPayloadForEntityKey(ctx, key.Bytes())
 				Expect(err).NotTo(HaveOccurred())
-				originalExpiration = row.NumericAttributes.Values["$expiration"]
+				originalExpiration = row.NumericAttributes.Values["$expiration"].Uint64()
 				Expect(originalExpiration).To(Equal(uint64(600))) // 100 + 500
 				return nil
 			})
@@ -597,20 +601,20 @@ var _ = Describe("SQLiteStore", func() {
 			err = sqlStore.ReadTransaction(ctx, func(q *store.Queries) error {
 				row, err := q.GetPayloadForEntityKey(ctx, key.Bytes())
 				Expect(err).NotTo(HaveOccurred())
-				newExpiration := row.NumericAttributes.Values["$expiration"]
+				newExpiration := row.NumericAttributes.Values["$expiration"].Uint64()
 				Expect(newExpiration).To(Equal(uint64(1200))) // 200 + 1000
 
 				// Verify old expiration bitmap is removed
 				oldExpBitmap, err := q.EvaluateNumericAttributeValueEqual(ctx, store.EvaluateNumericAttributeValueEqualParams{
 					Name:  "$expiration",
-					Value: 600,
+					Value: store.NewNumericValue(600),
 				})
 				Expect(err).To(HaveOccurred())
 
 				// Verify new expiration bitmap exists
 				newExpBitmap, err := q.EvaluateNumericAttributeValueEqual(ctx, store.EvaluateNumericAttributeValueEqualParams{
 					Name:  "$expiration",
-					Value: 1200,
+					Value: store.NewNumericValue(1200),
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(newExpBitmap.ToArray()).To(HaveLen(1))
@@ -819,7 +823,7 @@ var _ = Describe("SQLiteStore", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(row.Payload).To(Equal([]byte("final update")))
 				Expect(row.StringAttributes.Values["status"]).To(Equal("v3"))
-				Expect(row.NumericAttributes.Values["version"]).To(Equal(uint64(3)))
+				Expect(row.NumericAttributes.Values["version"].Uint64()).To(Equal(uint64(3)))
 				return nil
 			})
 			Expect(err).NotTo(HaveOccurred())
@@ -931,7 +935,7 @@ var _ = Describe("SQLiteStore", func() {
 				// The last update (second one) should be applied
 				Expect(row.Payload).To(Equal([]byte("second update - last one")))
 				Expect(row.StringAttributes.Values["status"]).To(Equal("v2"))
-				Expect(row.NumericAttributes.Values["version"]).To(Equal(uint64(2)))
+				Expect(row.NumericAttributes.Values["version"].Uint64()).To(Equal(uint64(2)))
 				return nil
 			})
 			Expect(err).NotTo(HaveOccurred())
@@ -1086,19 +1090,204 @@ var _ = Describe("SQLiteStore", func() {
 				Expect(row.StringAttributes.Values["$key"]).To(Equal(strings.ToLower(key.Hex())))
 
 				// Numeric attributes
-				Expect(row.NumericAttributes.Values["$expiration"]).To(Equal(uint64(600))) // 100 + 500
-				Expect(row.NumericAttributes.Values["$createdAtBlock"]).To(Equal(uint64(100)))
-				Expect(row.NumericAttributes.Values["$lastModifiedAtBlock"]).To(Equal(uint64(100)))
-				Expect(row.NumericAttributes.Values["$txIndex"]).To(Equal(uint64(5)))
-				Expect(row.NumericAttributes.Values["$opIndex"]).To(Equal(uint64(3)))
+				Expect(row.NumericAttributes.Values["$expiration"].Uint64()).To(Equal(uint64(600))) // 100 + 500
+				Expect(row.NumericAttributes.Values["$createdAtBlock"].Uint64()).To(Equal(uint64(100)))
+				Expect(row.NumericAttributes.Values["$lastModifiedAtBlock"].Uint64()).To(Equal(uint64(100)))
+				Expect(row.NumericAttributes.Values["$txIndex"].Uint64()).To(Equal(uint64(5)))
+				Expect(row.NumericAttributes.Values["$opIndex"].Uint64()).To(Equal(uint64(3)))
 
 				// Verify sequence calculation
 				expectedSequence := uint64(100)<<32 | uint64(5)<<16 | uint64(3)
-				Expect(row.NumericAttributes.Values["$sequence"]).To(Equal(expectedSequence))
+				Expect(row.NumericAttributes.Values["$sequence"].Uint64()).To(Equal(expectedSequence))
 
 				return nil
 			})
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+	Describe("FollowEvents payload attributes", func() {
+		It("should maintain the payload attributes and the version", func() {
+			key := common.BigToHash(big.NewInt(1))
+			update := func(content string) events.Operation {
+				return events.Operation{
+					Update: &events.OPUpdate{
+						Key:               key,
+						ContentType:       "application/json",
+						BTL:               100,
+						Owner:             common.HexToAddress("0x1234567890123456789012345678901234567890"),
+						Content:           []byte(content),
+						StringAttributes:  map[string]string{},
+						NumericAttributes: map[string]uint64{},
+					},
+				}
+			}
+
+			followBlocks(ctx, sqlStore, events.Block{
+				Number:     100,
+				Operations: []events.Operation{createOperation(0, map[string]string{}, map[string]uint64{})},
+			})
+
+			count := func(q string) uint64 {
+				res, err := sqlStore.CountEntities(ctx, q, nil)
+				Expect(err).NotTo(HaveOccurred())
+				return res.Count
+			}
+
+			Expect(count(`$contentType = "text/plain" && $version = 1 && $payloadSize = 7`)).To(Equal(uint64(1)))
+			Expect(count(`$payloadHash = "` + crypto.Keccak256Hash([]byte("content")).Hex() + `"`)).To(Equal(uint64(1)))
+
+			followBlocks(ctx, sqlStore, events.Block{
+				Number:     101,
+				Operations: []events.Operation{update(`{}`), update(`{"a": 1}`)},
+			})
+
+			Expect(count(`$contentType = "text/plain"`)).To(BeZero())
+			Expect(count(`$contentType ~ "application/*" && $version = 3 && $payloadSize > 7`)).To(Equal(uint64(1)))
+
+			extend := events.OPExtendBTL{Key: key, BTL: 1000}
+			changeOwner := events.OPChangeOwner{Key: key, Owner: common.HexToAddress("0x01")}
+			followBlocks(ctx, sqlStore, events.Block{
+				Number:     102,
+				Operations: []events.Operation{{ExtendBTL: &extend}, {ChangeOwner: &changeOwner}},
+			})
+
+			res, err := sqlStore.QueryEntities(ctx, `$version = 3`, &sqlitebitmapstore.Options{
+				IncludeData: &sqlitebitmapstore.IncludeData{
+					ContentType: true,
+					PayloadSize: true,
+					PayloadHash: true,
+					Version:     true,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			entities := decodeEntities(res)
+			Expect(entities).To(HaveLen(1))
+			Expect(*entities[0].ContentType).To(Equal("application/json"))
+			Expect(*entities[0].PayloadSize).To(Equal(uint64(8)))
+			Expect(*entities[0].PayloadHash).To(Equal(crypto.Keccak256Hash([]byte(`{"a": 1}`))))
+			Expect(*entities[0].Version).To(Equal(uint64(3)))
+
+			deleted := events.OPDelete(key)
+			followBlocks(ctx, sqlStore, events.Block{
+				Number:     103,
+				Operations: []events.Operation{{Delete: &deleted}},
+			})
+
+			Expect(count(`$version = 3 || $contentType = "application/json"`)).To(BeZero())
+		})
+
+		It("should backfill the payload attributes of existing databases", func() {
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 100,
+				Operations: []events.Operation{
+					createOperation(0, map[string]string{}, map[string]uint64{}),
+					createOperation(1, map[string]string{}, map[string]uint64{}),
+				},
+			})
+			Expect(sqlStore.Close()).To(Succeed())
+
+			db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "test.db"))
+			Expect(err).NotTo(HaveOccurred())
+			for _, stmt := range []string{
+				`UPDATE payloads SET
+					string_attributes = json_remove(string_attributes, '$.Values."$contentType"', '$.Values."$payloadHash"'),
+					numeric_attributes = json_remove(numeric_attributes, '$.Values."$payloadSize"', '$.Values."$version"')`,
+				`DELETE FROM string_attributes_values_bitmaps WHERE name IN ('$contentType', '$payloadHash')`,
+				`DELETE FROM numeric_attributes_values_bitmaps WHERE name IN ('$payloadSize', '$version')`,
+				`DELETE FROM string_attributes_stats WHERE name IN ('$contentType', '$payloadHash')`,
+				`DELETE FROM numeric_attributes_stats WHERE name IN ('$payloadSize', '$version')`,
+			} {
+				_, err = db.Exec(stmt)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(db.Close()).To(Succeed())
+
+			sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := sqlStore.CountEntities(ctx, `$contentType = "text/plain" && $version = 1 && $payloadSize = 7`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Count).To(Equal(uint64(2)))
+
+			catalog, err := sqlStore.AttributeCatalog(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog).To(HaveKeyWithValue("$version", query.AttributeTypes{NumericEntities: 2}))
+		})
+
+		It("should resume an interrupted backfill", func() {
+			key := common.BigToHash(big.NewInt(2))
+			followBlocks(ctx, sqlStore, events.Block{
+				Number: 100,
+				Operations: []events.Operation{
+					createOperation(0, map[string]string{}, map[string]uint64{}),
+					createOperation(1, map[string]string{}, map[string]uint64{}),
+				},
+			}, events.Block{
+				Number: 101,
+				Operations: []events.Operation{{
+					Update: &events.OPUpdate{
+						Key:               key,
+						ContentType:       "application/json",
+						BTL:               100,
+						Owner:             common.HexToAddress("0x1234567890123456789012345678901234567890"),
+						Content:           []byte(`{}`),
+						StringAttributes:  map[string]string{},
+						NumericAttributes: map[string]uint64{},
+					},
+				}},
+			})
+			Expect(sqlStore.Close()).To(Succeed())
+
+			// the batch with the first entity was committed, the one with the
+			// second, whose payload attribute values only it has, was not
+			db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "test.db"))
+			Expect(err).NotTo(HaveOccurred())
+			for _, stmt := range []struct {
+				query string
+				args  []any
+			}{
+				{
+					query: `DELETE FROM string_attributes_values_bitmaps WHERE name IN ('$contentType', '$payloadHash') AND value IN (?, ?)`,
+					args:  []any{"application/json", strings.ToLower(crypto.Keccak256Hash([]byte(`{}`)).Hex())},
+				},
+				{
+					query: `DELETE FROM numeric_attributes_values_bitmaps WHERE (name = '$payloadSize' AND value = ?) OR (name = '$version' AND value = ?)`,
+					args:  []any{store.NewNumericValue(2), store.NewNumericValue(2)},
+				},
+				{
+					query: `UPDATE payloads SET
+						string_attributes = json_remove(string_attributes, '$.Values."$contentType"', '$.Values."$payloadHash"'),
+						numeric_attributes = json_remove(numeric_attributes, '$.Values."$payloadSize"', '$.Values."$version"')
+					WHERE entity_key = ?`,
+					args: []any{key.Bytes()},
+				},
+				// the statistics are rebuilt from the bitmaps
+				{query: `UPDATE string_attributes_values_bitmaps SET cardinality = NULL`},
+				{query: `UPDATE numeric_attributes_values_bitmaps SET cardinality = NULL`},
+			} {
+				res, err := db.Exec(stmt.query, stmt.args...)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.RowsAffected()).NotTo(BeZero(), stmt.query)
+			}
+			Expect(db.Close()).To(Succeed())
+
+			sqlStore, err = sqlitebitmapstore.NewSQLiteStore(logger, filepath.Join(tmpDir, "test.db"), 4)
+			Expect(err).NotTo(HaveOccurred())
+
+			count := func(q string) uint64 {
+				res, err := sqlStore.CountEntities(ctx, q, nil)
+				Expect(err).NotTo(HaveOccurred())
+				return res.Count
+			}
+
+			Expect(count(`$contentType = "text/plain" && $version = 1 && $payloadSize = 7`)).To(Equal(uint64(1)))
+			// the backfilled entity starts at version 1
+			Expect(count(`$contentType = "application/json" && $version = 1 && $payloadSize = 2`)).To(Equal(uint64(1)))
+
+			catalog, err := sqlStore.AttributeCatalog(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog).To(HaveKeyWithValue("$version", query.AttributeTypes{NumericEntities: 2}))
+			Expect(catalog).To(HaveKeyWithValue("$payloadHash", query.AttributeTypes{StringEntities: 2}))
+		})
+	})
 })
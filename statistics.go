@@ -0,0 +1,183 @@
+package sqlitebitmapstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+// statisticsBackfillBatchSize is the number of bitmaps whose cardinalities
+// are backfilled in a transaction.
+const statisticsBackfillBatchSize = 1000
+
+// backfillStatistics sets the cardinality of the bitmaps written before the
+// store kept statistics, and then rebuilds the attribute statistics from them.
+// Every batch of bitmaps is committed on its own, so the write lock is not
+// held for the whole backfill and an interrupted backfill resumes where it
+// stopped. It does nothing once every bitmap has its cardinality.
+func backfillStatistics(ctx context.Context, db *sql.DB, log *slog.Logger) error {
+	backfilled := 0
+
+	for {
+		n, done, err := backfillStatisticsBatch(ctx, db)
+		if err != nil {
+			return err
+		}
+		backfilled += n
+		if done {
+			break
+		}
+	}
+
+	if backfilled > 0 {
+		log.Info("backfilled bitmap statistics", "bitmaps", backfilled)
+	}
+
+	return nil
+}
+
+// backfillStatisticsBatch sets the cardinality of up to
+// statisticsBackfillBatchSize bitmaps in a transaction, and returns their
+// number and whether the backfill is done. The transaction that sets the last
+// cardinalities also rebuilds the attribute statistics, so that they are
+// rebuilt exactly once every bitmap has its cardinality.
+func backfillStatisticsBatch(ctx context.Context, db *sql.DB) (int, bool, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st := store.New(tx)
+
+	strs, err := st.GetStringAttributeValueBitmapsWithoutCardinality(ctx, statisticsBackfillBatchSize)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get string attribute bitmaps: %w", err)
+	}
+	for _, row := range strs {
+		err = st.SetStringAttributeValueCardinality(ctx, store.SetStringAttributeValueCardinalityParams{
+			Name:        row.Name,
+			Value:       row.Value,
+			Cardinality: row.Bitmap.GetCardinality(),
+		})
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to set string attribute %q value %q cardinality: %w", row.Name, row.Value, err)
+		}
+	}
+
+	backfilled := len(strs)
+
+	if len(strs) == 0 {
+		numerics, err := st.GetNumericAttributeValueBitmapsWithoutCardinality(ctx, statisticsBackfillBatchSize)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get numeric attribute bitmaps: %w", err)
+		}
+		for _, row := range numerics {
+			err = st.SetNumericAttributeValueCardinality(ctx, store.SetNumericAttributeValueCardinalityParams{
+				Name:        row.Name,
+				Value:       row.Value,
+				Cardinality: row.Bitmap.GetCardinality(),
+			})
+			if err != nil {
+				return 0, false, fmt.Errorf("failed to set numeric attribute %q value %q cardinality: %w", row.Name, row.Value, err)
+			}
+		}
+		backfilled = len(numerics)
+	}
+
+	if backfilled == 0 {
+		return 0, true, nil
+	}
+
+	missing, err := st.HasAttributeValueBitmapsWithoutCardinality(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to check for bitmaps without cardinality: %w", err)
+	}
+
+	if !missing.Bool {
+		err = st.RebuildStringAttributeStats(ctx)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to rebuild string attribute stats: %w", err)
+		}
+
+		err = st.RebuildNumericAttributeStats(ctx)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to rebuild numeric attribute stats: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return backfilled, !missing.Bool, nil
+}
+
+// AttributeCatalog returns the attributes that entities have, with the number
+// of entities that have a string and a numeric value for each. It is read
+// from the attribute statistics, which FollowEvents keeps up to date.
+func (s *SQLiteStore) AttributeCatalog(ctx context.Context) (query.Catalog, error) {
+	q := s.NewQueries()
+
+	catalog := query.Catalog{}
+
+	strs, err := q.ListStringAttributeStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list string attributes: %w", err)
+	}
+	for _, stat := range strs {
+		types := catalog[stat.Name]
+		types.StringEntities = stat.Cardinality
+		catalog[stat.Name] = types
+	}
+
+	numerics, err := q.ListNumericAttributeStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list numeric attributes: %w", err)
+	}
+	for _, stat := range numerics {
+		types := catalog[stat.Name]
+		types.NumericEntities = stat.Cardinality
+		catalog[stat.Name] = types
+	}
+
+	return catalog, nil
+}
+
+// attributeCatalog returns the part of the attribute catalog with the given
+// names.
+func attributeCatalog(ctx context.Context, q *store.Queries, names []string) (query.Catalog, error) {
+	catalog := query.Catalog{}
+
+	for _, name := range names {
+		var types query.AttributeTypes
+
+		str, err := q.GetStringAttributeStats(ctx, name)
+		switch {
+		case err == nil:
+			types.StringEntities = str.Cardinality
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+
+		numeric, err := q.GetNumericAttributeStats(ctx, name)
+		switch {
+		case err == nil:
+			types.NumericEntities = numeric.Cardinality
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+
+		if types.StringEntities != 0 || types.NumericEntities != 0 {
+			catalog[name] = types
+		}
+	}
+
+	return catalog, nil
+}
@@ -0,0 +1,114 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: catalog.sql
+
+package store
+
+import (
+	"context"
+)
+
+const listNumericAttributeValues = `-- name: ListNumericAttributeValues :many
+SELECT value, cardinality FROM numeric_attributes_values_bitmaps
+WHERE name = ?1
+    AND (CAST(?2 AS BOOLEAN) = 0 OR value > ?3)
+ORDER BY value
+LIMIT ?4
+`
+
+type ListNumericAttributeValuesParams struct {
+	Name      string
+	HasAfter  bool
+	After     NumericValue
+	MaxValues int64
+}
+
+type ListNumericAttributeValuesRow struct {
+	Value       NumericValue
+	Cardinality uint64
+}
+
+func (q *Queries) ListNumericAttributeValues(ctx context.Context, arg ListNumericAttributeValuesParams) ([]ListNumericAttributeValuesRow, error) {
+	rows, err := q.query(ctx, q.listNumericAttributeValuesStmt, listNumericAttributeValues,
+		arg.Name,
+		arg.HasAfter,
+		arg.After,
+		arg.MaxValues,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListNumericAttributeValuesRow{}
+	for rows.Next() {
+		var i ListNumericAttributeValuesRow
+		if err := rows.Scan(&i.Value, &i.Cardinality); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStringAttributeValues = `-- name: ListStringAttributeValues :many
+SELECT value, cardinality FROM string_attributes_values_bitmaps
+WHERE name = ?1
+    AND value >= ?2
+    AND (CAST(?3 AS BOOLEAN) = 0 OR value < ?4)
+    AND (CAST(?5 AS BOOLEAN) = 0 OR value > ?6)
+ORDER BY value
+LIMIT ?7
+`
+
+type ListStringAttributeValuesParams struct {
+	Name                string
+	Prefix              string
+	HasPrefixUpperBound bool
+	PrefixUpperBound    string
+	HasAfter            bool
+	After               string
+	MaxValues           int64
+}
+
+type ListStringAttributeValuesRow struct {
+	Value       string
+	Cardinality uint64
+}
+
+func (q *Queries) ListStringAttributeValues(ctx context.Context, arg ListStringAttributeValuesParams) ([]ListStringAttributeValuesRow, error) {
+	rows, err := q.query(ctx, q.listStringAttributeValuesStmt, listStringAttributeValues,
+		arg.Name,
+		arg.Prefix,
+		arg.HasPrefixUpperBound,
+		arg.PrefixUpperBound,
+		arg.HasAfter,
+		arg.After,
+		arg.MaxValues,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStringAttributeValuesRow{}
+	for rows.Next() {
+		var i ListStringAttributeValuesRow
+		if err := rows.Scan(&i.Value, &i.Cardinality); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
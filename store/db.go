@@ -33,9 +33,57 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteStringAttributeValueBitmapStmt, err = db.PrepareContext(ctx, deleteStringAttributeValueBitmap); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteStringAttributeValueBitmap: %w", err)
 	}
+	if q.estimateNumericAttributeValueBetweenStmt, err = db.PrepareContext(ctx, estimateNumericAttributeValueBetween); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateNumericAttributeValueBetween: %w", err)
+	}
+	if q.estimateNumericAttributeValueGreaterOrEqualThanStmt, err = db.PrepareContext(ctx, estimateNumericAttributeValueGreaterOrEqualThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateNumericAttributeValueGreaterOrEqualThan: %w", err)
+	}
+	if q.estimateNumericAttributeValueGreaterThanStmt, err = db.PrepareContext(ctx, estimateNumericAttributeValueGreaterThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateNumericAttributeValueGreaterThan: %w", err)
+	}
+	if q.estimateNumericAttributeValueInclusionStmt, err = db.PrepareContext(ctx, estimateNumericAttributeValueInclusion); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateNumericAttributeValueInclusion: %w", err)
+	}
+	if q.estimateNumericAttributeValueLessOrEqualThanStmt, err = db.PrepareContext(ctx, estimateNumericAttributeValueLessOrEqualThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateNumericAttributeValueLessOrEqualThan: %w", err)
+	}
+	if q.estimateNumericAttributeValueLowerThanStmt, err = db.PrepareContext(ctx, estimateNumericAttributeValueLowerThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateNumericAttributeValueLowerThan: %w", err)
+	}
+	if q.estimateStringAttributeValueBetweenStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueBetween); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueBetween: %w", err)
+	}
+	if q.estimateStringAttributeValueGlobStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueGlob); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueGlob: %w", err)
+	}
+	if q.estimateStringAttributeValueGreaterOrEqualThanStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueGreaterOrEqualThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueGreaterOrEqualThan: %w", err)
+	}
+	if q.estimateStringAttributeValueGreaterThanStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueGreaterThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueGreaterThan: %w", err)
+	}
+	if q.estimateStringAttributeValueInclusionStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueInclusion); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueInclusion: %w", err)
+	}
+	if q.estimateStringAttributeValueLessOrEqualThanStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueLessOrEqualThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueLessOrEqualThan: %w", err)
+	}
+	if q.estimateStringAttributeValueLowerThanStmt, err = db.PrepareContext(ctx, estimateStringAttributeValueLowerThan); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateStringAttributeValueLowerThan: %w", err)
+	}
 	if q.evaluateAllStmt, err = db.PrepareContext(ctx, evaluateAll); err != nil {
 		return nil, fmt.Errorf("error preparing query EvaluateAll: %w", err)
 	}
+	if q.evaluateEntityKeyInclusionStmt, err = db.PrepareContext(ctx, evaluateEntityKeyInclusion); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateEntityKeyInclusion: %w", err)
+	}
+	if q.evaluateNumericAttributeStmt, err = db.PrepareContext(ctx, evaluateNumericAttribute); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateNumericAttribute: %w", err)
+	}
+	if q.evaluateNumericAttributeValueBetweenStmt, err = db.PrepareContext(ctx, evaluateNumericAttributeValueBetween); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateNumericAttributeValueBetween: %w", err)
+	}
 	if q.evaluateNumericAttributeValueEqualStmt, err = db.PrepareContext(ctx, evaluateNumericAttributeValueEqual); err != nil {
 		return nil, fmt.Errorf("error preparing query EvaluateNumericAttributeValueEqual: %w", err)
 	}
@@ -60,6 +108,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.evaluateNumericAttributeValueNotInclusionStmt, err = db.PrepareContext(ctx, evaluateNumericAttributeValueNotInclusion); err != nil {
 		return nil, fmt.Errorf("error preparing query EvaluateNumericAttributeValueNotInclusion: %w", err)
 	}
+	if q.evaluateNumericAttributesEqualStmt, err = db.PrepareContext(ctx, evaluateNumericAttributesEqual); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateNumericAttributesEqual: %w", err)
+	}
+	if q.evaluateStringAttributeStmt, err = db.PrepareContext(ctx, evaluateStringAttribute); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateStringAttribute: %w", err)
+	}
+	if q.evaluateStringAttributeValueBetweenStmt, err = db.PrepareContext(ctx, evaluateStringAttributeValueBetween); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateStringAttributeValueBetween: %w", err)
+	}
 	if q.evaluateStringAttributeValueEqualStmt, err = db.PrepareContext(ctx, evaluateStringAttributeValueEqual); err != nil {
 		return nil, fmt.Errorf("error preparing query EvaluateStringAttributeValueEqual: %w", err)
 	}
@@ -90,24 +147,96 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.evaluateStringAttributeValueNotInclusionStmt, err = db.PrepareContext(ctx, evaluateStringAttributeValueNotInclusion); err != nil {
 		return nil, fmt.Errorf("error preparing query EvaluateStringAttributeValueNotInclusion: %w", err)
 	}
+	if q.evaluateStringAttributeValueNotRangeStmt, err = db.PrepareContext(ctx, evaluateStringAttributeValueNotRange); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateStringAttributeValueNotRange: %w", err)
+	}
+	if q.evaluateStringAttributeValueRangeStmt, err = db.PrepareContext(ctx, evaluateStringAttributeValueRange); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateStringAttributeValueRange: %w", err)
+	}
+	if q.evaluateStringAttributesEqualStmt, err = db.PrepareContext(ctx, evaluateStringAttributesEqual); err != nil {
+		return nil, fmt.Errorf("error preparing query EvaluateStringAttributesEqual: %w", err)
+	}
+	if q.getEntityKeysStmt, err = db.PrepareContext(ctx, getEntityKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query GetEntityKeys: %w", err)
+	}
 	if q.getLastBlockStmt, err = db.PrepareContext(ctx, getLastBlock); err != nil {
 		return nil, fmt.Errorf("error preparing query GetLastBlock: %w", err)
 	}
 	if q.getNumberOfEntitiesStmt, err = db.PrepareContext(ctx, getNumberOfEntities); err != nil {
 		return nil, fmt.Errorf("error preparing query GetNumberOfEntities: %w", err)
 	}
+	if q.getNumericAttributeStatsStmt, err = db.PrepareContext(ctx, getNumericAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNumericAttributeStats: %w", err)
+	}
 	if q.getNumericAttributeValueBitmapStmt, err = db.PrepareContext(ctx, getNumericAttributeValueBitmap); err != nil {
 		return nil, fmt.Errorf("error preparing query GetNumericAttributeValueBitmap: %w", err)
 	}
+	if q.getNumericAttributeValueBitmapsStmt, err = db.PrepareContext(ctx, getNumericAttributeValueBitmaps); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNumericAttributeValueBitmaps: %w", err)
+	}
+	if q.getNumericAttributeValueBitmapsWithoutCardinalityStmt, err = db.PrepareContext(ctx, getNumericAttributeValueBitmapsWithoutCardinality); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNumericAttributeValueBitmapsWithoutCardinality: %w", err)
+	}
 	if q.getPayloadForEntityKeyStmt, err = db.PrepareContext(ctx, getPayloadForEntityKey); err != nil {
 		return nil, fmt.Errorf("error preparing query GetPayloadForEntityKey: %w", err)
 	}
+	if q.getPayloadsWithoutVersionStmt, err = db.PrepareContext(ctx, getPayloadsWithoutVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPayloadsWithoutVersion: %w", err)
+	}
+	if q.getStringAttributeStatsStmt, err = db.PrepareContext(ctx, getStringAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStringAttributeStats: %w", err)
+	}
 	if q.getStringAttributeValueBitmapStmt, err = db.PrepareContext(ctx, getStringAttributeValueBitmap); err != nil {
 		return nil, fmt.Errorf("error preparing query GetStringAttributeValueBitmap: %w", err)
 	}
+	if q.getStringAttributeValueBitmapsWithoutCardinalityStmt, err = db.PrepareContext(ctx, getStringAttributeValueBitmapsWithoutCardinality); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStringAttributeValueBitmapsWithoutCardinality: %w", err)
+	}
+	if q.getStringAttributeValuesStmt, err = db.PrepareContext(ctx, getStringAttributeValues); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStringAttributeValues: %w", err)
+	}
+	if q.hasAttributeValueBitmapsWithoutCardinalityStmt, err = db.PrepareContext(ctx, hasAttributeValueBitmapsWithoutCardinality); err != nil {
+		return nil, fmt.Errorf("error preparing query HasAttributeValueBitmapsWithoutCardinality: %w", err)
+	}
+	if q.listNumericAttributeStatsStmt, err = db.PrepareContext(ctx, listNumericAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query ListNumericAttributeStats: %w", err)
+	}
+	if q.listNumericAttributeValuesStmt, err = db.PrepareContext(ctx, listNumericAttributeValues); err != nil {
+		return nil, fmt.Errorf("error preparing query ListNumericAttributeValues: %w", err)
+	}
+	if q.listStringAttributeStatsStmt, err = db.PrepareContext(ctx, listStringAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query ListStringAttributeStats: %w", err)
+	}
+	if q.listStringAttributeValuesStmt, err = db.PrepareContext(ctx, listStringAttributeValues); err != nil {
+		return nil, fmt.Errorf("error preparing query ListStringAttributeValues: %w", err)
+	}
+	if q.rebuildNumericAttributeStatsStmt, err = db.PrepareContext(ctx, rebuildNumericAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query RebuildNumericAttributeStats: %w", err)
+	}
+	if q.rebuildStringAttributeStatsStmt, err = db.PrepareContext(ctx, rebuildStringAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query RebuildStringAttributeStats: %w", err)
+	}
 	if q.retrievePayloadsStmt, err = db.PrepareContext(ctx, retrievePayloads); err != nil {
 		return nil, fmt.Errorf("error preparing query RetrievePayloads: %w", err)
 	}
+	if q.scanAttributesStmt, err = db.PrepareContext(ctx, scanAttributes); err != nil {
+		return nil, fmt.Errorf("error preparing query ScanAttributes: %w", err)
+	}
+	if q.setNumericAttributeValueCardinalityStmt, err = db.PrepareContext(ctx, setNumericAttributeValueCardinality); err != nil {
+		return nil, fmt.Errorf("error preparing query SetNumericAttributeValueCardinality: %w", err)
+	}
+	if q.setStringAttributeValueCardinalityStmt, err = db.PrepareContext(ctx, setStringAttributeValueCardinality); err != nil {
+		return nil, fmt.Errorf("error preparing query SetStringAttributeValueCardinality: %w", err)
+	}
+	if q.updateNumericAttributeStatsStmt, err = db.PrepareContext(ctx, updateNumericAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateNumericAttributeStats: %w", err)
+	}
+	if q.updatePayloadAttributesStmt, err = db.PrepareContext(ctx, updatePayloadAttributes); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdatePayloadAttributes: %w", err)
+	}
+	if q.updateStringAttributeStatsStmt, err = db.PrepareContext(ctx, updateStringAttributeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateStringAttributeStats: %w", err)
+	}
 	if q.upsertLastBlockStmt, err = db.PrepareContext(ctx, upsertLastBlock); err != nil {
 		return nil, fmt.Errorf("error preparing query UpsertLastBlock: %w", err)
 	}
@@ -140,11 +269,91 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteStringAttributeValueBitmapStmt: %w", cerr)
 		}
 	}
+	if q.estimateNumericAttributeValueBetweenStmt != nil {
+		if cerr := q.estimateNumericAttributeValueBetweenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateNumericAttributeValueBetweenStmt: %w", cerr)
+		}
+	}
+	if q.estimateNumericAttributeValueGreaterOrEqualThanStmt != nil {
+		if cerr := q.estimateNumericAttributeValueGreaterOrEqualThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateNumericAttributeValueGreaterOrEqualThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateNumericAttributeValueGreaterThanStmt != nil {
+		if cerr := q.estimateNumericAttributeValueGreaterThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateNumericAttributeValueGreaterThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateNumericAttributeValueInclusionStmt != nil {
+		if cerr := q.estimateNumericAttributeValueInclusionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateNumericAttributeValueInclusionStmt: %w", cerr)
+		}
+	}
+	if q.estimateNumericAttributeValueLessOrEqualThanStmt != nil {
+		if cerr := q.estimateNumericAttributeValueLessOrEqualThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateNumericAttributeValueLessOrEqualThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateNumericAttributeValueLowerThanStmt != nil {
+		if cerr := q.estimateNumericAttributeValueLowerThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateNumericAttributeValueLowerThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueBetweenStmt != nil {
+		if cerr := q.estimateStringAttributeValueBetweenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueBetweenStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueGlobStmt != nil {
+		if cerr := q.estimateStringAttributeValueGlobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueGlobStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueGreaterOrEqualThanStmt != nil {
+		if cerr := q.estimateStringAttributeValueGreaterOrEqualThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueGreaterOrEqualThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueGreaterThanStmt != nil {
+		if cerr := q.estimateStringAttributeValueGreaterThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueGreaterThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueInclusionStmt != nil {
+		if cerr := q.estimateStringAttributeValueInclusionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueInclusionStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueLessOrEqualThanStmt != nil {
+		if cerr := q.estimateStringAttributeValueLessOrEqualThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueLessOrEqualThanStmt: %w", cerr)
+		}
+	}
+	if q.estimateStringAttributeValueLowerThanStmt != nil {
+		if cerr := q.estimateStringAttributeValueLowerThanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateStringAttributeValueLowerThanStmt: %w", cerr)
+		}
+	}
 	if q.evaluateAllStmt != nil {
 		if cerr := q.evaluateAllStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing evaluateAllStmt: %w", cerr)
 		}
 	}
+	if q.evaluateEntityKeyInclusionStmt != nil {
+		if cerr := q.evaluateEntityKeyInclusionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateEntityKeyInclusionStmt: %w", cerr)
+		}
+	}
+	if q.evaluateNumericAttributeStmt != nil {
+		if cerr := q.evaluateNumericAttributeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateNumericAttributeStmt: %w", cerr)
+		}
+	}
+	if q.evaluateNumericAttributeValueBetweenStmt != nil {
+		if cerr := q.evaluateNumericAttributeValueBetweenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateNumericAttributeValueBetweenStmt: %w", cerr)
+		}
+	}
 	if q.evaluateNumericAttributeValueEqualStmt != nil {
 		if cerr := q.evaluateNumericAttributeValueEqualStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing evaluateNumericAttributeValueEqualStmt: %w", cerr)
@@ -185,6 +394,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing evaluateNumericAttributeValueNotInclusionStmt: %w", cerr)
 		}
 	}
+	if q.evaluateNumericAttributesEqualStmt != nil {
+		if cerr := q.evaluateNumericAttributesEqualStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateNumericAttributesEqualStmt: %w", cerr)
+		}
+	}
+	if q.evaluateStringAttributeStmt != nil {
+		if cerr := q.evaluateStringAttributeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateStringAttributeStmt: %w", cerr)
+		}
+	}
+	if q.evaluateStringAttributeValueBetweenStmt != nil {
+		if cerr := q.evaluateStringAttributeValueBetweenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateStringAttributeValueBetweenStmt: %w", cerr)
+		}
+	}
 	if q.evaluateStringAttributeValueEqualStmt != nil {
 		if cerr := q.evaluateStringAttributeValueEqualStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing evaluateStringAttributeValueEqualStmt: %w", cerr)
@@ -235,6 +459,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing evaluateStringAttributeValueNotInclusionStmt: %w", cerr)
 		}
 	}
+	if q.evaluateStringAttributeValueNotRangeStmt != nil {
+		if cerr := q.evaluateStringAttributeValueNotRangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateStringAttributeValueNotRangeStmt: %w", cerr)
+		}
+	}
+	if q.evaluateStringAttributeValueRangeStmt != nil {
+		if cerr := q.evaluateStringAttributeValueRangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateStringAttributeValueRangeStmt: %w", cerr)
+		}
+	}
+	if q.evaluateStringAttributesEqualStmt != nil {
+		if cerr := q.evaluateStringAttributesEqualStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing evaluateStringAttributesEqualStmt: %w", cerr)
+		}
+	}
+	if q.getEntityKeysStmt != nil {
+		if cerr := q.getEntityKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getEntityKeysStmt: %w", cerr)
+		}
+	}
 	if q.getLastBlockStmt != nil {
 		if cerr := q.getLastBlockStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getLastBlockStmt: %w", cerr)
@@ -245,26 +489,126 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getNumberOfEntitiesStmt: %w", cerr)
 		}
 	}
+	if q.getNumericAttributeStatsStmt != nil {
+		if cerr := q.getNumericAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNumericAttributeStatsStmt: %w", cerr)
+		}
+	}
 	if q.getNumericAttributeValueBitmapStmt != nil {
 		if cerr := q.getNumericAttributeValueBitmapStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getNumericAttributeValueBitmapStmt: %w", cerr)
 		}
 	}
+	if q.getNumericAttributeValueBitmapsStmt != nil {
+		if cerr := q.getNumericAttributeValueBitmapsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNumericAttributeValueBitmapsStmt: %w", cerr)
+		}
+	}
+	if q.getNumericAttributeValueBitmapsWithoutCardinalityStmt != nil {
+		if cerr := q.getNumericAttributeValueBitmapsWithoutCardinalityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNumericAttributeValueBitmapsWithoutCardinalityStmt: %w", cerr)
+		}
+	}
 	if q.getPayloadForEntityKeyStmt != nil {
 		if cerr := q.getPayloadForEntityKeyStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getPayloadForEntityKeyStmt: %w", cerr)
 		}
 	}
+	if q.getPayloadsWithoutVersionStmt != nil {
+		if cerr := q.getPayloadsWithoutVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPayloadsWithoutVersionStmt: %w", cerr)
+		}
+	}
+	if q.getStringAttributeStatsStmt != nil {
+		if cerr := q.getStringAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStringAttributeStatsStmt: %w", cerr)
+		}
+	}
 	if q.getStringAttributeValueBitmapStmt != nil {
 		if cerr := q.getStringAttributeValueBitmapStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getStringAttributeValueBitmapStmt: %w", cerr)
 		}
 	}
+	if q.getStringAttributeValueBitmapsWithoutCardinalityStmt != nil {
+		if cerr := q.getStringAttributeValueBitmapsWithoutCardinalityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStringAttributeValueBitmapsWithoutCardinalityStmt: %w", cerr)
+		}
+	}
+	if q.getStringAttributeValuesStmt != nil {
+		if cerr := q.getStringAttributeValuesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStringAttributeValuesStmt: %w", cerr)
+		}
+	}
+	if q.hasAttributeValueBitmapsWithoutCardinalityStmt != nil {
+		if cerr := q.hasAttributeValueBitmapsWithoutCardinalityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing hasAttributeValueBitmapsWithoutCardinalityStmt: %w", cerr)
+		}
+	}
+	if q.listNumericAttributeStatsStmt != nil {
+		if cerr := q.listNumericAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listNumericAttributeStatsStmt: %w", cerr)
+		}
+	}
+	if q.listNumericAttributeValuesStmt != nil {
+		if cerr := q.listNumericAttributeValuesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listNumericAttributeValuesStmt: %w", cerr)
+		}
+	}
+	if q.listStringAttributeStatsStmt != nil {
+		if cerr := q.listStringAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listStringAttributeStatsStmt: %w", cerr)
+		}
+	}
+	if q.listStringAttributeValuesStmt != nil {
+		if cerr := q.listStringAttributeValuesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listStringAttributeValuesStmt: %w", cerr)
+		}
+	}
+	if q.rebuildNumericAttributeStatsStmt != nil {
+		if cerr := q.rebuildNumericAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rebuildNumericAttributeStatsStmt: %w", cerr)
+		}
+	}
+	if q.rebuildStringAttributeStatsStmt != nil {
+		if cerr := q.rebuildStringAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rebuildStringAttributeStatsStmt: %w", cerr)
+		}
+	}
 	if q.retrievePayloadsStmt != nil {
 		if cerr := q.retrievePayloadsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing retrievePayloadsStmt: %w", cerr)
 		}
 	}
+	if q.scanAttributesStmt != nil {
+		if cerr := q.scanAttributesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing scanAttributesStmt: %w", cerr)
+		}
+	}
+	if q.setNumericAttributeValueCardinalityStmt != nil {
+		if cerr := q.setNumericAttributeValueCardinalityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setNumericAttributeValueCardinalityStmt: %w", cerr)
+		}
+	}
+	if q.setStringAttributeValueCardinalityStmt != nil {
+		if cerr := q.setStringAttributeValueCardinalityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setStringAttributeValueCardinalityStmt: %w", cerr)
+		}
+	}
+	if q.updateNumericAttributeStatsStmt != nil {
+		if cerr := q.updateNumericAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateNumericAttributeStatsStmt: %w", cerr)
+		}
+	}
+	if q.updatePayloadAttributesStmt != nil {
+		if cerr := q.updatePayloadAttributesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updatePayloadAttributesStmt: %w", cerr)
+		}
+	}
+	if q.updateStringAttributeStatsStmt != nil {
+		if cerr := q.updateStringAttributeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateStringAttributeStatsStmt: %w", cerr)
+		}
+	}
 	if q.upsertLastBlockStmt != nil {
 		if cerr := q.upsertLastBlockStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing upsertLastBlockStmt: %w", cerr)
@@ -322,77 +666,163 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                                                  DBTX
-	tx                                                  *sql.Tx
-	deleteNumericAttributeValueBitmapStmt               *sql.Stmt
-	deletePayloadForEntityKeyStmt                       *sql.Stmt
-	deleteStringAttributeValueBitmapStmt                *sql.Stmt
-	evaluateAllStmt                                     *sql.Stmt
-	evaluateNumericAttributeValueEqualStmt              *sql.Stmt
-	evaluateNumericAttributeValueGreaterOrEqualThanStmt *sql.Stmt
-	evaluateNumericAttributeValueGreaterThanStmt        *sql.Stmt
-	evaluateNumericAttributeValueInclusionStmt          *sql.Stmt
-	evaluateNumericAttributeValueLessOrEqualThanStmt    *sql.Stmt
-	evaluateNumericAttributeValueLowerThanStmt          *sql.Stmt
-	evaluateNumericAttributeValueNotEqualStmt           *sql.Stmt
-	evaluateNumericAttributeValueNotInclusionStmt       *sql.Stmt
-	evaluateStringAttributeValueEqualStmt               *sql.Stmt
-	evaluateStringAttributeValueGlobStmt                *sql.Stmt
-	evaluateStringAttributeValueGreaterOrEqualThanStmt  *sql.Stmt
-	evaluateStringAttributeValueGreaterThanStmt         *sql.Stmt
-	evaluateStringAttributeValueInclusionStmt           *sql.Stmt
-	evaluateStringAttributeValueLessOrEqualThanStmt     *sql.Stmt
-	evaluateStringAttributeValueLowerThanStmt           *sql.Stmt
-	evaluateStringAttributeValueNotEqualStmt            *sql.Stmt
-	evaluateStringAttributeValueNotGlobStmt             *sql.Stmt
-	evaluateStringAttributeValueNotInclusionStmt        *sql.Stmt
-	getLastBlockStmt                                    *sql.Stmt
-	getNumberOfEntitiesStmt                             *sql.Stmt
-	getNumericAttributeValueBitmapStmt                  *sql.Stmt
-	getPayloadForEntityKeyStmt                          *sql.Stmt
-	getStringAttributeValueBitmapStmt                   *sql.Stmt
-	retrievePayloadsStmt                                *sql.Stmt
-	upsertLastBlockStmt                                 *sql.Stmt
-	upsertNumericAttributeValueBitmapStmt               *sql.Stmt
-	upsertPayloadStmt                                   *sql.Stmt
-	upsertStringAttributeValueBitmapStmt                *sql.Stmt
+	db                                                    DBTX
+	tx                                                    *sql.Tx
+	deleteNumericAttributeValueBitmapStmt                 *sql.Stmt
+	deletePayloadForEntityKeyStmt                         *sql.Stmt
+	deleteStringAttributeValueBitmapStmt                  *sql.Stmt
+	estimateNumericAttributeValueBetweenStmt              *sql.Stmt
+	estimateNumericAttributeValueGreaterOrEqualThanStmt   *sql.Stmt
+	estimateNumericAttributeValueGreaterThanStmt          *sql.Stmt
+	estimateNumericAttributeValueInclusionStmt            *sql.Stmt
+	estimateNumericAttributeValueLessOrEqualThanStmt      *sql.Stmt
+	estimateNumericAttributeValueLowerThanStmt            *sql.Stmt
+	estimateStringAttributeValueBetweenStmt               *sql.Stmt
+	estimateStringAttributeValueGlobStmt                  *sql.Stmt
+	estimateStringAttributeValueGreaterOrEqualThanStmt    *sql.Stmt
+	estimateStringAttributeValueGreaterThanStmt           *sql.Stmt
+	estimateStringAttributeValueInclusionStmt             *sql.Stmt
+	estimateStringAttributeValueLessOrEqualThanStmt       *sql.Stmt
+	estimateStringAttributeValueLowerThanStmt             *sql.Stmt
+	evaluateAllStmt                                       *sql.Stmt
+	evaluateEntityKeyInclusionStmt                        *sql.Stmt
+	evaluateNumericAttributeStmt                          *sql.Stmt
+	evaluateNumericAttributeValueBetweenStmt              *sql.Stmt
+	evaluateNumericAttributeValueEqualStmt                *sql.Stmt
+	evaluateNumericAttributeValueGreaterOrEqualThanStmt   *sql.Stmt
+	evaluateNumericAttributeValueGreaterThanStmt          *sql.Stmt
+	evaluateNumericAttributeValueInclusionStmt            *sql.Stmt
+	evaluateNumericAttributeValueLessOrEqualThanStmt      *sql.Stmt
+	evaluateNumericAttributeValueLowerThanStmt            *sql.Stmt
+	evaluateNumericAttributeValueNotEqualStmt             *sql.Stmt
+	evaluateNumericAttributeValueNotInclusionStmt         *sql.Stmt
+	evaluateNumericAttributesEqualStmt                    *sql.Stmt
+	evaluateStringAttributeStmt                           *sql.Stmt
+	evaluateStringAttributeValueBetweenStmt               *sql.Stmt
+	evaluateStringAttributeValueEqualStmt                 *sql.Stmt
+	evaluateStringAttributeValueGlobStmt                  *sql.Stmt
+	evaluateStringAttributeValueGreaterOrEqualThanStmt    *sql.Stmt
+	evaluateStringAttributeValueGreaterThanStmt           *sql.Stmt
+	evaluateStringAttributeValueInclusionStmt             *sql.Stmt
+	evaluateStringAttributeValueLessOrEqualThanStmt       *sql.Stmt
+	evaluateStringAttributeValueLowerThanStmt             *sql.Stmt
+	evaluateStringAttributeValueNotEqualStmt              *sql.Stmt
+	evaluateStringAttributeValueNotGlobStmt               *sql.Stmt
+	evaluateStringAttributeValueNotInclusionStmt          *sql.Stmt
+	evaluateStringAttributeValueNotRangeStmt              *sql.Stmt
+	evaluateStringAttributeValueRangeStmt                 *sql.Stmt
+	evaluateStringAttributesEqualStmt                     *sql.Stmt
+	getEntityKeysStmt                                     *sql.Stmt
+	getLastBlockStmt                                      *sql.Stmt
+	getNumberOfEntitiesStmt                               *sql.Stmt
+	getNumericAttributeStatsStmt                          *sql.Stmt
+	getNumericAttributeValueBitmapStmt                    *sql.Stmt
+	getNumericAttributeValueBitmapsStmt                   *sql.Stmt
+	getNumericAttributeValueBitmapsWithoutCardinalityStmt *sql.Stmt
+	getPayloadForEntityKeyStmt                            *sql.Stmt
+	getPayloadsWithoutVersionStmt                         *sql.Stmt
+	getStringAttributeStatsStmt                           *sql.Stmt
+	getStringAttributeValueBitmapStmt                     *sql.Stmt
+	getStringAttributeValueBitmapsWithoutCardinalityStmt  *sql.Stmt
+	getStringAttributeValuesStmt                          *sql.Stmt
+	hasAttributeValueBitmapsWithoutCardinalityStmt        *sql.Stmt
+	listNumericAttributeStatsStmt                         *sql.Stmt
+	listNumericAttributeValuesStmt                        *sql.Stmt
+	listStringAttributeStatsStmt                          *sql.Stmt
+	listStringAttributeValuesStmt                         *sql.Stmt
+	rebuildNumericAttributeStatsStmt                      *sql.Stmt
+	rebuildStringAttributeStatsStmt                       *sql.Stmt
+	retrievePayloadsStmt                                  *sql.Stmt
+	scanAttributesStmt                                    *sql.Stmt
+	setNumericAttributeValueCardinalityStmt               *sql.Stmt
+	setStringAttributeValueCardinalityStmt                *sql.Stmt
+	updateNumericAttributeStatsStmt                       *sql.Stmt
+	updatePayloadAttributesStmt                           *sql.Stmt
+	updateStringAttributeStatsStmt                        *sql.Stmt
+	upsertLastBlockStmt                                   *sql.Stmt
+	upsertNumericAttributeValueBitmapStmt                 *sql.Stmt
+	upsertPayloadStmt                                     *sql.Stmt
+	upsertStringAttributeValueBitmapStmt                  *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                                     tx,
-		tx:                                     tx,
-		deleteNumericAttributeValueBitmapStmt:  q.deleteNumericAttributeValueBitmapStmt,
-		deletePayloadForEntityKeyStmt:          q.deletePayloadForEntityKeyStmt,
-		deleteStringAttributeValueBitmapStmt:   q.deleteStringAttributeValueBitmapStmt,
-		evaluateAllStmt:                        q.evaluateAllStmt,
-		evaluateNumericAttributeValueEqualStmt: q.evaluateNumericAttributeValueEqualStmt,
-		evaluateNumericAttributeValueGreaterOrEqualThanStmt: q.evaluateNumericAttributeValueGreaterOrEqualThanStmt,
-		evaluateNumericAttributeValueGreaterThanStmt:        q.evaluateNumericAttributeValueGreaterThanStmt,
-		evaluateNumericAttributeValueInclusionStmt:          q.evaluateNumericAttributeValueInclusionStmt,
-		evaluateNumericAttributeValueLessOrEqualThanStmt:    q.evaluateNumericAttributeValueLessOrEqualThanStmt,
-		evaluateNumericAttributeValueLowerThanStmt:          q.evaluateNumericAttributeValueLowerThanStmt,
-		evaluateNumericAttributeValueNotEqualStmt:           q.evaluateNumericAttributeValueNotEqualStmt,
-		evaluateNumericAttributeValueNotInclusionStmt:       q.evaluateNumericAttributeValueNotInclusionStmt,
-		evaluateStringAttributeValueEqualStmt:               q.evaluateStringAttributeValueEqualStmt,
-		evaluateStringAttributeValueGlobStmt:                q.evaluateStringAttributeValueGlobStmt,
-		evaluateStringAttributeValueGreaterOrEqualThanStmt:  q.evaluateStringAttributeValueGreaterOrEqualThanStmt,
-		evaluateStringAttributeValueGreaterThanStmt:         q.evaluateStringAttributeValueGreaterThanStmt,
-		evaluateStringAttributeValueInclusionStmt:           q.evaluateStringAttributeValueInclusionStmt,
-		evaluateStringAttributeValueLessOrEqualThanStmt:     q.evaluateStringAttributeValueLessOrEqualThanStmt,
-		evaluateStringAttributeValueLowerThanStmt:           q.evaluateStringAttributeValueLowerThanStmt,
-		evaluateStringAttributeValueNotEqualStmt:            q.evaluateStringAttributeValueNotEqualStmt,
-		evaluateStringAttributeValueNotGlobStmt:             q.evaluateStringAttributeValueNotGlobStmt,
-		evaluateStringAttributeValueNotInclusionStmt:        q.evaluateStringAttributeValueNotInclusionStmt,
-		getLastBlockStmt:                      q.getLastBlockStmt,
-		getNumberOfEntitiesStmt:               q.getNumberOfEntitiesStmt,
-		getNumericAttributeValueBitmapStmt:    q.getNumericAttributeValueBitmapStmt,
-		getPayloadForEntityKeyStmt:            q.getPayloadForEntityKeyStmt,
-		getStringAttributeValueBitmapStmt:     q.getStringAttributeValueBitmapStmt,
-		retrievePayloadsStmt:                  q.retrievePayloadsStmt,
-		upsertLastBlockStmt:                   q.upsertLastBlockStmt,
-		upsertNumericAttributeValueBitmapStmt: q.upsertNumericAttributeValueBitmapStmt,
-		upsertPayloadStmt:                     q.upsertPayloadStmt,
-		upsertStringAttributeValueBitmapStmt:  q.upsertStringAttributeValueBitmapStmt,
+		db:                                       tx,
+		tx:                                       tx,
+		deleteNumericAttributeValueBitmapStmt:    q.deleteNumericAttributeValueBitmapStmt,
+		deletePayloadForEntityKeyStmt:            q.deletePayloadForEntityKeyStmt,
+		deleteStringAttributeValueBitmapStmt:     q.deleteStringAttributeValueBitmapStmt,
+		estimateNumericAttributeValueBetweenStmt: q.estimateNumericAttributeValueBetweenStmt,
+		estimateNumericAttributeValueGreaterOrEqualThanStmt: q.estimateNumericAttributeValueGreaterOrEqualThanStmt,
+		estimateNumericAttributeValueGreaterThanStmt:        q.estimateNumericAttributeValueGreaterThanStmt,
+		estimateNumericAttributeValueInclusionStmt:          q.estimateNumericAttributeValueInclusionStmt,
+		estimateNumericAttributeValueLessOrEqualThanStmt:    q.estimateNumericAttributeValueLessOrEqualThanStmt,
+		estimateNumericAttributeValueLowerThanStmt:          q.estimateNumericAttributeValueLowerThanStmt,
+		estimateStringAttributeValueBetweenStmt:             q.estimateStringAttributeValueBetweenStmt,
+		estimateStringAttributeValueGlobStmt:                q.estimateStringAttributeValueGlobStmt,
+		estimateStringAttributeValueGreaterOrEqualThanStmt:  q.estimateStringAttributeValueGreaterOrEqualThanStmt,
+		estimateStringAttributeValueGreaterThanStmt:         q.estimateStringAttributeValueGreaterThanStmt,
+		estimateStringAttributeValueInclusionStmt:           q.estimateStringAttributeValueInclusionStmt,
+		estimateStringAttributeValueLessOrEqualThanStmt:     q.estimateStringAttributeValueLessOrEqualThanStmt,
+		estimateStringAttributeValueLowerThanStmt:           q.estimateStringAttributeValueLowerThanStmt,
+		evaluateAllStmt:                                       q.evaluateAllStmt,
+		evaluateEntityKeyInclusionStmt:                        q.evaluateEntityKeyInclusionStmt,
+		evaluateNumericAttributeStmt:                          q.evaluateNumericAttributeStmt,
+		evaluateNumericAttributeValueBetweenStmt:              q.evaluateNumericAttributeValueBetweenStmt,
+		evaluateNumericAttributeValueEqualStmt:                q.evaluateNumericAttributeValueEqualStmt,
+		evaluateNumericAttributeValueGreaterOrEqualThanStmt:   q.evaluateNumericAttributeValueGreaterOrEqualThanStmt,
+		evaluateNumericAttributeValueGreaterThanStmt:          q.evaluateNumericAttributeValueGreaterThanStmt,
+		evaluateNumericAttributeValueInclusionStmt:            q.evaluateNumericAttributeValueInclusionStmt,
+		evaluateNumericAttributeValueLessOrEqualThanStmt:      q.evaluateNumericAttributeValueLessOrEqualThanStmt,
+		evaluateNumericAttributeValueLowerThanStmt:            q.evaluateNumericAttributeValueLowerThanStmt,
+		evaluateNumericAttributeValueNotEqualStmt:             q.evaluateNumericAttributeValueNotEqualStmt,
+		evaluateNumericAttributeValueNotInclusionStmt:         q.evaluateNumericAttributeValueNotInclusionStmt,
+		evaluateNumericAttributesEqualStmt:                    q.evaluateNumericAttributesEqualStmt,
+		evaluateStringAttributeStmt:                           q.evaluateStringAttributeStmt,
+		evaluateStringAttributeValueBetweenStmt:               q.evaluateStringAttributeValueBetweenStmt,
+		evaluateStringAttributeValueEqualStmt:                 q.evaluateStringAttributeValueEqualStmt,
+		evaluateStringAttributeValueGlobStmt:                  q.evaluateStringAttributeValueGlobStmt,
+		evaluateStringAttributeValueGreaterOrEqualThanStmt:    q.evaluateStringAttributeValueGreaterOrEqualThanStmt,
+		evaluateStringAttributeValueGreaterThanStmt:           q.evaluateStringAttributeValueGreaterThanStmt,
+		evaluateStringAttributeValueInclusionStmt:             q.evaluateStringAttributeValueInclusionStmt,
+		evaluateStringAttributeValueLessOrEqualThanStmt:       q.evaluateStringAttributeValueLessOrEqualThanStmt,
+		evaluateStringAttributeValueLowerThanStmt:             q.evaluateStringAttributeValueLowerThanStmt,
+		evaluateStringAttributeValueNotEqualStmt:              q.evaluateStringAttributeValueNotEqualStmt,
+		evaluateStringAttributeValueNotGlobStmt:               q.evaluateStringAttributeValueNotGlobStmt,
+		evaluateStringAttributeValueNotInclusionStmt:          q.evaluateStringAttributeValueNotInclusionStmt,
+		evaluateStringAttributeValueNotRangeStmt:              q.evaluateStringAttributeValueNotRangeStmt,
+		evaluateStringAttributeValueRangeStmt:                 q.evaluateStringAttributeValueRangeStmt,
+		evaluateStringAttributesEqualStmt:                     q.evaluateStringAttributesEqualStmt,
+		getEntityKeysStmt:                                     q.getEntityKeysStmt,
+		getLastBlockStmt:                                      q.getLastBlockStmt,
+		getNumberOfEntitiesStmt:                               q.getNumberOfEntitiesStmt,
+		getNumericAttributeStatsStmt:                          q.getNumericAttributeStatsStmt,
+		getNumericAttributeValueBitmapStmt:                    q.getNumericAttributeValueBitmapStmt,
+		getNumericAttributeValueBitmapsStmt:                   q.getNumericAttributeValueBitmapsStmt,
+		getNumericAttributeValueBitmapsWithoutCardinalityStmt: q.getNumericAttributeValueBitmapsWithoutCardinalityStmt,
+		getPayloadForEntityKeyStmt:                            q.getPayloadForEntityKeyStmt,
+		getPayloadsWithoutVersionStmt:                         q.getPayloadsWithoutVersionStmt,
+		getStringAttributeStatsStmt:                           q.getStringAttributeStatsStmt,
+		getStringAttributeValueBitmapStmt:                     q.getStringAttributeValueBitmapStmt,
+		getStringAttributeValueBitmapsWithoutCardinalityStmt:  q.getStringAttributeValueBitmapsWithoutCardinalityStmt,
+		getStringAttributeValuesStmt:                          q.getStringAttributeValuesStmt,
+		hasAttributeValueBitmapsWithoutCardinalityStmt:        q.hasAttributeValueBitmapsWithoutCardinalityStmt,
+		listNumericAttributeStatsStmt:                         q.listNumericAttributeStatsStmt,
+		listNumericAttributeValuesStmt:                        q.listNumericAttributeValuesStmt,
+		listStringAttributeStatsStmt:                          q.listStringAttributeStatsStmt,
+		listStringAttributeValuesStmt:                         q.listStringAttributeValuesStmt,
+		rebuildNumericAttributeStatsStmt:                      q.rebuildNumericAttributeStatsStmt,
+		rebuildStringAttributeStatsStmt:                       q.rebuildStringAttributeStatsStmt,
+		retrievePayloadsStmt:                                  q.retrievePayloadsStmt,
+		scanAttributesStmt:                                    q.scanAttributesStmt,
+		setNumericAttributeValueCardinalityStmt:               q.setNumericAttributeValueCardinalityStmt,
+		setStringAttributeValueCardinalityStmt:                q.setStringAttributeValueCardinalityStmt,
+		updateNumericAttributeStatsStmt:                       q.updateNumericAttributeStatsStmt,
+		updatePayloadAttributesStmt:                           q.updatePayloadAttributesStmt,
+		updateStringAttributeStatsStmt:                        q.updateStringAttributeStatsStmt,
+		upsertLastBlockStmt:                                   q.upsertLastBlockStmt,
+		upsertNumericAttributeValueBitmapStmt:                 q.upsertNumericAttributeValueBitmapStmt,
+		upsertPayloadStmt:                                     q.upsertPayloadStmt,
+		upsertStringAttributeValueBitmapStmt:                  q.upsertStringAttributeValueBitmapStmt,
 	}
 }
@@ -10,6 +10,271 @@ import (
 	"strings"
 )
 
+const estimateNumericAttributeValueBetween = `-- name: EstimateNumericAttributeValueBetween :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2 AND value <= ?3
+AND (CAST(?4 AS BOOLEAN) OR value != ?2)
+AND (CAST(?5 AS BOOLEAN) OR value != ?3)
+`
+
+type EstimateNumericAttributeValueBetweenParams struct {
+	Name          string
+	FromValue     NumericValue
+	ToValue       NumericValue
+	FromInclusive bool
+	ToInclusive   bool
+}
+
+func (q *Queries) EstimateNumericAttributeValueBetween(ctx context.Context, arg EstimateNumericAttributeValueBetweenParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateNumericAttributeValueBetweenStmt, estimateNumericAttributeValueBetween,
+		arg.Name,
+		arg.FromValue,
+		arg.ToValue,
+		arg.FromInclusive,
+		arg.ToInclusive,
+	)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateNumericAttributeValueGreaterOrEqualThan = `-- name: EstimateNumericAttributeValueGreaterOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2
+`
+
+type EstimateNumericAttributeValueGreaterOrEqualThanParams struct {
+	Name  string
+	Value NumericValue
+}
+
+func (q *Queries) EstimateNumericAttributeValueGreaterOrEqualThan(ctx context.Context, arg EstimateNumericAttributeValueGreaterOrEqualThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateNumericAttributeValueGreaterOrEqualThanStmt, estimateNumericAttributeValueGreaterOrEqualThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateNumericAttributeValueGreaterThan = `-- name: EstimateNumericAttributeValueGreaterThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value > ?2
+`
+
+type EstimateNumericAttributeValueGreaterThanParams struct {
+	Name  string
+	Value NumericValue
+}
+
+func (q *Queries) EstimateNumericAttributeValueGreaterThan(ctx context.Context, arg EstimateNumericAttributeValueGreaterThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateNumericAttributeValueGreaterThanStmt, estimateNumericAttributeValueGreaterThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateNumericAttributeValueInclusion = `-- name: EstimateNumericAttributeValueInclusion :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value IN (/*SLICE:values*/?)
+`
+
+type EstimateNumericAttributeValueInclusionParams struct {
+	Name   string
+	Values []NumericValue
+}
+
+func (q *Queries) EstimateNumericAttributeValueInclusion(ctx context.Context, arg EstimateNumericAttributeValueInclusionParams) (int64, error) {
+	query := estimateNumericAttributeValueInclusion
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Name)
+	if len(arg.Values) > 0 {
+		for _, v := range arg.Values {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:values*/?", strings.Repeat(",?", len(arg.Values))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:values*/?", "NULL", 1)
+	}
+	row := q.queryRow(ctx, nil, query, queryParams...)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateNumericAttributeValueLessOrEqualThan = `-- name: EstimateNumericAttributeValueLessOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value <= ?2
+`
+
+type EstimateNumericAttributeValueLessOrEqualThanParams struct {
+	Name  string
+	Value NumericValue
+}
+
+func (q *Queries) EstimateNumericAttributeValueLessOrEqualThan(ctx context.Context, arg EstimateNumericAttributeValueLessOrEqualThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateNumericAttributeValueLessOrEqualThanStmt, estimateNumericAttributeValueLessOrEqualThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateNumericAttributeValueLowerThan = `-- name: EstimateNumericAttributeValueLowerThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value < ?2
+`
+
+type EstimateNumericAttributeValueLowerThanParams struct {
+	Name  string
+	Value NumericValue
+}
+
+func (q *Queries) EstimateNumericAttributeValueLowerThan(ctx context.Context, arg EstimateNumericAttributeValueLowerThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateNumericAttributeValueLowerThanStmt, estimateNumericAttributeValueLowerThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueBetween = `-- name: EstimateStringAttributeValueBetween :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2 AND value <= ?3
+AND (CAST(?4 AS BOOLEAN) OR value != ?2)
+AND (CAST(?5 AS BOOLEAN) OR value != ?3)
+`
+
+type EstimateStringAttributeValueBetweenParams struct {
+	Name          string
+	FromValue     string
+	ToValue       string
+	FromInclusive bool
+	ToInclusive   bool
+}
+
+func (q *Queries) EstimateStringAttributeValueBetween(ctx context.Context, arg EstimateStringAttributeValueBetweenParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateStringAttributeValueBetweenStmt, estimateStringAttributeValueBetween,
+		arg.Name,
+		arg.FromValue,
+		arg.ToValue,
+		arg.FromInclusive,
+		arg.ToInclusive,
+	)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueGlob = `-- name: EstimateStringAttributeValueGlob :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value GLOB ?2
+`
+
+type EstimateStringAttributeValueGlobParams struct {
+	Name  string
+	Value string
+}
+
+func (q *Queries) EstimateStringAttributeValueGlob(ctx context.Context, arg EstimateStringAttributeValueGlobParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateStringAttributeValueGlobStmt, estimateStringAttributeValueGlob, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueGreaterOrEqualThan = `-- name: EstimateStringAttributeValueGreaterOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2
+`
+
+type EstimateStringAttributeValueGreaterOrEqualThanParams struct {
+	Name  string
+	Value string
+}
+
+func (q *Queries) EstimateStringAttributeValueGreaterOrEqualThan(ctx context.Context, arg EstimateStringAttributeValueGreaterOrEqualThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateStringAttributeValueGreaterOrEqualThanStmt, estimateStringAttributeValueGreaterOrEqualThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueGreaterThan = `-- name: EstimateStringAttributeValueGreaterThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value > ?2
+`
+
+type EstimateStringAttributeValueGreaterThanParams struct {
+	Name  string
+	Value string
+}
+
+func (q *Queries) EstimateStringAttributeValueGreaterThan(ctx context.Context, arg EstimateStringAttributeValueGreaterThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateStringAttributeValueGreaterThanStmt, estimateStringAttributeValueGreaterThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueInclusion = `-- name: EstimateStringAttributeValueInclusion :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value IN (/*SLICE:values*/?)
+`
+
+type EstimateStringAttributeValueInclusionParams struct {
+	Name   string
+	Values []string
+}
+
+func (q *Queries) EstimateStringAttributeValueInclusion(ctx context.Context, arg EstimateStringAttributeValueInclusionParams) (int64, error) {
+	query := estimateStringAttributeValueInclusion
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Name)
+	if len(arg.Values) > 0 {
+		for _, v := range arg.Values {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:values*/?", strings.Repeat(",?", len(arg.Values))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:values*/?", "NULL", 1)
+	}
+	row := q.queryRow(ctx, nil, query, queryParams...)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueLessOrEqualThan = `-- name: EstimateStringAttributeValueLessOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value <= ?2
+`
+
+type EstimateStringAttributeValueLessOrEqualThanParams struct {
+	Name  string
+	Value string
+}
+
+func (q *Queries) EstimateStringAttributeValueLessOrEqualThan(ctx context.Context, arg EstimateStringAttributeValueLessOrEqualThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateStringAttributeValueLessOrEqualThanStmt, estimateStringAttributeValueLessOrEqualThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const estimateStringAttributeValueLowerThan = `-- name: EstimateStringAttributeValueLowerThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value < ?2
+`
+
+type EstimateStringAttributeValueLowerThanParams struct {
+	Name  string
+	Value string
+}
+
+func (q *Queries) EstimateStringAttributeValueLowerThan(ctx context.Context, arg EstimateStringAttributeValueLowerThanParams) (int64, error) {
+	row := q.queryRow(ctx, q.estimateStringAttributeValueLowerThanStmt, estimateStringAttributeValueLowerThan, arg.Name, arg.Value)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const evaluateAll = `-- name: EvaluateAll :many
 SELECT id FROM payloads
 ORDER BY id DESC
@@ -38,6 +303,119 @@ func (q *Queries) EvaluateAll(ctx context.Context) ([]uint64, error) {
 	return items, nil
 }
 
+const evaluateEntityKeyInclusion = `-- name: EvaluateEntityKeyInclusion :many
+
+SELECT id FROM payloads
+WHERE entity_key IN (/*SLICE:keys*/?)
+`
+
+// Entity keys are looked up in the unique index on the payloads instead of the
+// bitmaps of $key.
+func (q *Queries) EvaluateEntityKeyInclusion(ctx context.Context, keys [][]byte) ([]uint64, error) {
+	query := evaluateEntityKeyInclusion
+	var queryParams []interface{}
+	if len(keys) > 0 {
+		for _, v := range keys {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:keys*/?", strings.Repeat(",?", len(keys))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:keys*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uint64{}
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const evaluateNumericAttribute = `-- name: EvaluateNumericAttribute :many
+SELECT bitmap FROM numeric_attributes_values_bitmaps
+WHERE name = ?1
+`
+
+func (q *Queries) EvaluateNumericAttribute(ctx context.Context, name string) ([]*Bitmap, error) {
+	rows, err := q.query(ctx, q.evaluateNumericAttributeStmt, evaluateNumericAttribute, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Bitmap{}
+	for rows.Next() {
+		var bitmap *Bitmap
+		if err := rows.Scan(&bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, bitmap)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const evaluateNumericAttributeValueBetween = `-- name: EvaluateNumericAttributeValueBetween :many
+SELECT bitmap FROM numeric_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2 AND value <= ?3
+AND (CAST(?4 AS BOOLEAN) OR value != ?2)
+AND (CAST(?5 AS BOOLEAN) OR value != ?3)
+`
+
+type EvaluateNumericAttributeValueBetweenParams struct {
+	Name          string
+	FromValue     NumericValue
+	ToValue       NumericValue
+	FromInclusive bool
+	ToInclusive   bool
+}
+
+func (q *Queries) EvaluateNumericAttributeValueBetween(ctx context.Context, arg EvaluateNumericAttributeValueBetweenParams) ([]*Bitmap, error) {
+	rows, err := q.query(ctx, q.evaluateNumericAttributeValueBetweenStmt, evaluateNumericAttributeValueBetween,
+		arg.Name,
+		arg.FromValue,
+		arg.ToValue,
+		arg.FromInclusive,
+		arg.ToInclusive,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Bitmap{}
+	for rows.Next() {
+		var bitmap *Bitmap
+		if err := rows.Scan(&bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, bitmap)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const evaluateNumericAttributeValueEqual = `-- name: EvaluateNumericAttributeValueEqual :one
 SELECT bitmap FROM numeric_attributes_values_bitmaps
 WHERE name = ?1 AND value = ?2
@@ -45,7 +423,7 @@ WHERE name = ?1 AND value = ?2
 
 type EvaluateNumericAttributeValueEqualParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueEqual(ctx context.Context, arg EvaluateNumericAttributeValueEqualParams) (*Bitmap, error) {
@@ -62,7 +440,7 @@ WHERE name = ?1 AND value >= ?2
 
 type EvaluateNumericAttributeValueGreaterOrEqualThanParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueGreaterOrEqualThan(ctx context.Context, arg EvaluateNumericAttributeValueGreaterOrEqualThanParams) ([]*Bitmap, error) {
@@ -95,7 +473,7 @@ WHERE name = ?1 AND value > ?2
 
 type EvaluateNumericAttributeValueGreaterThanParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueGreaterThan(ctx context.Context, arg EvaluateNumericAttributeValueGreaterThanParams) ([]*Bitmap, error) {
@@ -128,7 +506,7 @@ WHERE name = ?1 AND value IN (/*SLICE:values*/?)
 
 type EvaluateNumericAttributeValueInclusionParams struct {
 	Name   string
-	Values []uint64
+	Values []NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueInclusion(ctx context.Context, arg EvaluateNumericAttributeValueInclusionParams) ([]*Bitmap, error) {
@@ -172,7 +550,7 @@ WHERE name = ?1 AND value <= ?2
 
 type EvaluateNumericAttributeValueLessOrEqualThanParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueLessOrEqualThan(ctx context.Context, arg EvaluateNumericAttributeValueLessOrEqualThanParams) ([]*Bitmap, error) {
@@ -205,7 +583,7 @@ WHERE name = ?1 AND value < ?2
 
 type EvaluateNumericAttributeValueLowerThanParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueLowerThan(ctx context.Context, arg EvaluateNumericAttributeValueLowerThanParams) ([]*Bitmap, error) {
@@ -238,7 +616,7 @@ WHERE name = ?1 AND value != ?2
 
 type EvaluateNumericAttributeValueNotEqualParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueNotEqual(ctx context.Context, arg EvaluateNumericAttributeValueNotEqualParams) ([]*Bitmap, error) {
@@ -271,7 +649,7 @@ WHERE name = ?1 AND value NOT IN (/*SLICE:values*/?)
 
 type EvaluateNumericAttributeValueNotInclusionParams struct {
 	Name   string
-	Values []uint64
+	Values []NumericValue
 }
 
 func (q *Queries) EvaluateNumericAttributeValueNotInclusion(ctx context.Context, arg EvaluateNumericAttributeValueNotInclusionParams) ([]*Bitmap, error) {
@@ -308,6 +686,118 @@ func (q *Queries) EvaluateNumericAttributeValueNotInclusion(ctx context.Context,
 	return items, nil
 }
 
+const evaluateNumericAttributesEqual = `-- name: EvaluateNumericAttributesEqual :many
+SELECT l.bitmap AS bitmap, r.bitmap AS other_bitmap
+FROM numeric_attributes_values_bitmaps l
+JOIN numeric_attributes_values_bitmaps r ON r.name = ?1 AND r.value = l.value
+WHERE l.name = ?2
+`
+
+type EvaluateNumericAttributesEqualParams struct {
+	Other string
+	Name  string
+}
+
+type EvaluateNumericAttributesEqualRow struct {
+	Bitmap      *Bitmap
+	OtherBitmap *Bitmap
+}
+
+func (q *Queries) EvaluateNumericAttributesEqual(ctx context.Context, arg EvaluateNumericAttributesEqualParams) ([]EvaluateNumericAttributesEqualRow, error) {
+	rows, err := q.query(ctx, q.evaluateNumericAttributesEqualStmt, evaluateNumericAttributesEqual, arg.Other, arg.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EvaluateNumericAttributesEqualRow{}
+	for rows.Next() {
+		var i EvaluateNumericAttributesEqualRow
+		if err := rows.Scan(&i.Bitmap, &i.OtherBitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const evaluateStringAttribute = `-- name: EvaluateStringAttribute :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = ?1
+`
+
+func (q *Queries) EvaluateStringAttribute(ctx context.Context, name string) ([]*Bitmap, error) {
+	rows, err := q.query(ctx, q.evaluateStringAttributeStmt, evaluateStringAttribute, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Bitmap{}
+	for rows.Next() {
+		var bitmap *Bitmap
+		if err := rows.Scan(&bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, bitmap)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const evaluateStringAttributeValueBetween = `-- name: EvaluateStringAttributeValueBetween :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2 AND value <= ?3
+AND (CAST(?4 AS BOOLEAN) OR value != ?2)
+AND (CAST(?5 AS BOOLEAN) OR value != ?3)
+`
+
+type EvaluateStringAttributeValueBetweenParams struct {
+	Name          string
+	FromValue     string
+	ToValue       string
+	FromInclusive bool
+	ToInclusive   bool
+}
+
+func (q *Queries) EvaluateStringAttributeValueBetween(ctx context.Context, arg EvaluateStringAttributeValueBetweenParams) ([]*Bitmap, error) {
+	rows, err := q.query(ctx, q.evaluateStringAttributeValueBetweenStmt, evaluateStringAttributeValueBetween,
+		arg.Name,
+		arg.FromValue,
+		arg.ToValue,
+		arg.FromInclusive,
+		arg.ToInclusive,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Bitmap{}
+	for rows.Next() {
+		var bitmap *Bitmap
+		if err := rows.Scan(&bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, bitmap)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const evaluateStringAttributeValueEqual = `-- name: EvaluateStringAttributeValueEqual :one
 SELECT bitmap FROM string_attributes_values_bitmaps
 WHERE name = ?1 AND value = ?2
@@ -643,3 +1133,237 @@ func (q *Queries) EvaluateStringAttributeValueNotInclusion(ctx context.Context,
 	}
 	return items, nil
 }
+
+const evaluateStringAttributeValueNotRange = `-- name: EvaluateStringAttributeValueNotRange :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND (value < ?2 OR value >= ?3)
+`
+
+type EvaluateStringAttributeValueNotRangeParams struct {
+	Name      string
+	FromValue string
+	ToValue   string
+}
+
+func (q *Queries) EvaluateStringAttributeValueNotRange(ctx context.Context, arg EvaluateStringAttributeValueNotRangeParams) ([]*Bitmap, error) {
+	rows, err := q.query(ctx, q.evaluateStringAttributeValueNotRangeStmt, evaluateStringAttributeValueNotRange, arg.Name, arg.FromValue, arg.ToValue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Bitmap{}
+	for rows.Next() {
+		var bitmap *Bitmap
+		if err := rows.Scan(&bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, bitmap)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const evaluateStringAttributeValueRange = `-- name: EvaluateStringAttributeValueRange :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = ?1 AND value >= ?2 AND value < ?3
+`
+
+type EvaluateStringAttributeValueRangeParams struct {
+	Name      string
+	FromValue string
+	ToValue   string
+}
+
+func (q *Queries) EvaluateStringAttributeValueRange(ctx context.Context, arg EvaluateStringAttributeValueRangeParams) ([]*Bitmap, error) {
+	rows, err := q.query(ctx, q.evaluateStringAttributeValueRangeStmt, evaluateStringAttributeValueRange, arg.Name, arg.FromValue, arg.ToValue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Bitmap{}
+	for rows.Next() {
+		var bitmap *Bitmap
+		if err := rows.Scan(&bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, bitmap)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const evaluateStringAttributesEqual = `-- name: EvaluateStringAttributesEqual :many
+
+SELECT l.bitmap AS bitmap, r.bitmap AS other_bitmap
+FROM string_attributes_values_bitmaps l
+JOIN string_attributes_values_bitmaps r ON r.name = ?1 AND r.value = l.value
+WHERE l.name = ?2
+`
+
+type EvaluateStringAttributesEqualParams struct {
+	Other string
+	Name  string
+}
+
+type EvaluateStringAttributesEqualRow struct {
+	Bitmap      *Bitmap
+	OtherBitmap *Bitmap
+}
+
+// Attribute comparisons join the bitmaps of two attributes on their values.
+func (q *Queries) EvaluateStringAttributesEqual(ctx context.Context, arg EvaluateStringAttributesEqualParams) ([]EvaluateStringAttributesEqualRow, error) {
+	rows, err := q.query(ctx, q.evaluateStringAttributesEqualStmt, evaluateStringAttributesEqual, arg.Other, arg.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EvaluateStringAttributesEqualRow{}
+	for rows.Next() {
+		var i EvaluateStringAttributesEqualRow
+		if err := rows.Scan(&i.Bitmap, &i.OtherBitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNumericAttributeStats = `-- name: GetNumericAttributeStats :one
+SELECT distinct_values, cardinality FROM numeric_attributes_stats
+WHERE name = ?1
+`
+
+type GetNumericAttributeStatsRow struct {
+	DistinctValues uint64
+	Cardinality    uint64
+}
+
+func (q *Queries) GetNumericAttributeStats(ctx context.Context, name string) (GetNumericAttributeStatsRow, error) {
+	row := q.queryRow(ctx, q.getNumericAttributeStatsStmt, getNumericAttributeStats, name)
+	var i GetNumericAttributeStatsRow
+	err := row.Scan(&i.DistinctValues, &i.Cardinality)
+	return i, err
+}
+
+const getStringAttributeStats = `-- name: GetStringAttributeStats :one
+
+SELECT distinct_values, cardinality FROM string_attributes_stats
+WHERE name = ?1
+`
+
+type GetStringAttributeStatsRow struct {
+	DistinctValues uint64
+	Cardinality    uint64
+}
+
+// Estimates sum the cardinalities of the bitmaps that the evaluations above
+// would load, without loading them.
+func (q *Queries) GetStringAttributeStats(ctx context.Context, name string) (GetStringAttributeStatsRow, error) {
+	row := q.queryRow(ctx, q.getStringAttributeStatsStmt, getStringAttributeStats, name)
+	var i GetStringAttributeStatsRow
+	err := row.Scan(&i.DistinctValues, &i.Cardinality)
+	return i, err
+}
+
+const getStringAttributeValues = `-- name: GetStringAttributeValues :many
+SELECT value FROM string_attributes_values_bitmaps
+WHERE name = ?1
+ORDER BY value
+`
+
+func (q *Queries) GetStringAttributeValues(ctx context.Context, name string) ([]string, error) {
+	rows, err := q.query(ctx, q.getStringAttributeValuesStmt, getStringAttributeValues, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNumericAttributeStats = `-- name: ListNumericAttributeStats :many
+SELECT name, distinct_values, cardinality FROM numeric_attributes_stats
+WHERE cardinality > 0
+ORDER BY name
+`
+
+func (q *Queries) ListNumericAttributeStats(ctx context.Context) ([]NumericAttributesStat, error) {
+	rows, err := q.query(ctx, q.listNumericAttributeStatsStmt, listNumericAttributeStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []NumericAttributesStat{}
+	for rows.Next() {
+		var i NumericAttributesStat
+		if err := rows.Scan(&i.Name, &i.DistinctValues, &i.Cardinality); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStringAttributeStats = `-- name: ListStringAttributeStats :many
+SELECT name, distinct_values, cardinality FROM string_attributes_stats
+WHERE cardinality > 0
+ORDER BY name
+`
+
+func (q *Queries) ListStringAttributeStats(ctx context.Context) ([]StringAttributesStat, error) {
+	rows, err := q.query(ctx, q.listStringAttributeStatsStmt, listStringAttributeStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []StringAttributesStat{}
+	for rows.Next() {
+		var i StringAttributesStat
+		if err := rows.Scan(&i.Name, &i.DistinctValues, &i.Cardinality); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
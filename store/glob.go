@@ -0,0 +1,93 @@
+package store
+
+import "unicode/utf8"
+
+// MatchGlob reports whether s matches pattern with the semantics of the
+// SQLite GLOB operator: '*' matches any sequence of characters, '?' matches a
+// single character and '[...]' matches a character class, negated with '^'.
+// Matching is case sensitive.
+func MatchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		c, size := utf8.DecodeRuneInString(pattern)
+		switch c {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); {
+				if MatchGlob(pattern, s[i:]) {
+					return true
+				}
+				if i == len(s) {
+					break
+				}
+				_, n := utf8.DecodeRuneInString(s[i:])
+				i += n
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			_, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+			pattern = pattern[size:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			r, n := utf8.DecodeRuneInString(s)
+			matched, rest, ok := matchClass(pattern[size:], r)
+			if !ok || !matched {
+				return false
+			}
+			s = s[n:]
+			pattern = rest
+		default:
+			r, n := utf8.DecodeRuneInString(s)
+			if len(s) == 0 || r != c {
+				return false
+			}
+			s = s[n:]
+			pattern = pattern[size:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches r against the character class at the start of pattern,
+// which follows the opening '['. It returns the remainder of the pattern after
+// the closing ']', and ok is false if the class is not terminated.
+func matchClass(pattern string, r rune) (matched bool, rest string, ok bool) {
+	negated := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negated = true
+		pattern = pattern[1:]
+	}
+
+	first := true
+	for len(pattern) > 0 {
+		lo, size := utf8.DecodeRuneInString(pattern)
+		if lo == ']' && !first {
+			return matched != negated, pattern[size:], true
+		}
+		first = false
+		pattern = pattern[size:]
+
+		hi := lo
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			var n int
+			hi, n = utf8.DecodeRuneInString(pattern[1:])
+			pattern = pattern[1+n:]
+		}
+
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+
+	return false, "", false
+}
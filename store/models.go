@@ -9,10 +9,17 @@ type LastBlock struct {
 	Block uint64
 }
 
+type NumericAttributesStat struct {
+	Name           string
+	DistinctValues uint64
+	Cardinality    uint64
+}
+
 type NumericAttributesValuesBitmap struct {
-	Name   string
-	Value  uint64
-	Bitmap *Bitmap
+	Name        string
+	Value       NumericValue
+	Bitmap      *Bitmap
+	Cardinality uint64
 }
 
 type Payload struct {
@@ -24,8 +31,15 @@ type Payload struct {
 	NumericAttributes *NumericAttributes
 }
 
+type StringAttributesStat struct {
+	Name           string
+	DistinctValues uint64
+	Cardinality    uint64
+}
+
 type StringAttributesValuesBitmap struct {
-	Name   string
-	Value  string
-	Bitmap *Bitmap
+	Name        string
+	Value       string
+	Bitmap      *Bitmap
+	Cardinality uint64
 }
@@ -7,10 +7,10 @@ import (
 )
 
 type NumericAttributes struct {
-	Values map[string]uint64
+	Values map[string]NumericValue
 }
 
-func NewNumericAttributes(values map[string]uint64) *NumericAttributes {
+func NewNumericAttributes(values map[string]NumericValue) *NumericAttributes {
 	return &NumericAttributes{Values: values}
 }
 
@@ -18,7 +18,7 @@ func NewNumericAttributes(values map[string]uint64) *NumericAttributes {
 func (b *NumericAttributes) Scan(src any) error {
 
 	if b.Values == nil {
-		b.Values = make(map[string]uint64)
+		b.Values = make(map[string]NumericValue)
 	}
 
 	if src == nil {
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// NumericFractionDigits is the number of decimal digits after the decimal
+// point that a numeric value can hold.
+const NumericFractionDigits = 18
+
+// numericValueSize is the size of an encoded numeric value: a sign byte, the
+// 32 byte integer part and the 8 byte fractional part.
+const numericValueSize = 1 + 32 + 8
+
+var fractionScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(NumericFractionDigits), nil)
+
+// NumericValue is the value of a numeric attribute, a signed fixed-point
+// decimal with an integer part of up to 256 bits and 18 fractional digits.
+// Unsigned integers of up to 256 bits are represented exactly.
+//
+// In the attribute index it is stored as a 41 byte blob: a sign byte (0 for
+// negative values, 1 otherwise), followed by the big-endian integer part and
+// the fractional part in units of 10^-18. For negative values the magnitude
+// bytes are inverted. SQLite compares blobs byte by byte, so the order of the
+// encoded values is the numeric order and range queries can be answered by
+// the primary key.
+type NumericValue struct {
+	neg  bool
+	i    uint256.Int
+	frac uint64
+}
+
+func NewNumericValue(v uint64) NumericValue {
+	var n NumericValue
+	n.i.SetUint64(v)
+	return n
+}
+
+// NumericValueFromBig converts an integer.
+func NumericValueFromBig(b *big.Int) (NumericValue, error) {
+	var n NumericValue
+	if overflow := n.i.SetFromBig(new(big.Int).Abs(b)); overflow {
+		return NumericValue{}, fmt.Errorf("numeric value %s does not fit in 256 bits", b)
+	}
+	n.neg = b.Sign() < 0
+	return n, nil
+}
+
+// NumericValueFromScaled converts an integer counting units of 10^-18.
+func NumericValueFromScaled(scaled *big.Int) (NumericValue, error) {
+	i, frac := new(big.Int).QuoRem(new(big.Int).Abs(scaled), fractionScale, new(big.Int))
+
+	var n NumericValue
+	if overflow := n.i.SetFromBig(i); overflow {
+		return NumericValue{}, fmt.Errorf("numeric value %s does not fit in 256 bits", i)
+	}
+	n.frac = frac.Uint64()
+	n.neg = scaled.Sign() < 0
+	return n, nil
+}
+
+// ParseNumericValue parses a decimal number, optionally negative and with up
+// to 18 fractional digits, or a 0x prefixed hexadecimal integer.
+func ParseNumericValue(s string) (NumericValue, error) {
+	invalid := fmt.Errorf("invalid numeric value %q", s)
+
+	digits, neg := strings.CutPrefix(s, "-")
+
+	b, ok := new(big.Int), false
+	var frac uint64
+	if hex, isHex := strings.CutPrefix(strings.ToLower(digits), "0x"); isHex {
+		// big.Int accepts a sign after the prefix
+		if !strings.ContainsAny(hex, "+-_") {
+			b, ok = b.SetString(hex, 16)
+		}
+	} else if !strings.ContainsAny(digits, "+-_") {
+		intPart, fracPart, hasFrac := strings.Cut(digits, ".")
+		b, ok = b.SetString(intPart, 10)
+		if hasFrac {
+			if len(fracPart) == 0 || len(fracPart) > NumericFractionDigits || strings.ContainsAny(fracPart, "+-_") {
+				return NumericValue{}, invalid
+			}
+			f, ok2 := new(big.Int).SetString(fracPart+strings.Repeat("0", NumericFractionDigits-len(fracPart)), 10)
+			if !ok2 {
+				return NumericValue{}, invalid
+			}
+			frac = f.Uint64()
+		}
+	}
+	if !ok {
+		return NumericValue{}, invalid
+	}
+
+	n, err := NumericValueFromBig(b)
+	if err != nil {
+		return NumericValue{}, err
+	}
+	n.frac = frac
+	n.neg = neg && (!n.i.IsZero() || frac != 0)
+	return n, nil
+}
+
+// IsUint64 reports whether the value is a non-negative integer that fits in
+// 64 bits.
+func (n NumericValue) IsUint64() bool {
+	return !n.neg && n.frac == 0 && n.i.IsUint64()
+}
+
+// Uint64 returns the lower 64 bits of the integer part of the value.
+func (n NumericValue) Uint64() uint64 {
+	return n.i.Uint64()
+}
+
+func (n NumericValue) IsNegative() bool {
+	return n.neg
+}
+
+func (n NumericValue) IsInteger() bool {
+	return n.frac == 0
+}
+
+// Scaled returns the value as an integer counting units of 10^-18.
+func (n NumericValue) Scaled() *big.Int {
+	s := n.i.ToBig()
+	s.Mul(s, fractionScale)
+	s.Add(s, new(big.Int).SetUint64(n.frac))
+	if n.neg {
+		s.Neg(s)
+	}
+	return s
+}
+
+// Rat returns the exact value as a rational number.
+func (n NumericValue) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(n.Scaled(), fractionScale)
+}
+
+func (n NumericValue) Cmp(o NumericValue) int {
+	a, b := n.encode(), o.encode()
+	return bytes.Compare(a[:], b[:])
+}
+
+// String returns the decimal representation of the value, without trailing
+// fractional zeros.
+func (n NumericValue) String() string {
+	s := n.i.Dec()
+	if n.frac != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%018d", n.frac), "0")
+	}
+	if n.neg {
+		s = "-" + s
+	}
+	return s
+}
+
+func (n NumericValue) encode() [numericValueSize]byte {
+	var b [numericValueSize]byte
+	i := n.i.Bytes32()
+	copy(b[1:33], i[:])
+	binary.BigEndian.PutUint64(b[33:], n.frac)
+	if n.neg {
+		for j := 1; j < len(b); j++ {
+			b[j] = ^b[j]
+		}
+	} else {
+		b[0] = 1
+	}
+	return b
+}
+
+// Scanner interface for reading from DB
+func (n *NumericValue) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte, got %T", src)
+	}
+	if len(data) != numericValueSize {
+		return fmt.Errorf("expected %d bytes, got %d", numericValueSize, len(data))
+	}
+
+	var b [numericValueSize]byte
+	copy(b[:], data)
+	neg := b[0] == 0
+	if neg {
+		for j := 1; j < len(b); j++ {
+			b[j] = ^b[j]
+		}
+	}
+
+	n.neg = neg
+	n.i.SetBytes32(b[1:33])
+	n.frac = binary.BigEndian.Uint64(b[33:])
+	return nil
+}
+
+// Valuer interface for writing to DB
+func (n NumericValue) Value() (driver.Value, error) {
+	b := n.encode()
+	return b[:], nil
+}
+
+// MarshalJSON encodes the value as a JSON string holding the decimal number,
+// since many clients parse JSON numbers as doubles and lose the precision of
+// large values.
+func (n NumericValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + n.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal or
+// hexadecimal number.
+func (n *NumericValue) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	return n.UnmarshalText(data)
+}
+
+func (n *NumericValue) UnmarshalText(text []byte) error {
+	v, err := ParseNumericValue(string(text))
+	if err != nil {
+		return err
+	}
+	*n = v
+	return nil
+}
+
+// Capture lets the query parser capture numeric literals.
+func (n *NumericValue) Capture(values []string) error {
+	return n.UnmarshalText([]byte(strings.Join(values, "")))
+}
+
+// NewNumericValues converts attribute values as they appear in events. The
+// events carry numeric attributes as uint64, so stored attributes are
+// non-negative integers below 2^64 until the events carry wider values.
+func NewNumericValues(values map[string]uint64) map[string]NumericValue {
+	res := make(map[string]NumericValue, len(values))
+	for k, v := range values {
+		res[k] = NewNumericValue(v)
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumericValue(t *testing.T) {
+	t.Run("parse and format", func(t *testing.T) {
+		for _, s := range []string{"0", "42", "-42", "1.5", "-0.000000000000000001", "115792089237316195423570985008687907853269984665640564039457584007913129639935"} {
+			v, err := ParseNumericValue(s)
+			require.NoError(t, err, s)
+			require.Equal(t, s, v.String())
+		}
+
+		v, err := ParseNumericValue("-0x10")
+		require.NoError(t, err)
+		require.Equal(t, "-16", v.String())
+
+		v, err = ParseNumericValue("-0.0")
+		require.NoError(t, err)
+		require.Equal(t, NewNumericValue(0), v)
+
+		for _, s := range []string{"", "-", "1.", ".5", "+1", "1_000", "1.0000000000000000001", "1.-5", "0x", "0x-5", "0x+5", "-0x-5", "0X+5", "0x_5"} {
+			_, err := ParseNumericValue(s)
+			require.Error(t, err, s)
+		}
+	})
+
+	t.Run("encoding preserves order", func(t *testing.T) {
+		var values []NumericValue
+		for _, s := range []string{"-1000000", "-2.5", "-2", "-1.999", "-0.000000000000000001", "0", "0.5", "1", "1.25", "18446744073709551616"} {
+			v, err := ParseNumericValue(s)
+			require.NoError(t, err)
+			values = append(values, v)
+		}
+
+		var encoded [][]byte
+		for _, v := range values {
+			b, err := v.Value()
+			require.NoError(t, err)
+			encoded = append(encoded, b.([]byte))
+
+			var decoded NumericValue
+			require.NoError(t, decoded.Scan(b))
+			require.Equal(t, v, decoded)
+		}
+
+		require.True(t, slices.IsSortedFunc(encoded, func(a, b []byte) int { return slices.Compare(a, b) }))
+		require.True(t, slices.IsSortedFunc(values, NumericValue.Cmp))
+	})
+	t.Run("JSON", func(t *testing.T) {
+		v, err := ParseNumericValue("-115792089237316195423570985008687907853269984665640564039457584007913129639935.5")
+		require.NoError(t, err)
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.Equal(t, `"-115792089237316195423570985008687907853269984665640564039457584007913129639935.5"`, string(data))
+
+		var decoded NumericValue
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, v, decoded)
+
+		// numbers written by earlier versions are decoded as well
+		require.NoError(t, json.Unmarshal([]byte(`18446744073709551615`), &decoded))
+		require.Equal(t, NewNumericValue(math.MaxUint64), decoded)
+	})
+}
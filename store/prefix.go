@@ -0,0 +1,17 @@
+package store
+
+// PrefixUpperBound returns the smallest string that is larger than every
+// string starting with prefix, comparing bytes like SQLite does. There is no
+// such string if prefix is empty or consists only of 0xff bytes. The strings
+// with the prefix are then the ones from prefix up to the bound, which SQLite
+// finds with a range scan of an index.
+func PrefixUpperBound(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PayloadProjection selects the parts of a payload row that
+// RetrieveProjectedPayloads returns. Attributes are selected with SQLite GLOB
+// patterns on their names.
+type PayloadProjection struct {
+	Payload           bool
+	StringAttributes  []string
+	NumericAttributes []string
+}
+
+// projectedAttributesColumn rebuilds the attributes JSON of column with only
+// the attributes whose name matches one of the patterns bound to the next
+// parameter. Values are copied as JSON text, so large numbers keep their
+// precision.
+func projectedAttributesColumn(column string) string {
+	return `(
+        SELECT json_object('Values', json(json_group_object(a.key, json(` + column + ` -> a.fullkey))))
+        FROM json_each(` + column + `, '$.Values') AS a
+        WHERE EXISTS (SELECT 1 FROM json_each(?) AS n WHERE a.key GLOB n.value)
+    )`
+}
+
+// RetrieveProjectedPayloads is RetrievePayloads restricted to a projection.
+// The attribute filtering happens inside SQLite, so attributes that are not
+// requested are never decoded in Go, and a payload that is not requested is
+// never read.
+//
+// This query is written by hand because sqlc does not support parameters
+// inside nested sub-selects.
+func (q *Queries) RetrieveProjectedPayloads(ctx context.Context, ids []uint64, projection PayloadProjection) ([]RetrievePayloadsRow, error) {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return q.retrieveProjectedPayloads(ctx, "id", args, projection)
+}
+
+// RetrieveProjectedPayloadsByKeys is RetrieveProjectedPayloads for the
+// payloads with the entity keys, which are looked up in
+// payloads_entity_key_index. Keys that no payload has are skipped.
+func (q *Queries) RetrieveProjectedPayloadsByKeys(ctx context.Context, keys [][]byte, projection PayloadProjection) ([]RetrievePayloadsRow, error) {
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	return q.retrieveProjectedPayloads(ctx, "entity_key", args, projection)
+}
+
+// retrieveProjectedPayloads returns the projected payloads whose column has
+// one of the values in args.
+func (q *Queries) retrieveProjectedPayloads(ctx context.Context, column string, args []interface{}, projection PayloadProjection) ([]RetrievePayloadsRow, error) {
+	if len(args) == 0 {
+		return []RetrievePayloadsRow{}, nil
+	}
+
+	var queryParams []interface{}
+
+	payloadColumn := "x''"
+	if projection.Payload {
+		payloadColumn = "payload"
+	}
+
+	stringAttributesColumn := `'{"Values":{}}'`
+	if len(projection.StringAttributes) > 0 {
+		stringAttributesColumn = projectedAttributesColumn("string_attributes")
+		patterns, err := json.Marshal(projection.StringAttributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal string attribute patterns: %w", err)
+		}
+		queryParams = append(queryParams, string(patterns))
+	}
+
+	numericAttributesColumn := `'{"Values":{}}'`
+	if len(projection.NumericAttributes) > 0 {
+		numericAttributesColumn = projectedAttributesColumn("numeric_attributes")
+		patterns, err := json.Marshal(projection.NumericAttributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal numeric attribute patterns: %w", err)
+		}
+		queryParams = append(queryParams, string(patterns))
+	}
+
+	queryParams = append(queryParams, args...)
+
+	query := `SELECT entity_key, id, ` + payloadColumn + `, content_type, ` +
+		stringAttributesColumn + `, ` + numericAttributesColumn + `
+FROM payloads
+WHERE ` + column + ` IN (` + strings.Repeat(",?", len(args))[1:] + `)
+ORDER BY id DESC`
+
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RetrievePayloadsRow{}
+	for rows.Next() {
+		var i RetrievePayloadsRow
+		if err := rows.Scan(
+			&i.EntityKey,
+			&i.ID,
+			&i.Payload,
+			&i.ContentType,
+			&i.StringAttributes,
+			&i.NumericAttributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -6,13 +6,32 @@ package store
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
 	DeleteNumericAttributeValueBitmap(ctx context.Context, arg DeleteNumericAttributeValueBitmapParams) error
 	DeletePayloadForEntityKey(ctx context.Context, entityKey []byte) error
 	DeleteStringAttributeValueBitmap(ctx context.Context, arg DeleteStringAttributeValueBitmapParams) error
+	EstimateNumericAttributeValueBetween(ctx context.Context, arg EstimateNumericAttributeValueBetweenParams) (int64, error)
+	EstimateNumericAttributeValueGreaterOrEqualThan(ctx context.Context, arg EstimateNumericAttributeValueGreaterOrEqualThanParams) (int64, error)
+	EstimateNumericAttributeValueGreaterThan(ctx context.Context, arg EstimateNumericAttributeValueGreaterThanParams) (int64, error)
+	EstimateNumericAttributeValueInclusion(ctx context.Context, arg EstimateNumericAttributeValueInclusionParams) (int64, error)
+	EstimateNumericAttributeValueLessOrEqualThan(ctx context.Context, arg EstimateNumericAttributeValueLessOrEqualThanParams) (int64, error)
+	EstimateNumericAttributeValueLowerThan(ctx context.Context, arg EstimateNumericAttributeValueLowerThanParams) (int64, error)
+	EstimateStringAttributeValueBetween(ctx context.Context, arg EstimateStringAttributeValueBetweenParams) (int64, error)
+	EstimateStringAttributeValueGlob(ctx context.Context, arg EstimateStringAttributeValueGlobParams) (int64, error)
+	EstimateStringAttributeValueGreaterOrEqualThan(ctx context.Context, arg EstimateStringAttributeValueGreaterOrEqualThanParams) (int64, error)
+	EstimateStringAttributeValueGreaterThan(ctx context.Context, arg EstimateStringAttributeValueGreaterThanParams) (int64, error)
+	EstimateStringAttributeValueInclusion(ctx context.Context, arg EstimateStringAttributeValueInclusionParams) (int64, error)
+	EstimateStringAttributeValueLessOrEqualThan(ctx context.Context, arg EstimateStringAttributeValueLessOrEqualThanParams) (int64, error)
+	EstimateStringAttributeValueLowerThan(ctx context.Context, arg EstimateStringAttributeValueLowerThanParams) (int64, error)
 	EvaluateAll(ctx context.Context) ([]uint64, error)
+	// Entity keys are looked up in the unique index on the payloads instead of the
+	// bitmaps of $key.
+	EvaluateEntityKeyInclusion(ctx context.Context, keys [][]byte) ([]uint64, error)
+	EvaluateNumericAttribute(ctx context.Context, name string) ([]*Bitmap, error)
+	EvaluateNumericAttributeValueBetween(ctx context.Context, arg EvaluateNumericAttributeValueBetweenParams) ([]*Bitmap, error)
 	EvaluateNumericAttributeValueEqual(ctx context.Context, arg EvaluateNumericAttributeValueEqualParams) (*Bitmap, error)
 	EvaluateNumericAttributeValueGreaterOrEqualThan(ctx context.Context, arg EvaluateNumericAttributeValueGreaterOrEqualThanParams) ([]*Bitmap, error)
 	EvaluateNumericAttributeValueGreaterThan(ctx context.Context, arg EvaluateNumericAttributeValueGreaterThanParams) ([]*Bitmap, error)
@@ -21,6 +40,9 @@ type Querier interface {
 	EvaluateNumericAttributeValueLowerThan(ctx context.Context, arg EvaluateNumericAttributeValueLowerThanParams) ([]*Bitmap, error)
 	EvaluateNumericAttributeValueNotEqual(ctx context.Context, arg EvaluateNumericAttributeValueNotEqualParams) ([]*Bitmap, error)
 	EvaluateNumericAttributeValueNotInclusion(ctx context.Context, arg EvaluateNumericAttributeValueNotInclusionParams) ([]*Bitmap, error)
+	EvaluateNumericAttributesEqual(ctx context.Context, arg EvaluateNumericAttributesEqualParams) ([]EvaluateNumericAttributesEqualRow, error)
+	EvaluateStringAttribute(ctx context.Context, name string) ([]*Bitmap, error)
+	EvaluateStringAttributeValueBetween(ctx context.Context, arg EvaluateStringAttributeValueBetweenParams) ([]*Bitmap, error)
 	EvaluateStringAttributeValueEqual(ctx context.Context, arg EvaluateStringAttributeValueEqualParams) (*Bitmap, error)
 	EvaluateStringAttributeValueGlob(ctx context.Context, arg EvaluateStringAttributeValueGlobParams) ([]*Bitmap, error)
 	EvaluateStringAttributeValueGreaterOrEqualThan(ctx context.Context, arg EvaluateStringAttributeValueGreaterOrEqualThanParams) ([]*Bitmap, error)
@@ -31,12 +53,39 @@ type Querier interface {
 	EvaluateStringAttributeValueNotEqual(ctx context.Context, arg EvaluateStringAttributeValueNotEqualParams) ([]*Bitmap, error)
 	EvaluateStringAttributeValueNotGlob(ctx context.Context, arg EvaluateStringAttributeValueNotGlobParams) ([]*Bitmap, error)
 	EvaluateStringAttributeValueNotInclusion(ctx context.Context, arg EvaluateStringAttributeValueNotInclusionParams) ([]*Bitmap, error)
+	EvaluateStringAttributeValueNotRange(ctx context.Context, arg EvaluateStringAttributeValueNotRangeParams) ([]*Bitmap, error)
+	EvaluateStringAttributeValueRange(ctx context.Context, arg EvaluateStringAttributeValueRangeParams) ([]*Bitmap, error)
+	// Attribute comparisons join the bitmaps of two attributes on their values.
+	EvaluateStringAttributesEqual(ctx context.Context, arg EvaluateStringAttributesEqualParams) ([]EvaluateStringAttributesEqualRow, error)
+	GetEntityKeys(ctx context.Context, ids []uint64) ([][]byte, error)
 	GetLastBlock(ctx context.Context) (uint64, error)
 	GetNumberOfEntities(ctx context.Context) (int64, error)
+	GetNumericAttributeStats(ctx context.Context, name string) (GetNumericAttributeStatsRow, error)
 	GetNumericAttributeValueBitmap(ctx context.Context, arg GetNumericAttributeValueBitmapParams) (*Bitmap, error)
+	GetNumericAttributeValueBitmaps(ctx context.Context, name string) ([]GetNumericAttributeValueBitmapsRow, error)
+	GetNumericAttributeValueBitmapsWithoutCardinality(ctx context.Context, limit int64) ([]GetNumericAttributeValueBitmapsWithoutCardinalityRow, error)
 	GetPayloadForEntityKey(ctx context.Context, entityKey []byte) (GetPayloadForEntityKeyRow, error)
+	GetPayloadsWithoutVersion(ctx context.Context, arg GetPayloadsWithoutVersionParams) ([]GetPayloadsWithoutVersionRow, error)
+	// Estimates sum the cardinalities of the bitmaps that the evaluations above
+	// would load, without loading them.
+	GetStringAttributeStats(ctx context.Context, name string) (GetStringAttributeStatsRow, error)
 	GetStringAttributeValueBitmap(ctx context.Context, arg GetStringAttributeValueBitmapParams) (*Bitmap, error)
+	GetStringAttributeValueBitmapsWithoutCardinality(ctx context.Context, limit int64) ([]GetStringAttributeValueBitmapsWithoutCardinalityRow, error)
+	GetStringAttributeValues(ctx context.Context, name string) ([]string, error)
+	HasAttributeValueBitmapsWithoutCardinality(ctx context.Context) (sql.NullBool, error)
+	ListNumericAttributeStats(ctx context.Context) ([]NumericAttributesStat, error)
+	ListNumericAttributeValues(ctx context.Context, arg ListNumericAttributeValuesParams) ([]ListNumericAttributeValuesRow, error)
+	ListStringAttributeStats(ctx context.Context) ([]StringAttributesStat, error)
+	ListStringAttributeValues(ctx context.Context, arg ListStringAttributeValuesParams) ([]ListStringAttributeValuesRow, error)
+	RebuildNumericAttributeStats(ctx context.Context) error
+	RebuildStringAttributeStats(ctx context.Context) error
 	RetrievePayloads(ctx context.Context, ids []uint64) ([]RetrievePayloadsRow, error)
+	ScanAttributes(ctx context.Context, ids []uint64) ([]ScanAttributesRow, error)
+	SetNumericAttributeValueCardinality(ctx context.Context, arg SetNumericAttributeValueCardinalityParams) error
+	SetStringAttributeValueCardinality(ctx context.Context, arg SetStringAttributeValueCardinalityParams) error
+	UpdateNumericAttributeStats(ctx context.Context, arg UpdateNumericAttributeStatsParams) error
+	UpdatePayloadAttributes(ctx context.Context, arg UpdatePayloadAttributesParams) error
+	UpdateStringAttributeStats(ctx context.Context, arg UpdateStringAttributeStatsParams) error
 	UpsertLastBlock(ctx context.Context, block uint64) error
 	UpsertNumericAttributeValueBitmap(ctx context.Context, arg UpsertNumericAttributeValueBitmapParams) error
 	UpsertPayload(ctx context.Context, arg UpsertPayloadParams) (uint64, error)
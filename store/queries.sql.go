@@ -7,6 +7,7 @@ package store
 
 import (
 	"context"
+	"database/sql"
 )
 
 const deleteNumericAttributeValueBitmap = `-- name: DeleteNumericAttributeValueBitmap :exec
@@ -16,7 +17,7 @@ WHERE name = ? AND value = ?
 
 type DeleteNumericAttributeValueBitmapParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) DeleteNumericAttributeValueBitmap(ctx context.Context, arg DeleteNumericAttributeValueBitmapParams) error {
@@ -67,7 +68,7 @@ WHERE name = ? AND value = ?
 
 type GetNumericAttributeValueBitmapParams struct {
 	Name  string
-	Value uint64
+	Value NumericValue
 }
 
 func (q *Queries) GetNumericAttributeValueBitmap(ctx context.Context, arg GetNumericAttributeValueBitmapParams) (*Bitmap, error) {
@@ -77,6 +78,41 @@ func (q *Queries) GetNumericAttributeValueBitmap(ctx context.Context, arg GetNum
 	return bitmap, err
 }
 
+const getNumericAttributeValueBitmapsWithoutCardinality = `-- name: GetNumericAttributeValueBitmapsWithoutCardinality :many
+SELECT name, value, bitmap FROM numeric_attributes_values_bitmaps
+WHERE cardinality IS NULL
+LIMIT ?
+`
+
+type GetNumericAttributeValueBitmapsWithoutCardinalityRow struct {
+	Name   string
+	Value  NumericValue
+	Bitmap *Bitmap
+}
+
+func (q *Queries) GetNumericAttributeValueBitmapsWithoutCardinality(ctx context.Context, limit int64) ([]GetNumericAttributeValueBitmapsWithoutCardinalityRow, error) {
+	rows, err := q.query(ctx, q.getNumericAttributeValueBitmapsWithoutCardinalityStmt, getNumericAttributeValueBitmapsWithoutCardinality, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetNumericAttributeValueBitmapsWithoutCardinalityRow{}
+	for rows.Next() {
+		var i GetNumericAttributeValueBitmapsWithoutCardinalityRow
+		if err := rows.Scan(&i.Name, &i.Value, &i.Bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPayloadForEntityKey = `-- name: GetPayloadForEntityKey :one
 SELECT entity_key, id, payload, content_type, string_attributes, numeric_attributes
 FROM payloads
@@ -106,6 +142,56 @@ func (q *Queries) GetPayloadForEntityKey(ctx context.Context, entityKey []byte)
 	return i, err
 }
 
+const getPayloadsWithoutVersion = `-- name: GetPayloadsWithoutVersion :many
+SELECT id, payload, content_type, string_attributes, numeric_attributes
+FROM payloads
+WHERE id > ?1 AND json_extract(numeric_attributes, '$.Values."$version"') IS NULL
+ORDER BY id
+LIMIT ?2
+`
+
+type GetPayloadsWithoutVersionParams struct {
+	AfterID   uint64
+	BatchSize int64
+}
+
+type GetPayloadsWithoutVersionRow struct {
+	ID                uint64
+	Payload           []byte
+	ContentType       string
+	StringAttributes  *StringAttributes
+	NumericAttributes *NumericAttributes
+}
+
+func (q *Queries) GetPayloadsWithoutVersion(ctx context.Context, arg GetPayloadsWithoutVersionParams) ([]GetPayloadsWithoutVersionRow, error) {
+	rows, err := q.query(ctx, q.getPayloadsWithoutVersionStmt, getPayloadsWithoutVersion, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetPayloadsWithoutVersionRow{}
+	for rows.Next() {
+		var i GetPayloadsWithoutVersionRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Payload,
+			&i.ContentType,
+			&i.StringAttributes,
+			&i.NumericAttributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStringAttributeValueBitmap = `-- name: GetStringAttributeValueBitmap :one
 SELECT bitmap FROM string_attributes_values_bitmaps
 WHERE name = ? AND value = ?
@@ -123,6 +209,161 @@ func (q *Queries) GetStringAttributeValueBitmap(ctx context.Context, arg GetStri
 	return bitmap, err
 }
 
+const getStringAttributeValueBitmapsWithoutCardinality = `-- name: GetStringAttributeValueBitmapsWithoutCardinality :many
+SELECT name, value, bitmap FROM string_attributes_values_bitmaps
+WHERE cardinality IS NULL
+LIMIT ?
+`
+
+type GetStringAttributeValueBitmapsWithoutCardinalityRow struct {
+	Name   string
+	Value  string
+	Bitmap *Bitmap
+}
+
+func (q *Queries) GetStringAttributeValueBitmapsWithoutCardinality(ctx context.Context, limit int64) ([]GetStringAttributeValueBitmapsWithoutCardinalityRow, error) {
+	rows, err := q.query(ctx, q.getStringAttributeValueBitmapsWithoutCardinalityStmt, getStringAttributeValueBitmapsWithoutCardinality, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetStringAttributeValueBitmapsWithoutCardinalityRow{}
+	for rows.Next() {
+		var i GetStringAttributeValueBitmapsWithoutCardinalityRow
+		if err := rows.Scan(&i.Name, &i.Value, &i.Bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hasAttributeValueBitmapsWithoutCardinality = `-- name: HasAttributeValueBitmapsWithoutCardinality :one
+SELECT EXISTS (SELECT 1 FROM string_attributes_values_bitmaps WHERE cardinality IS NULL)
+    OR EXISTS (SELECT 1 FROM numeric_attributes_values_bitmaps WHERE cardinality IS NULL)
+`
+
+func (q *Queries) HasAttributeValueBitmapsWithoutCardinality(ctx context.Context) (sql.NullBool, error) {
+	row := q.queryRow(ctx, q.hasAttributeValueBitmapsWithoutCardinalityStmt, hasAttributeValueBitmapsWithoutCardinality)
+	var column_1 sql.NullBool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const rebuildNumericAttributeStats = `-- name: RebuildNumericAttributeStats :exec
+INSERT OR REPLACE INTO numeric_attributes_stats (name, distinct_values, cardinality)
+SELECT name, COUNT(*), SUM(cardinality) FROM numeric_attributes_values_bitmaps
+GROUP BY name
+`
+
+func (q *Queries) RebuildNumericAttributeStats(ctx context.Context) error {
+	_, err := q.exec(ctx, q.rebuildNumericAttributeStatsStmt, rebuildNumericAttributeStats)
+	return err
+}
+
+const rebuildStringAttributeStats = `-- name: RebuildStringAttributeStats :exec
+INSERT OR REPLACE INTO string_attributes_stats (name, distinct_values, cardinality)
+SELECT name, COUNT(*), SUM(cardinality) FROM string_attributes_values_bitmaps
+GROUP BY name
+`
+
+func (q *Queries) RebuildStringAttributeStats(ctx context.Context) error {
+	_, err := q.exec(ctx, q.rebuildStringAttributeStatsStmt, rebuildStringAttributeStats)
+	return err
+}
+
+const setNumericAttributeValueCardinality = `-- name: SetNumericAttributeValueCardinality :exec
+UPDATE numeric_attributes_values_bitmaps SET cardinality = ?
+WHERE name = ? AND value = ?
+`
+
+type SetNumericAttributeValueCardinalityParams struct {
+	Cardinality uint64
+	Name        string
+	Value       NumericValue
+}
+
+func (q *Queries) SetNumericAttributeValueCardinality(ctx context.Context, arg SetNumericAttributeValueCardinalityParams) error {
+	_, err := q.exec(ctx, q.setNumericAttributeValueCardinalityStmt, setNumericAttributeValueCardinality, arg.Cardinality, arg.Name, arg.Value)
+	return err
+}
+
+const setStringAttributeValueCardinality = `-- name: SetStringAttributeValueCardinality :exec
+UPDATE string_attributes_values_bitmaps SET cardinality = ?
+WHERE name = ? AND value = ?
+`
+
+type SetStringAttributeValueCardinalityParams struct {
+	Cardinality uint64
+	Name        string
+	Value       string
+}
+
+func (q *Queries) SetStringAttributeValueCardinality(ctx context.Context, arg SetStringAttributeValueCardinalityParams) error {
+	_, err := q.exec(ctx, q.setStringAttributeValueCardinalityStmt, setStringAttributeValueCardinality, arg.Cardinality, arg.Name, arg.Value)
+	return err
+}
+
+const updateNumericAttributeStats = `-- name: UpdateNumericAttributeStats :exec
+INSERT INTO numeric_attributes_stats (name, distinct_values, cardinality)
+VALUES (?1, CAST(?2 AS INTEGER), CAST(?3 AS INTEGER))
+ON CONFLICT (name) DO UPDATE SET
+    distinct_values = distinct_values + excluded.distinct_values,
+    cardinality = cardinality + excluded.cardinality
+`
+
+type UpdateNumericAttributeStatsParams struct {
+	Name                string
+	DistinctValuesDelta int64
+	CardinalityDelta    int64
+}
+
+func (q *Queries) UpdateNumericAttributeStats(ctx context.Context, arg UpdateNumericAttributeStatsParams) error {
+	_, err := q.exec(ctx, q.updateNumericAttributeStatsStmt, updateNumericAttributeStats, arg.Name, arg.DistinctValuesDelta, arg.CardinalityDelta)
+	return err
+}
+
+const updatePayloadAttributes = `-- name: UpdatePayloadAttributes :exec
+UPDATE payloads SET string_attributes = ?, numeric_attributes = ?
+WHERE id = ?
+`
+
+type UpdatePayloadAttributesParams struct {
+	StringAttributes  *StringAttributes
+	NumericAttributes *NumericAttributes
+	ID                uint64
+}
+
+func (q *Queries) UpdatePayloadAttributes(ctx context.Context, arg UpdatePayloadAttributesParams) error {
+	_, err := q.exec(ctx, q.updatePayloadAttributesStmt, updatePayloadAttributes, arg.StringAttributes, arg.NumericAttributes, arg.ID)
+	return err
+}
+
+const updateStringAttributeStats = `-- name: UpdateStringAttributeStats :exec
+INSERT INTO string_attributes_stats (name, distinct_values, cardinality)
+VALUES (?1, CAST(?2 AS INTEGER), CAST(?3 AS INTEGER))
+ON CONFLICT (name) DO UPDATE SET
+    distinct_values = distinct_values + excluded.distinct_values,
+    cardinality = cardinality + excluded.cardinality
+`
+
+type UpdateStringAttributeStatsParams struct {
+	Name                string
+	DistinctValuesDelta int64
+	CardinalityDelta    int64
+}
+
+func (q *Queries) UpdateStringAttributeStats(ctx context.Context, arg UpdateStringAttributeStatsParams) error {
+	_, err := q.exec(ctx, q.updateStringAttributeStatsStmt, updateStringAttributeStats, arg.Name, arg.DistinctValuesDelta, arg.CardinalityDelta)
+	return err
+}
+
 const upsertLastBlock = `-- name: UpsertLastBlock :exec
 INSERT INTO last_block (id, block)
 VALUES (1, ?)
@@ -135,19 +376,25 @@ func (q *Queries) UpsertLastBlock(ctx context.Context, block uint64) error {
 }
 
 const upsertNumericAttributeValueBitmap = `-- name: UpsertNumericAttributeValueBitmap :exec
-INSERT INTO numeric_attributes_values_bitmaps (name, value, bitmap)
-VALUES (?, ?, ?)
-ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap
+INSERT INTO numeric_attributes_values_bitmaps (name, value, bitmap, cardinality)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap, cardinality = excluded.cardinality
 `
 
 type UpsertNumericAttributeValueBitmapParams struct {
-	Name   string
-	Value  uint64
-	Bitmap *Bitmap
+	Name        string
+	Value       NumericValue
+	Bitmap      *Bitmap
+	Cardinality uint64
 }
 
 func (q *Queries) UpsertNumericAttributeValueBitmap(ctx context.Context, arg UpsertNumericAttributeValueBitmapParams) error {
-	_, err := q.exec(ctx, q.upsertNumericAttributeValueBitmapStmt, upsertNumericAttributeValueBitmap, arg.Name, arg.Value, arg.Bitmap)
+	_, err := q.exec(ctx, q.upsertNumericAttributeValueBitmapStmt, upsertNumericAttributeValueBitmap,
+		arg.Name,
+		arg.Value,
+		arg.Bitmap,
+		arg.Cardinality,
+	)
 	return err
 }
 
@@ -189,18 +436,24 @@ func (q *Queries) UpsertPayload(ctx context.Context, arg UpsertPayloadParams) (u
 }
 
 const upsertStringAttributeValueBitmap = `-- name: UpsertStringAttributeValueBitmap :exec
-INSERT INTO string_attributes_values_bitmaps (name, value, bitmap)
-VALUES (?, ?, ?)
-ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap
+INSERT INTO string_attributes_values_bitmaps (name, value, bitmap, cardinality)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap, cardinality = excluded.cardinality
 `
 
 type UpsertStringAttributeValueBitmapParams struct {
-	Name   string
-	Value  string
-	Bitmap *Bitmap
+	Name        string
+	Value       string
+	Bitmap      *Bitmap
+	Cardinality uint64
 }
 
 func (q *Queries) UpsertStringAttributeValueBitmap(ctx context.Context, arg UpsertStringAttributeValueBitmapParams) error {
-	_, err := q.exec(ctx, q.upsertStringAttributeValueBitmapStmt, upsertStringAttributeValueBitmap, arg.Name, arg.Value, arg.Bitmap)
+	_, err := q.exec(ctx, q.upsertStringAttributeValueBitmapStmt, upsertStringAttributeValueBitmap,
+		arg.Name,
+		arg.Value,
+		arg.Bitmap,
+		arg.Cardinality,
+	)
 	return err
 }
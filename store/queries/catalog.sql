@@ -0,0 +1,15 @@
+-- name: ListStringAttributeValues :many
+SELECT value, cardinality FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name)
+    AND value >= sqlc.arg(prefix)
+    AND (CAST(sqlc.arg(has_prefix_upper_bound) AS BOOLEAN) = 0 OR value < sqlc.arg(prefix_upper_bound))
+    AND (CAST(sqlc.arg(has_after) AS BOOLEAN) = 0 OR value > sqlc.arg(after))
+ORDER BY value
+LIMIT sqlc.arg(max_values);
+
+-- name: ListNumericAttributeValues :many
+SELECT value, cardinality FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name)
+    AND (CAST(sqlc.arg(has_after) AS BOOLEAN) = 0 OR value > sqlc.arg(after))
+ORDER BY value
+LIMIT sqlc.arg(max_values);
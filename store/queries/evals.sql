@@ -46,6 +46,28 @@ WHERE name = sqlc.arg(name) AND value NOT GLOB sqlc.arg(value);
 SELECT bitmap FROM string_attributes_values_bitmaps
 WHERE name = sqlc.arg(name) AND value NOT IN (sqlc.Slice('values'));
 
+-- name: EvaluateStringAttribute :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name);
+
+-- name: EvaluateNumericAttribute :many
+SELECT bitmap FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name);
+
+-- Attribute comparisons join the bitmaps of two attributes on their values.
+
+-- name: EvaluateStringAttributesEqual :many
+SELECT l.bitmap AS bitmap, r.bitmap AS other_bitmap
+FROM string_attributes_values_bitmaps l
+JOIN string_attributes_values_bitmaps r ON r.name = sqlc.arg(other) AND r.value = l.value
+WHERE l.name = sqlc.arg(name);
+
+-- name: EvaluateNumericAttributesEqual :many
+SELECT l.bitmap AS bitmap, r.bitmap AS other_bitmap
+FROM numeric_attributes_values_bitmaps l
+JOIN numeric_attributes_values_bitmaps r ON r.name = sqlc.arg(other) AND r.value = l.value
+WHERE l.name = sqlc.arg(name);
+
 -- name: EvaluateStringAttributeValueInclusion :many
 SELECT bitmap FROM string_attributes_values_bitmaps
 WHERE name = sqlc.arg(name) AND value IN (sqlc.Slice('values'));
@@ -73,3 +95,112 @@ WHERE name = sqlc.arg(name) AND value IN (sqlc.Slice('values'));
 -- name: EvaluateNumericAttributeValueNotInclusion :many
 SELECT bitmap FROM numeric_attributes_values_bitmaps
 WHERE name = sqlc.arg(name) AND value NOT IN (sqlc.Slice('values'));
+
+-- name: EvaluateStringAttributeValueRange :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(from_value) AND value < sqlc.arg(to_value);
+
+-- name: EvaluateStringAttributeValueNotRange :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND (value < sqlc.arg(from_value) OR value >= sqlc.arg(to_value));
+
+-- name: GetStringAttributeValues :many
+SELECT value FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name)
+ORDER BY value;
+
+-- name: EvaluateStringAttributeValueBetween :many
+SELECT bitmap FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(from_value) AND value <= sqlc.arg(to_value)
+AND (CAST(sqlc.arg(from_inclusive) AS BOOLEAN) OR value != sqlc.arg(from_value))
+AND (CAST(sqlc.arg(to_inclusive) AS BOOLEAN) OR value != sqlc.arg(to_value));
+
+-- name: EvaluateNumericAttributeValueBetween :many
+SELECT bitmap FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(from_value) AND value <= sqlc.arg(to_value)
+AND (CAST(sqlc.arg(from_inclusive) AS BOOLEAN) OR value != sqlc.arg(from_value))
+AND (CAST(sqlc.arg(to_inclusive) AS BOOLEAN) OR value != sqlc.arg(to_value));
+
+-- Estimates sum the cardinalities of the bitmaps that the evaluations above
+-- would load, without loading them.
+
+-- name: GetStringAttributeStats :one
+SELECT distinct_values, cardinality FROM string_attributes_stats
+WHERE name = sqlc.arg(name);
+
+-- name: ListStringAttributeStats :many
+SELECT name, distinct_values, cardinality FROM string_attributes_stats
+WHERE cardinality > 0
+ORDER BY name;
+
+-- name: EstimateStringAttributeValueInclusion :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value IN (sqlc.slice('values'));
+
+-- name: EstimateStringAttributeValueLowerThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value < sqlc.arg(value);
+
+-- name: EstimateStringAttributeValueLessOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value <= sqlc.arg(value);
+
+-- name: EstimateStringAttributeValueGreaterThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value > sqlc.arg(value);
+
+-- name: EstimateStringAttributeValueGreaterOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(value);
+
+-- name: EstimateStringAttributeValueBetween :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(from_value) AND value <= sqlc.arg(to_value)
+AND (CAST(sqlc.arg(from_inclusive) AS BOOLEAN) OR value != sqlc.arg(from_value))
+AND (CAST(sqlc.arg(to_inclusive) AS BOOLEAN) OR value != sqlc.arg(to_value));
+
+-- name: GetNumericAttributeStats :one
+SELECT distinct_values, cardinality FROM numeric_attributes_stats
+WHERE name = sqlc.arg(name);
+
+-- name: ListNumericAttributeStats :many
+SELECT name, distinct_values, cardinality FROM numeric_attributes_stats
+WHERE cardinality > 0
+ORDER BY name;
+
+-- name: EstimateNumericAttributeValueInclusion :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value IN (sqlc.slice('values'));
+
+-- name: EstimateNumericAttributeValueLowerThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value < sqlc.arg(value);
+
+-- name: EstimateNumericAttributeValueLessOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value <= sqlc.arg(value);
+
+-- name: EstimateNumericAttributeValueGreaterThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value > sqlc.arg(value);
+
+-- name: EstimateNumericAttributeValueGreaterOrEqualThan :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(value);
+
+-- name: EstimateNumericAttributeValueBetween :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value >= sqlc.arg(from_value) AND value <= sqlc.arg(to_value)
+AND (CAST(sqlc.arg(from_inclusive) AS BOOLEAN) OR value != sqlc.arg(from_value))
+AND (CAST(sqlc.arg(to_inclusive) AS BOOLEAN) OR value != sqlc.arg(to_value));
+
+-- name: EstimateStringAttributeValueGlob :one
+SELECT CAST(COALESCE(SUM(cardinality), 0) AS INTEGER) FROM string_attributes_values_bitmaps
+WHERE name = sqlc.arg(name) AND value GLOB sqlc.arg(value);
+
+-- Entity keys are looked up in the unique index on the payloads instead of the
+-- bitmaps of $key.
+
+-- name: EvaluateEntityKeyInclusion :many
+SELECT id FROM payloads
+WHERE entity_key IN (sqlc.slice('keys'));
@@ -23,9 +23,9 @@ FROM payloads
 WHERE entity_key = ?;
 
 -- name: UpsertStringAttributeValueBitmap :exec
-INSERT INTO string_attributes_values_bitmaps (name, value, bitmap)
-VALUES (?, ?, ?)
-ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap;
+INSERT INTO string_attributes_values_bitmaps (name, value, bitmap, cardinality)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap, cardinality = excluded.cardinality;
 
 -- name: DeleteStringAttributeValueBitmap :exec
 DELETE FROM string_attributes_values_bitmaps
@@ -35,10 +35,35 @@ WHERE name = ? AND value = ?;
 SELECT bitmap FROM string_attributes_values_bitmaps
 WHERE name = ? AND value = ?;
 
+-- name: GetStringAttributeValueBitmapsWithoutCardinality :many
+SELECT name, value, bitmap FROM string_attributes_values_bitmaps
+WHERE cardinality IS NULL
+LIMIT ?;
+
+-- name: HasAttributeValueBitmapsWithoutCardinality :one
+SELECT EXISTS (SELECT 1 FROM string_attributes_values_bitmaps WHERE cardinality IS NULL)
+    OR EXISTS (SELECT 1 FROM numeric_attributes_values_bitmaps WHERE cardinality IS NULL);
+
+-- name: SetStringAttributeValueCardinality :exec
+UPDATE string_attributes_values_bitmaps SET cardinality = ?
+WHERE name = ? AND value = ?;
+
+-- name: UpdateStringAttributeStats :exec
+INSERT INTO string_attributes_stats (name, distinct_values, cardinality)
+VALUES (sqlc.arg(name), CAST(sqlc.arg(distinct_values_delta) AS INTEGER), CAST(sqlc.arg(cardinality_delta) AS INTEGER))
+ON CONFLICT (name) DO UPDATE SET
+    distinct_values = distinct_values + excluded.distinct_values,
+    cardinality = cardinality + excluded.cardinality;
+
+-- name: RebuildStringAttributeStats :exec
+INSERT OR REPLACE INTO string_attributes_stats (name, distinct_values, cardinality)
+SELECT name, COUNT(*), SUM(cardinality) FROM string_attributes_values_bitmaps
+GROUP BY name;
+
 -- name: UpsertNumericAttributeValueBitmap :exec
-INSERT INTO numeric_attributes_values_bitmaps (name, value, bitmap)
-VALUES (?, ?, ?)
-ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap;
+INSERT INTO numeric_attributes_values_bitmaps (name, value, bitmap, cardinality)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (name, value) DO UPDATE SET bitmap = excluded.bitmap, cardinality = excluded.cardinality;
 
 -- name: DeleteNumericAttributeValueBitmap :exec
 DELETE FROM numeric_attributes_values_bitmaps
@@ -48,6 +73,27 @@ WHERE name = ? AND value = ?;
 SELECT bitmap FROM numeric_attributes_values_bitmaps
 WHERE name = ? AND value = ?;
 
+-- name: GetNumericAttributeValueBitmapsWithoutCardinality :many
+SELECT name, value, bitmap FROM numeric_attributes_values_bitmaps
+WHERE cardinality IS NULL
+LIMIT ?;
+
+-- name: SetNumericAttributeValueCardinality :exec
+UPDATE numeric_attributes_values_bitmaps SET cardinality = ?
+WHERE name = ? AND value = ?;
+
+-- name: UpdateNumericAttributeStats :exec
+INSERT INTO numeric_attributes_stats (name, distinct_values, cardinality)
+VALUES (sqlc.arg(name), CAST(sqlc.arg(distinct_values_delta) AS INTEGER), CAST(sqlc.arg(cardinality_delta) AS INTEGER))
+ON CONFLICT (name) DO UPDATE SET
+    distinct_values = distinct_values + excluded.distinct_values,
+    cardinality = cardinality + excluded.cardinality;
+
+-- name: RebuildNumericAttributeStats :exec
+INSERT OR REPLACE INTO numeric_attributes_stats (name, distinct_values, cardinality)
+SELECT name, COUNT(*), SUM(cardinality) FROM numeric_attributes_values_bitmaps
+GROUP BY name;
+
 -- name: UpsertLastBlock :exec
 INSERT INTO last_block (id, block)
 VALUES (1, ?)
@@ -55,3 +101,14 @@ ON CONFLICT (id) DO UPDATE SET block = EXCLUDED.block;
 
 -- name: GetLastBlock :one
 SELECT block FROM last_block;
+
+-- name: GetPayloadsWithoutVersion :many
+SELECT id, payload, content_type, string_attributes, numeric_attributes
+FROM payloads
+WHERE id > sqlc.arg(after_id) AND json_extract(numeric_attributes, '$.Values."$version"') IS NULL
+ORDER BY id
+LIMIT sqlc.arg(batch_size);
+
+-- name: UpdatePayloadAttributes :exec
+UPDATE payloads SET string_attributes = ?, numeric_attributes = ?
+WHERE id = ?;
@@ -5,4 +5,17 @@ WHERE id IN (sqlc.slice(ids))
 ORDER BY id DESC;
 
 -- name: GetNumberOfEntities :one
-SELECT COUNT(*) FROM payloads;
\ No newline at end of file
+SELECT COUNT(*) FROM payloads;
+-- name: GetNumericAttributeValueBitmaps :many
+SELECT value, bitmap FROM numeric_attributes_values_bitmaps
+WHERE name = sqlc.arg(name)
+ORDER BY value;
+
+-- name: GetEntityKeys :many
+SELECT entity_key FROM payloads
+WHERE id IN (sqlc.slice(ids));
+
+-- name: ScanAttributes :many
+SELECT id, string_attributes, numeric_attributes
+FROM payloads
+WHERE id IN (sqlc.slice(ids));
@@ -10,6 +10,44 @@ import (
 	"strings"
 )
 
+const getEntityKeys = `-- name: GetEntityKeys :many
+SELECT entity_key FROM payloads
+WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) GetEntityKeys(ctx context.Context, ids []uint64) ([][]byte, error) {
+	query := getEntityKeys
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := [][]byte{}
+	for rows.Next() {
+		var entity_key []byte
+		if err := rows.Scan(&entity_key); err != nil {
+			return nil, err
+		}
+		items = append(items, entity_key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getNumberOfEntities = `-- name: GetNumberOfEntities :one
 SELECT COUNT(*) FROM payloads
 `
@@ -21,6 +59,40 @@ func (q *Queries) GetNumberOfEntities(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const getNumericAttributeValueBitmaps = `-- name: GetNumericAttributeValueBitmaps :many
+SELECT value, bitmap FROM numeric_attributes_values_bitmaps
+WHERE name = ?1
+ORDER BY value
+`
+
+type GetNumericAttributeValueBitmapsRow struct {
+	Value  NumericValue
+	Bitmap *Bitmap
+}
+
+func (q *Queries) GetNumericAttributeValueBitmaps(ctx context.Context, name string) ([]GetNumericAttributeValueBitmapsRow, error) {
+	rows, err := q.query(ctx, q.getNumericAttributeValueBitmapsStmt, getNumericAttributeValueBitmaps, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetNumericAttributeValueBitmapsRow{}
+	for rows.Next() {
+		var i GetNumericAttributeValueBitmapsRow
+		if err := rows.Scan(&i.Value, &i.Bitmap); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const retrievePayloads = `-- name: RetrievePayloads :many
 SELECT entity_key, id, payload, content_type, string_attributes, numeric_attributes
 FROM payloads
@@ -76,3 +148,48 @@ func (q *Queries) RetrievePayloads(ctx context.Context, ids []uint64) ([]Retriev
 	}
 	return items, nil
 }
+
+const scanAttributes = `-- name: ScanAttributes :many
+SELECT id, string_attributes, numeric_attributes
+FROM payloads
+WHERE id IN (/*SLICE:ids*/?)
+`
+
+type ScanAttributesRow struct {
+	ID                uint64
+	StringAttributes  *StringAttributes
+	NumericAttributes *NumericAttributes
+}
+
+func (q *Queries) ScanAttributes(ctx context.Context, ids []uint64) ([]ScanAttributesRow, error) {
+	query := scanAttributes
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScanAttributesRow{}
+	for rows.Next() {
+		var i ScanAttributesRow
+		if err := rows.Scan(&i.ID, &i.StringAttributes, &i.NumericAttributes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,16 @@
+-- Numeric attribute values are stored as 32 byte big-endian blobs, which
+-- sort in numeric order for the full range of 256 bit unsigned integers.
+CREATE TABLE numeric_attributes_values_bitmaps_new (
+    name TEXT NOT NULL,
+    value BLOB NOT NULL,
+    bitmap BLOB,
+    PRIMARY KEY (name, value)
+);
+
+INSERT INTO numeric_attributes_values_bitmaps_new (name, value, bitmap)
+SELECT name, unhex(printf('%064x', value)), bitmap
+FROM numeric_attributes_values_bitmaps;
+
+DROP TABLE numeric_attributes_values_bitmaps;
+
+ALTER TABLE numeric_attributes_values_bitmaps_new RENAME TO numeric_attributes_values_bitmaps;
@@ -0,0 +1,5 @@
+-- Numeric attribute values gain a sign byte and an 8 byte fractional part.
+-- All existing values are non-negative integers, so they are prefixed with
+-- the non-negative sign byte and get a zero fraction.
+UPDATE numeric_attributes_values_bitmaps
+SET value = unhex('01' || hex(value) || '0000000000000000');
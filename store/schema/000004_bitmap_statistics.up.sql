@@ -0,0 +1,25 @@
+-- The cardinality of every bitmap, for estimating the number of entities that
+-- a query term matches. It is NULL until the store backfills it, since SQL
+-- cannot read the serialised bitmaps.
+ALTER TABLE string_attributes_values_bitmaps ADD COLUMN cardinality INTEGER;
+ALTER TABLE numeric_attributes_values_bitmaps ADD COLUMN cardinality INTEGER;
+
+-- Covering indexes, so estimates do not read the bitmaps.
+CREATE INDEX string_attributes_values_cardinality_index
+ON string_attributes_values_bitmaps (name, value, cardinality);
+CREATE INDEX numeric_attributes_values_cardinality_index
+ON numeric_attributes_values_bitmaps (name, value, cardinality);
+
+-- Per attribute, the number of distinct values and the number of entities
+-- with the attribute, which is the sum of the cardinalities of its bitmaps.
+CREATE TABLE string_attributes_stats (
+    name TEXT NOT NULL PRIMARY KEY,
+    distinct_values INTEGER NOT NULL,
+    cardinality INTEGER NOT NULL
+);
+
+CREATE TABLE numeric_attributes_stats (
+    name TEXT NOT NULL PRIMARY KEY,
+    distinct_values INTEGER NOT NULL,
+    cardinality INTEGER NOT NULL
+);